@@ -1,236 +1,524 @@
-﻿package main
-
-import (
-	"encoding/json"
-	"flag"
-	"fmt"
-	"log"
-	"os"
-	"os/signal"
-	"syscall"
-	"time"
-
-	"github.com/kalon-network/kalon/core"
-	"github.com/kalon-network/kalon/network"
-	"github.com/kalon-network/kalon/rpc"
-	"github.com/kalon-network/kalon/storage"
-)
-
-// NodeV2 represents a professional node
-type NodeV2 struct {
-	config     *NodeConfig
-	blockchain *core.BlockchainV2
-	rpcServer  *rpc.ServerV2
-	p2p        *network.P2P
-	running    bool
-}
-
-// NodeConfig represents node configuration
-type NodeConfig struct {
-	DataDir string
-	Genesis string
-	RPCAddr string
-	P2PAddr string
-}
-
-func main() {
-	var (
-		dataDir = flag.String("datadir", "data/testnet", "Data directory")
-		genesis = flag.String("genesis", "genesis/testnet.json", "Genesis file")
-		rpcAddr = flag.String("rpc", ":16316", "RPC server address")
-		p2pAddr = flag.String("p2p", ":17335", "P2P server address")
-	)
-	flag.Parse()
-
-	config := &NodeConfig{
-		DataDir: *dataDir,
-		Genesis: *genesis,
-		RPCAddr: *rpcAddr,
-		P2PAddr: *p2pAddr,
-	}
-
-	node := NewNodeV2(config)
-
-	// Start node
-	if err := node.Start(); err != nil {
-		log.Fatalf("ÔØî Failed to start node: %v", err)
-	}
-
-	// Wait for shutdown signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	<-sigChan
-	log.Printf("­ƒøæ Shutdown signal received")
-
-	// Stop node
-	if err := node.Stop(); err != nil {
-		log.Printf("ÔØî Error stopping node: %v", err)
-	}
-}
-
-// NewNodeV2 creates a new professional node
-func NewNodeV2(config *NodeConfig) *NodeV2 {
-	return &NodeV2{
-		config: config,
-	}
-}
-
-// Start starts the node professionally
-func (n *NodeV2) Start() error {
-	log.Printf("­ƒÜÇ Starting Professional Kalon Node v2.0")
-	log.Printf("   Data Dir: %s", n.config.DataDir)
-	log.Printf("   Genesis: %s", n.config.Genesis)
-	log.Printf("   RPC: %s", n.config.RPCAddr)
-	log.Printf("   P2P: %s", n.config.P2PAddr)
-
-	// Load genesis configuration
-	genesis, err := n.loadGenesis()
-	if err != nil {
-		return err
-	}
-
-	// Initialize persistent storage
-	dbPath := n.config.DataDir + "/chaindb"
-	log.Printf("🔧 Initializing persistent storage at %s", dbPath)
-	levelDBStorage, err := storage.NewLevelDBStorage(dbPath)
-	if err != nil {
-		log.Printf("⚠️ Failed to initialize LevelDB: %v. Continuing in-memory mode.", err)
-		// Create blockchain without persistence
-		n.blockchain = core.NewBlockchainV2(genesis, nil)
-	} else {
-		// Create storage persister
-		persister := storage.NewBlockStorage(levelDBStorage)
-		n.blockchain = core.NewBlockchainV2(genesis, persister)
-	}
-	log.Printf("✅ Blockchain initialized with height: %d", n.blockchain.GetHeight())
-
-	// Create RPC server
-	n.rpcServer = rpc.NewServerV2(n.config.RPCAddr, n.blockchain)
-
-	// Start RPC server
-	go func() {
-		if err := n.rpcServer.Start(); err != nil {
-			log.Printf("ÔØî RPC Server error: %v", err)
-		}
-	}()
-
-	// Initialize P2P network
-	p2pConfig := &network.P2PConfig{
-		ListenAddr:   n.config.P2PAddr,
-		SeedNodes:    []string{}, // TODO: Add seed nodes
-		MaxPeers:     50,
-		DialTimeout:  10 * time.Second,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		KeepAlive:    60 * time.Second,
-	}
-	n.p2p = network.NewP2P(p2pConfig)
-
-	// Start P2P server
-	if err := n.p2p.Start(); err != nil {
-		log.Printf("⚠️ Failed to start P2P: %v", err)
-	} else {
-		log.Printf("✅ P2P network started on %s", n.config.P2PAddr)
-	}
-
-	// Wait a moment for server to start
-	time.Sleep(1 * time.Second)
-
-	log.Printf("✅ Node started successfully")
-	n.running = true
-
-	return nil
-}
-
-// Stop stops the node gracefully
-func (n *NodeV2) Stop() error {
-	if !n.running {
-		return nil
-	}
-
-	log.Printf("­ƒøæ Stopping node...")
-
-	// Stop RPC server
-	if n.rpcServer != nil {
-		n.rpcServer.Stop()
-	}
-
-	// Stop P2P network
-	if n.p2p != nil {
-		n.p2p.Stop()
-	}
-
-	// Close blockchain storage
-	if n.blockchain != nil {
-		if err := n.blockchain.Close(); err != nil {
-			log.Printf("⚠️ Error closing blockchain: %v", err)
-		}
-	}
-
-	log.Printf("✅ Node stopped successfully")
-	n.running = false
-
-	return nil
-}
-
-// loadGenesis loads the genesis configuration
-func (n *NodeV2) loadGenesis() (*core.GenesisConfig, error) {
-	// Load genesis from file
-	data, err := os.ReadFile(n.config.Genesis)
-	if err != nil {
-		log.Printf("⚠️ Failed to read genesis file %s: %v. Using defaults.", n.config.Genesis, err)
-		// Return default genesis with proper difficulty
-		return &core.GenesisConfig{
-			ChainID:            7718,
-			Name:               "Kalon Testnet",
-			Symbol:             "tKALON",
-			BlockTimeTarget:    30,
-			MaxSupply:          1000000000,
-			InitialBlockReward: 5.0,
-			HalvingSchedule:    []core.HalvingEvent{},
-			Difficulty: core.DifficultyConfig{
-				Algo:                 "LWMA",
-				Window:               120,
-				InitialDifficulty:    5000,
-				MaxAdjustPerBlockPct: 25,
-				LaunchGuard: core.LaunchGuard{
-					Enabled:                   true,
-					DurationHours:             24,
-					DifficultyFloorMultiplier: 4.0,
-					InitialReward:             2.0,
-				},
-			},
-			AddressFormat: core.AddressFormat{
-				Type: "bech32",
-				HRP:  "tkalon",
-			},
-			Premine: core.PremineConfig{
-				Enabled: false,
-			},
-			TreasuryAddress: "tkalon1treasury0000000000000000000000000000000000000000000000000000000000",
-			NetworkFee: core.NetworkFeeConfig{
-				BlockFeeRate:       0.05,
-				TxFeeShareTreasury: 0.20,
-				BaseTxFee:          0.01,
-				GasPrice:           1000,
-			},
-			Governance: core.GovernanceConfig{
-				Parameters: core.GovernanceParameters{
-					NetworkFeeRate:     0.05,
-					TxFeeShareTreasury: 0.20,
-					TreasuryCapPercent: 10,
-				},
-			},
-		}, nil
-	}
-
-	// Parse JSON
-	var genesis core.GenesisConfig
-	if err := json.Unmarshal(data, &genesis); err != nil {
-		return nil, fmt.Errorf("failed to parse genesis JSON: %w", err)
-	}
-
-	log.Printf("✅ Loaded genesis from %s", n.config.Genesis)
-	return &genesis, nil
-}
+﻿package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+	"github.com/kalon-network/kalon/network"
+	"github.com/kalon-network/kalon/rpc"
+	"github.com/kalon-network/kalon/storage"
+)
+
+// NodeV2 represents a professional node
+type NodeV2 struct {
+	config     *NodeConfig
+	blockchain *core.BlockchainV2
+	rpcServer  *rpc.ServerV2
+	p2p        *network.P2P
+	running    bool
+	quit       chan struct{}
+}
+
+// NodeConfig represents node configuration
+type NodeConfig struct {
+	DataDir string
+	Genesis string
+	RPCAddr string
+	P2PAddr string
+
+	SeedNodes   []string
+	AddNodes    []string
+	ConnectOnly []string
+	Outbound    int
+	MaxInbound  int
+
+	Encrypt      bool
+	AllowedPeers []string
+
+	RPCCookie bool             // Write an admin cookie to the data directory for local tools
+	RPCAuth   string           // JSON file with RPC credentials and roles
+	RPCAccess rpc.AccessConfig // Allowed RPC clients, trusted proxies and limits
+
+	RPCTLS        bool        // Serve RPC over HTTPS
+	RPCTLSCert    string      // Certificate file, self-signed in the data directory if empty
+	RPCTLSKey     string      // Key file of RPCTLSCert
+	RPCSocket     string      // Unix socket to serve RPC on as well, none if empty
+	RPCSocketMode os.FileMode // Permissions of the Unix socket
+	RPCReadyPeers int         // Peers needed before /readyz reports ready
+}
+
+// stringList is a flag that may be repeated or given comma-separated values
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+func main() {
+	var (
+		dataDir = flag.String("datadir", "data/testnet", "Data directory")
+		genesis = flag.String("genesis", "genesis/testnet.json", "Genesis file")
+		rpcAddr = flag.String("rpc", ":16316", "RPC server address")
+		p2pAddr = flag.String("p2p", ":17335", "P2P server address")
+
+		outbound   = flag.Int("outbound", network.DefaultTargetOutbound, "Number of outbound peer connections to maintain")
+		maxInbound = flag.Int("maxinbound", network.DefaultMaxInbound, "Number of inbound peer connections to accept")
+		encrypt    = flag.Bool("encrypt", false, "Encrypt peer connections with TLS authenticated by node keys")
+
+		rpcCookie = flag.Bool("rpccookie", true, "Write an RPC admin cookie to the data directory for local tools")
+		rpcAuth   = flag.String("rpcauth", "", "JSON file with RPC tokens, users and roles")
+
+		rpcRateLimit     = flag.Int("rpcratelimit", rpc.DefaultRateLimit, "RPC cost units each client may spend per minute (0 disables)")
+		rpcLimitLocal    = flag.Bool("rpclimitlocal", false, "Apply RPC rate limits to loopback clients too")
+		rpcMaxConcurrent = flag.Int("rpcmaxconcurrent", rpc.DefaultMaxConcurrent, "RPC requests served at the same time")
+
+		rpcTLS        = flag.Bool("rpctls", false, "Serve RPC over HTTPS")
+		rpcTLSCert    = flag.String("rpctlscert", "", "RPC certificate file (default: self-signed rpc.cert in the data directory)")
+		rpcTLSKey     = flag.String("rpctlskey", "", "RPC certificate key file")
+		rpcSocket     = flag.String("rpcsocket", "", "Unix socket to serve RPC on for local tools")
+		rpcSocketMode = flag.String("rpcsocketmode", "0600", "Permissions of the RPC Unix socket")
+		rpcReadyPeers = flag.Int("rpcreadypeers", 1, "Peers needed before /readyz reports the node ready")
+
+		seeds, addNodes, connect, allowPeers stringList
+
+		rpcAllowIPs, rpcTrustedProxies, rpcAllowOrigins, rpcMethodCosts stringList
+	)
+	flag.Var(&seeds, "seed", "Seed node to query for peer addresses (repeatable)")
+	flag.Var(&addNodes, "addnode", "Peer to stay connected to in addition to discovered peers (repeatable)")
+	flag.Var(&connect, "connect", "Connect only to this peer, disabling discovery (repeatable)")
+	flag.Var(&allowPeers, "allowpeer", "Node ID allowed to connect, requires -encrypt (repeatable)")
+	flag.Var(&rpcAllowIPs, "rpcallowip", "IP or CIDR range allowed to use RPC, all if none given (repeatable)")
+	flag.Var(&rpcTrustedProxies, "rpctrustedproxy", "IP or CIDR range of a proxy whose X-Forwarded-For header is trusted (repeatable)")
+	flag.Var(&rpcAllowOrigins, "rpcalloworigin", "Web origin whose pages may open RPC WebSockets, * for any (repeatable)")
+	flag.Var(&rpcMethodCosts, "rpcmethodcost", "Rate limit cost of an RPC method as method=cost, 1 if not given (repeatable)")
+	flag.Parse()
+
+	methodCosts, err := parseMethodCosts(rpcMethodCosts)
+	if err != nil {
+		log.Fatalf("ÔØî Invalid -rpcmethodcost: %v", err)
+	}
+	socketMode, err := strconv.ParseUint(*rpcSocketMode, 8, 32)
+	if err != nil || socketMode > 0777 {
+		log.Fatalf("ÔØî Invalid -rpcsocketmode: %s", *rpcSocketMode)
+	}
+	// Zero disables rate limits on the command line, which the RPC server takes as negative
+	if *rpcRateLimit == 0 {
+		*rpcRateLimit = -1
+	}
+
+	config := &NodeConfig{
+		DataDir: *dataDir,
+		Genesis: *genesis,
+		RPCAddr: *rpcAddr,
+		P2PAddr: *p2pAddr,
+
+		SeedNodes:   seeds,
+		AddNodes:    addNodes,
+		ConnectOnly: connect,
+		Outbound:    *outbound,
+		MaxInbound:  *maxInbound,
+
+		Encrypt:      *encrypt,
+		AllowedPeers: allowPeers,
+
+		RPCCookie: *rpcCookie,
+		RPCAuth:   *rpcAuth,
+		RPCAccess: rpc.AccessConfig{
+			AllowedNets:    rpcAllowIPs,
+			TrustedProxies: rpcTrustedProxies,
+			AllowedOrigins: rpcAllowOrigins,
+			RateLimit:      *rpcRateLimit,
+			LimitLoopback:  *rpcLimitLocal,
+			MethodCosts:    methodCosts,
+			MaxConcurrent:  *rpcMaxConcurrent,
+		},
+
+		RPCTLS:        *rpcTLS,
+		RPCTLSCert:    *rpcTLSCert,
+		RPCTLSKey:     *rpcTLSKey,
+		RPCSocket:     *rpcSocket,
+		RPCSocketMode: os.FileMode(socketMode),
+		RPCReadyPeers: *rpcReadyPeers,
+	}
+
+	node := NewNodeV2(config)
+
+	// Start node
+	if err := node.Start(); err != nil {
+		log.Fatalf("ÔØî Failed to start node: %v", err)
+	}
+
+	// Wait for shutdown signal
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	<-sigChan
+	log.Printf("­ƒøæ Shutdown signal received")
+
+	// Stop node
+	if err := node.Stop(); err != nil {
+		log.Printf("ÔØî Error stopping node: %v", err)
+	}
+}
+
+// parseMethodCosts parses method=cost pairs
+func parseMethodCosts(list []string) (map[string]int, error) {
+	costs := make(map[string]int, len(list))
+	for _, item := range list {
+		method, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected method=cost, got %q", item)
+		}
+		cost, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cost of %s: %q", method, value)
+		}
+		costs[method] = cost
+	}
+	return costs, nil
+}
+
+// NewNodeV2 creates a new professional node
+func NewNodeV2(config *NodeConfig) *NodeV2 {
+	return &NodeV2{
+		config: config,
+		quit:   make(chan struct{}),
+	}
+}
+
+// Start starts the node professionally
+func (n *NodeV2) Start() error {
+	log.Printf("­ƒÜÇ Starting Professional Kalon Node v2.0")
+	log.Printf("   Data Dir: %s", n.config.DataDir)
+	log.Printf("   Genesis: %s", n.config.Genesis)
+	log.Printf("   RPC: %s", n.config.RPCAddr)
+	log.Printf("   P2P: %s", n.config.P2PAddr)
+
+	// Load genesis configuration
+	genesis, err := n.loadGenesis()
+	if err != nil {
+		return err
+	}
+
+	// Initialize persistent storage
+	dbPath := n.config.DataDir + "/chaindb"
+	log.Printf("🔧 Initializing persistent storage at %s", dbPath)
+	levelDBStorage, err := storage.NewLevelDBStorage(dbPath)
+	if err != nil {
+		log.Printf("⚠️ Failed to initialize LevelDB: %v. Continuing in-memory mode.", err)
+		// Create blockchain without persistence
+		n.blockchain = core.NewBlockchainV2(genesis, nil)
+	} else {
+		// Create storage persister
+		persister := storage.NewBlockStorage(levelDBStorage)
+		n.blockchain = core.NewBlockchainV2(genesis, persister)
+	}
+	log.Printf("✅ Blockchain initialized with height: %d", n.blockchain.GetHeight())
+
+	// Create RPC server
+	var auth rpc.AuthConfig
+	if n.config.RPCAuth != "" {
+		if auth, err = rpc.LoadAuthConfig(n.config.RPCAuth); err != nil {
+			return err
+		}
+	}
+	n.rpcServer, err = rpc.NewServerV2WithConfig(rpc.ServerConfig{
+		Addr:    n.config.RPCAddr,
+		DataDir: n.config.DataDir,
+		Cookie:  n.config.RPCCookie,
+		Auth:    auth,
+		Access:  n.config.RPCAccess,
+
+		TLS:     n.config.RPCTLS,
+		TLSCert: n.config.RPCTLSCert,
+		TLSKey:  n.config.RPCTLSKey,
+
+		UnixSocket: n.config.RPCSocket,
+		SocketMode: n.config.RPCSocketMode,
+
+		ReadyMinPeers: n.config.RPCReadyPeers,
+	}, n.blockchain)
+	if err != nil {
+		return fmt.Errorf("invalid RPC configuration: %v", err)
+	}
+
+	// Start RPC server; a port already in use stops the node here
+	if err := n.rpcServer.Start(); err != nil {
+		n.blockchain.Close()
+		return fmt.Errorf("failed to start RPC server: %v", err)
+	}
+
+	// Initialize P2P network
+	p2pConfig := &network.P2PConfig{
+		ListenAddr:   n.config.P2PAddr,
+		SeedNodes:    n.config.SeedNodes,
+		MaxPeers:     50,
+		DialTimeout:  10 * time.Second,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		KeepAlive:    60 * time.Second,
+		// Reject oversized payloads before they reach the blockchain
+		MaxBlockBytes: int(genesis.MaxScheduledBlockBytes()),
+		MaxTxBytes:    int(genesis.MaxScheduledTxBytes()),
+		// Only peers on the same chain complete the handshake
+		ChainID:  genesis.ChainID,
+		Services: network.ServiceNodeNetwork,
+		// Peer discovery and the address book
+		AddNodes:       n.config.AddNodes,
+		ConnectOnly:    n.config.ConnectOnly,
+		TargetOutbound: n.config.Outbound,
+		MaxInbound:     n.config.MaxInbound,
+		DataDir:        n.config.DataDir,
+		// Authenticated transport
+		Encrypt:      n.config.Encrypt,
+		AllowedPeers: n.config.AllowedPeers,
+	}
+	n.p2p = network.NewP2P(p2pConfig)
+	n.p2p.SetTimeSource(n.blockchain.GetNetworkTime())
+	n.p2p.SetChain(n.blockchain)
+
+	// Start P2P server
+	if err := n.p2p.Start(); err != nil {
+		log.Printf("⚠️ Failed to start P2P: %v", err)
+	} else {
+		log.Printf("✅ P2P network started on %s", n.config.P2PAddr)
+	}
+
+	// Feed blocks and transactions from peers into the blockchain and relay accepted ones
+	n.rpcServer.SetP2P(n.p2p)
+	go n.processPeerBlocks()
+	go n.processPeerTransactions()
+
+	log.Printf("✅ Node started successfully")
+	n.running = true
+
+	return nil
+}
+
+// Stop stops the node gracefully
+func (n *NodeV2) Stop() error {
+	if !n.running {
+		return nil
+	}
+
+	log.Printf("­ƒøæ Stopping node...")
+	close(n.quit)
+
+	// Stop RPC server
+	if n.rpcServer != nil {
+		n.rpcServer.Stop()
+	}
+
+	// Stop P2P network
+	if n.p2p != nil {
+		n.p2p.Stop()
+	}
+
+	// Close blockchain storage
+	if n.blockchain != nil {
+		if err := n.blockchain.Close(); err != nil {
+			log.Printf("⚠️ Error closing blockchain: %v", err)
+		}
+	}
+
+	log.Printf("✅ Node stopped successfully")
+	n.running = false
+
+	return nil
+}
+
+// Blocks received ahead of our clock are kept and retried instead of rejected
+const (
+	futureBlockRetryInterval = 10 * time.Second
+	maxFutureBlocks          = 16
+)
+
+// processPeerBlocks validates blocks received from peers and relays accepted ones
+func (n *NodeV2) processPeerBlocks() {
+	var future []*network.ReceivedBlock
+	retry := time.NewTicker(futureBlockRetryInterval)
+	defer retry.Stop()
+
+	for {
+		select {
+		case <-n.quit:
+			return
+		case <-retry.C:
+			pending := future
+			future = nil
+			for _, received := range pending {
+				if n.acceptPeerBlock(received) {
+					future = append(future, received)
+				}
+			}
+		case received := <-n.p2p.GetBlockChannel():
+			if n.acceptPeerBlock(received) {
+				if len(future) == maxFutureBlocks {
+					future = future[1:]
+				}
+				future = append(future, received)
+			}
+		}
+	}
+}
+
+// acceptPeerBlock adds a block received from a peer and relays it. It reports
+// whether the block is too far in the future to accept yet and should be retried.
+func (n *NodeV2) acceptPeerBlock(received *network.ReceivedBlock) bool {
+	block := received.Block
+
+	// Blocks at or below our height are duplicates or stale forks
+	if block.Header.Number <= n.blockchain.GetHeight() {
+		return false
+	}
+
+	// Only a block that extends our tip is known to be invalid rather than just out of order
+	best := n.blockchain.GetBestBlock()
+	extendsTip := block.Header.Number == best.Header.Number+1 && block.Header.ParentHash == best.Hash
+
+	if err := n.blockchain.AddBlockV2(block); err != nil {
+		// Clocks drift, so a block from the near future is retried once our time catches up
+		if errors.Is(err, core.ErrFutureBlock) {
+			log.Printf("⏳ Deferring block #%d from peer %s: %v", block.Header.Number, received.PeerID, err)
+			return true
+		}
+		log.Printf("⚠️ Rejected block #%d from peer %s: %v", block.Header.Number, received.PeerID, err)
+		// Sync may have moved the tip meanwhile, making the rejection a duplicate
+		if extendsTip && n.blockchain.GetBestBlock().Hash == best.Hash {
+			n.p2p.Misbehaving(received.PeerID, network.BanThreshold, fmt.Sprintf("invalid block #%d: %v", block.Header.Number, err))
+		}
+		return false
+	}
+
+	if err := n.p2p.RelayBlock(block, received.PeerID); err != nil {
+		log.Printf("⚠️ Failed to relay block #%d: %v", block.Header.Number, err)
+	}
+	return false
+}
+
+// processPeerTransactions adds transactions received from peers to the mempool and relays accepted ones
+func (n *NodeV2) processPeerTransactions() {
+	for {
+		select {
+		case <-n.quit:
+			return
+		case received := <-n.p2p.GetTransactionChannel():
+			tx := received.Tx
+
+			// Already known transactions have been relayed before
+			if n.blockchain.GetMempool().HasTransaction(tx.Hash) {
+				continue
+			}
+
+			// Peers relay the same signed transactions wallets send, so they are validated alike.
+			// Spends of missing, spent or immature outputs can lose a race with a block or
+			// the mempool, so only transactions that can never be valid count against the peer.
+			if err := n.blockchain.AcceptRawTransaction(tx); err != nil {
+				log.Printf("⚠️ Rejected transaction %x from peer %s: %v", tx.Hash, received.PeerID, err)
+				if errors.Is(err, core.ErrInvalidTransaction) {
+					n.p2p.Misbehaving(received.PeerID, 10, fmt.Sprintf("invalid transaction %x: %v", tx.Hash, err))
+				}
+				continue
+			}
+
+			if err := n.p2p.RelayTransaction(tx, received.PeerID); err != nil {
+				log.Printf("⚠️ Failed to relay transaction %x: %v", tx.Hash, err)
+			}
+		}
+	}
+}
+
+// loadGenesis loads the genesis configuration
+func (n *NodeV2) loadGenesis() (*core.GenesisConfig, error) {
+	// Load genesis from file
+	data, err := os.ReadFile(n.config.Genesis)
+	if err != nil {
+		log.Printf("⚠️ Failed to read genesis file %s: %v. Using defaults.", n.config.Genesis, err)
+		retarget, maturity := true, uint64(20)
+		// Return default genesis with proper difficulty
+		return &core.GenesisConfig{
+			ChainID:            7718,
+			Name:               "Kalon Testnet",
+			Symbol:             "tKALON",
+			BlockTimeTarget:    30,
+			MaxSupply:          1000000000,
+			InitialBlockReward: 5.0,
+			HalvingSchedule:    []core.HalvingEvent{},
+			Difficulty: core.DifficultyConfig{
+				Algo:                 "LWMA",
+				Window:               120,
+				InitialDifficulty:    5000,
+				MaxAdjustPerBlockPct: 25,
+				LaunchGuard: core.LaunchGuard{
+					Enabled:                   true,
+					DurationHours:             24,
+					DifficultyFloorMultiplier: 4.0,
+					InitialReward:             2.0,
+				},
+			},
+			AddressFormat: core.AddressFormat{
+				Type: "bech32",
+				HRP:  "tkalon",
+			},
+			Premine: core.PremineConfig{
+				Enabled: false,
+			},
+			TreasuryAddress: "tkalon1treasury0000000000000000000000000000000000000000000000000000000000",
+			NetworkFee: core.NetworkFeeConfig{
+				BlockFeeRate:       0.05,
+				TxFeeShareTreasury: 0.20,
+				BaseTxFee:          0.01,
+				GasPrice:           1000,
+			},
+			Governance: core.GovernanceConfig{
+				Parameters: core.GovernanceParameters{
+					NetworkFeeRate:     0.05,
+					TxFeeShareTreasury: 0.20,
+					TreasuryCapPercent: 10,
+				},
+			},
+			Forks: []core.ConsensusFork{
+				{Name: "difficultyRetarget", Height: 400000, DifficultyRetarget: &retarget},
+				{Name: "consensusLimits", Height: 500000, CoinbaseMaturity: &maturity},
+			},
+			Upgrades: []core.NetworkUpgrade{
+				{Name: core.UpgradeMedianTimePast, Height: 500000},
+				{Name: core.UpgradeStrictDifficulty, Height: 400000},
+				{Name: core.UpgradeCoinbaseLimit, Height: 500000},
+				{Name: core.UpgradeTxCommitments, Height: 500000},
+			},
+		}, nil
+	}
+
+	// Parse JSON
+	var genesis core.GenesisConfig
+	if err := json.Unmarshal(data, &genesis); err != nil {
+		return nil, fmt.Errorf("failed to parse genesis JSON: %w", err)
+	}
+
+	if err := genesis.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid genesis: %w", err)
+	}
+
+	log.Printf("✅ Loaded genesis from %s", n.config.Genesis)
+	return &genesis, nil
+}
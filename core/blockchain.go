@@ -1,717 +1,916 @@
-package core
-
-import (
-	"encoding/binary"
-	"encoding/hex"
-	"fmt"
-	"log"
-	"sync"
-	"time"
-)
-
-// BlockchainV2 represents a professional blockchain implementation
-type BlockchainV2 struct {
-	mu           sync.RWMutex
-	blocks       []*Block
-	height       uint64
-	bestBlock    *Block
-	genesis      *GenesisConfig
-	consensus    *ConsensusV2
-	eventBus     *EventBus
-	stateManager *StateManager
-	utxoSet      *UTXOSet
-	mempool      *Mempool
-	storage      BlockPersister // Interface for persistent storage
-}
-
-// BlockPersister defines the interface for persisting blocks
-type BlockPersister interface {
-	StoreBlock(block *Block) error
-	GetBlockByNumber(number uint64) (*Block, error)
-	GetBlockByHash(hash []byte) (*Block, error)
-	GetBestBlock() (*Block, error)
-	GetBlockCount() (uint64, error)
-	Close() error
-}
-
-// Mempool manages pending transactions
-type Mempool struct {
-	mu           sync.RWMutex
-	transactions map[string]*Transaction // Key: transaction hash
-}
-
-// EventBus handles blockchain events
-type EventBus struct {
-	mu       sync.RWMutex
-	channels map[string][]chan interface{}
-}
-
-// StateManager manages blockchain state
-type StateManager struct {
-	mu    sync.RWMutex
-	state map[string]interface{}
-}
-
-// ConsensusV2 represents professional consensus mechanism
-type ConsensusV2 struct {
-	mu         sync.RWMutex
-	difficulty uint64
-	target     uint64
-	blockTime  time.Duration
-	adjustment *DifficultyAdjustment
-}
-
-// DifficultyAdjustment handles LWMA difficulty adjustment
-type DifficultyAdjustment struct {
-	mu          sync.RWMutex
-	windowSize  int
-	blockTimes  []time.Time
-	adjustments []uint64
-}
-
-// NewBlockchainV2 creates a new professional blockchain
-func NewBlockchainV2(genesis *GenesisConfig, persister BlockPersister) *BlockchainV2 {
-	bc := &BlockchainV2{
-		blocks:       make([]*Block, 0),
-		height:       0,
-		genesis:      genesis,
-		consensus:    NewConsensusV2(),
-		eventBus:     NewEventBus(),
-		stateManager: NewStateManager(),
-		utxoSet:      NewUTXOSet(),
-		mempool:      NewMempool(),
-		storage:      persister,
-	}
-
-	// Try to load existing chain from storage
-	if bc.storage != nil {
-		bc.loadChainFromStorage()
-	}
-
-	// Create genesis block if chain is empty
-	if bc.height == 0 {
-		genesisBlock := bc.createGenesisBlockV2()
-		bc.addBlockV2(genesisBlock)
-	}
-
-	return bc
-}
-
-// NewEventBus creates a new event bus
-func NewEventBus() *EventBus {
-	return &EventBus{
-		channels: make(map[string][]chan interface{}),
-	}
-}
-
-// NewStateManager creates a new state manager
-func NewStateManager() *StateManager {
-	return &StateManager{
-		state: make(map[string]interface{}),
-	}
-}
-
-// NewConsensusV2 creates a new consensus mechanism
-func NewConsensusV2() *ConsensusV2 {
-	return &ConsensusV2{
-		difficulty: 10,            // Default difficulty for testnet
-		target:     1 << (64 - 1), // 1 difficulty = 2^63 target
-		blockTime:  15 * time.Second,
-		adjustment: NewDifficultyAdjustment(),
-	}
-}
-
-// NewDifficultyAdjustment creates a new difficulty adjustment
-func NewDifficultyAdjustment() *DifficultyAdjustment {
-	return &DifficultyAdjustment{
-		windowSize:  144, // 24 hours at 30s blocks
-		blockTimes:  make([]time.Time, 0),
-		adjustments: make([]uint64, 0),
-	}
-}
-
-// createGenesisBlockV2 creates the genesis block with professional approach
-func (bc *BlockchainV2) createGenesisBlockV2() *Block {
-	genesisTimestamp := time.Unix(1609459200, 0) // 2021-01-01 00:00:00 UTC
-
-	genesisBlock := &Block{
-		Header: BlockHeader{
-			ParentHash:  Hash{},
-			Number:      0,
-			Timestamp:   genesisTimestamp,
-			Difficulty:  bc.genesis.Difficulty.InitialDifficulty,
-			Miner:       Address{},
-			Nonce:       0,
-			MerkleRoot:  Hash{},
-			TxCount:     0,
-			NetworkFee:  0,
-			TreasuryFee: 0,
-		},
-		Txs:  []Transaction{},
-		Hash: Hash{},
-	}
-
-	// Calculate hash using deterministic method
-	genesisBlock.Hash = genesisBlock.CalculateHash()
-
-	return genesisBlock
-}
-
-// addBlockV2 adds a block atomically
-func (bc *BlockchainV2) addBlockV2(block *Block) error {
-	// CRITICAL: Hold lock only for in-memory operations
-	// Storage operations happen AFTER lock release to prevent blocking read operations
-	bc.mu.Lock()
-
-	// Validate block
-	if err := bc.validateBlockV2(block); err != nil {
-		bc.mu.Unlock()
-		return fmt.Errorf("block validation failed: %v", err)
-	}
-
-	// Process UTXOs for all transactions in the block
-	for _, tx := range block.Txs {
-		bc.processTransactionUTXOs(&tx, block.Hash)
-		// Remove from mempool if it exists
-		bc.mempool.RemoveTransaction(tx.Hash)
-	}
-
-	// Add block atomically to in-memory structures
-	bc.blocks = append(bc.blocks, block)
-	bc.height = block.Header.Number
-	bc.bestBlock = block
-
-	// Update state
-	bc.stateManager.SetState("height", bc.height)
-	bc.stateManager.SetState("bestBlock", block.Hash)
-
-	// Emit event
-	bc.eventBus.Emit("blockAdded", map[string]interface{}{
-		"block":  block,
-		"height": bc.height,
-	})
-
-	// CRITICAL: Release lock BEFORE slow storage operations
-	// This allows createBlockTemplate and other read operations to proceed immediately
-	bc.mu.Unlock()
-
-	// Save to persistent storage AFTER lock release
-	// This I/O operation can take 100-500ms and should not block read operations
-	if bc.storage != nil {
-		if err := bc.storage.StoreBlock(block); err != nil {
-			log.Printf("⚠️ Failed to save block to storage: %v", err)
-		} else {
-			log.Printf("✅ Block #%d saved to storage", block.Header.Number)
-		}
-	}
-
-	log.Printf("✅ Block #%d added successfully: %x", block.Header.Number, block.Hash)
-
-	return nil
-}
-
-// CreateTransaction creates a transaction from UTXOs
-// Note: Transaction must be signed separately using crypto.SignTransaction()
-func (bc *BlockchainV2) CreateTransaction(from Address, to Address, amount uint64, fee uint64) (*Transaction, error) {
-	// Get UTXOs for sender
-	utxos := bc.utxoSet.GetUTXOs(from)
-
-	// Calculate total available balance
-	totalBalance := uint64(0)
-	for _, utxo := range utxos {
-		totalBalance += utxo.Amount
-	}
-
-	if totalBalance < amount+fee {
-		return nil, fmt.Errorf("insufficient balance: need %d, have %d", amount+fee, totalBalance)
-	}
-
-	// Create inputs
-	inputs := []TxInput{}
-	totalInput := uint64(0)
-	for _, utxo := range utxos {
-		if totalInput >= amount+fee {
-			break
-		}
-		inputs = append(inputs, TxInput{
-			PreviousTxHash: utxo.TxHash,
-			Index:          utxo.Index,
-			Signature:      []byte{}, // Signature will be added using crypto.SignTransaction()
-		})
-		totalInput += utxo.Amount
-	}
-
-	// Create outputs
-	outputs := []TxOutput{
-		{Address: to, Amount: amount},
-	}
-
-	// Add change output if needed
-	change := totalInput - amount - fee
-	if change > 0 {
-		outputs = append(outputs, TxOutput{Address: from, Amount: change})
-	}
-
-	// Create transaction
-	tx := &Transaction{
-		From:      from,
-		To:        to,
-		Amount:    amount,
-		Fee:       fee,
-		Timestamp: time.Now(),
-		Inputs:    inputs,
-		Outputs:   outputs,
-		Signature: []byte{}, // Will be set when signed
-	}
-
-	// Calculate hash
-	tx.Hash = tx.CalculateHash()
-
-	return tx, nil
-}
-
-// processTransactionUTXOs processes UTXOs for a transaction
-func (bc *BlockchainV2) processTransactionUTXOs(tx *Transaction, blockHash Hash) {
-	// Mark input UTXOs as spent
-	for _, input := range tx.Inputs {
-		bc.utxoSet.SpendUTXO(input.PreviousTxHash, input.Index)
-	}
-
-	// Create new UTXOs for outputs
-	for i, output := range tx.Outputs {
-		bc.utxoSet.AddUTXO(tx.Hash, uint32(i), output.Amount, output.Address, blockHash)
-		LogDebug("UTXO created - Address: %s, Amount: %d, TxHash: %x", hex.EncodeToString(output.Address[:]), output.Amount, tx.Hash)
-	}
-}
-
-// AddBlockV2 is the main function for adding blocks - ensures UTXO processing
-func (bc *BlockchainV2) AddBlockV2(block *Block) error {
-	return bc.addBlockV2(block)
-}
-
-// GetBalance returns the balance for an address
-func (bc *BlockchainV2) GetBalance(address Address) uint64 {
-	return bc.utxoSet.GetBalance(address)
-}
-
-// GetUTXOs returns all UTXOs for an address
-func (bc *BlockchainV2) GetUTXOs(address Address) []*UTXO {
-	return bc.utxoSet.GetUTXOs(address)
-}
-
-// GetMempool returns the mempool
-func (bc *BlockchainV2) GetMempool() *Mempool {
-	return bc.mempool
-}
-
-// validateBlockV2 validates a block professionally
-func (bc *BlockchainV2) validateBlockV2(block *Block) error {
-	// Check if it's genesis block
-	if block.Header.Number == 0 {
-		return nil
-	}
-
-	// Get parent block
-	parent := bc.bestBlock
-	if parent == nil {
-		return fmt.Errorf("no parent block found")
-	}
-
-	// Validate parent hash
-	if block.Header.ParentHash != parent.Hash {
-		return fmt.Errorf("invalid parent hash: expected %x, got %x", parent.Hash, block.Header.ParentHash)
-	}
-
-	// Validate block number
-	if block.Header.Number != parent.Header.Number+1 {
-		return fmt.Errorf("invalid block number: expected %d, got %d", parent.Header.Number+1, block.Header.Number)
-	}
-
-	// Validate timestamp
-	if block.Header.Timestamp.Before(parent.Header.Timestamp) {
-		return fmt.Errorf("block timestamp before parent: %v < %v", block.Header.Timestamp, parent.Header.Timestamp)
-	}
-
-	// Validate proof of work
-	if !bc.consensus.ValidateProofOfWorkV2(block) {
-		return fmt.Errorf("invalid proof of work")
-	}
-
-	return nil
-}
-
-// GetBestBlock returns the best block thread-safely
-func (bc *BlockchainV2) GetBestBlock() *Block {
-	bc.mu.RLock()
-	defer bc.mu.RUnlock()
-	return bc.bestBlock
-}
-
-// GetRecentBlocks returns the most recent blocks
-func (bc *BlockchainV2) GetRecentBlocks(limit int) []*Block {
-	bc.mu.RLock()
-	defer bc.mu.RUnlock()
-
-	if len(bc.blocks) == 0 {
-		return []*Block{}
-	}
-
-	// Get the last 'limit' blocks
-	start := len(bc.blocks) - limit
-	if start < 0 {
-		start = 0
-	}
-
-	// Create a slice in reverse order (newest first)
-	result := make([]*Block, 0, limit)
-	for i := len(bc.blocks) - 1; i >= start; i-- {
-		result = append(result, bc.blocks[i])
-		if len(result) >= limit {
-			break
-		}
-	}
-
-	return result
-}
-
-// GetHeight returns the current height thread-safely
-func (bc *BlockchainV2) GetHeight() uint64 {
-	bc.mu.RLock()
-	defer bc.mu.RUnlock()
-	return bc.height
-}
-
-// GetConsensus returns the consensus mechanism
-func (bc *BlockchainV2) GetConsensus() *ConsensusV2 {
-	return bc.consensus
-}
-
-// GetGenesis returns the genesis configuration
-func (bc *BlockchainV2) GetGenesis() *GenesisConfig {
-	return bc.genesis
-}
-
-// GetEventBus returns the event bus
-func (bc *BlockchainV2) GetEventBus() *EventBus {
-	return bc.eventBus
-}
-
-// NewMempool creates a new mempool
-func NewMempool() *Mempool {
-	return &Mempool{
-		transactions: make(map[string]*Transaction),
-	}
-}
-
-// AddTransaction adds a transaction to the mempool
-func (m *Mempool) AddTransaction(tx *Transaction) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.transactions[hex.EncodeToString(tx.Hash[:])] = tx
-	log.Printf("📥 Transaction added to mempool: %x", tx.Hash)
-}
-
-// GetPendingTransactions returns all pending transactions
-func (m *Mempool) GetPendingTransactions() []*Transaction {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	var txs []*Transaction
-	for _, tx := range m.transactions {
-		txs = append(txs, tx)
-	}
-	return txs
-}
-
-// RemoveTransaction removes a transaction from the mempool
-func (m *Mempool) RemoveTransaction(txHash Hash) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	delete(m.transactions, hex.EncodeToString(txHash[:]))
-}
-
-// Clear removes all transactions from the mempool
-func (m *Mempool) Clear() {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.transactions = make(map[string]*Transaction)
-}
-
-// CreateNewBlockV2 creates a new block template professionally
-func (bc *BlockchainV2) CreateNewBlockV2(miner Address, txs []Transaction) *Block {
-	bc.mu.RLock()
-	parent := bc.bestBlock
-	bc.mu.RUnlock()
-
-	if parent == nil {
-		return nil
-	}
-
-	// Calculate difficulty using ConsensusManager (uses Genesis config)
-	consensusManager := NewConsensusManager(bc.genesis)
-	difficulty := consensusManager.CalculateDifficulty(parent.Header.Number+1, parent)
-
-	// Create block reward transaction
-	blockReward := bc.calculateBlockReward(parent.Header.Number + 1)
-	rewardTx := bc.createBlockRewardTransaction(miner, blockReward)
-
-	// Get pending transactions from mempool
-	pendingTxs := bc.mempool.GetPendingTransactions()
-	for _, tx := range pendingTxs {
-		txs = append(txs, *tx)
-	}
-
-	// Add reward transaction to the beginning of transactions
-	allTxs := append([]Transaction{rewardTx}, txs...)
-
-	// Create block template
-	block := &Block{
-		Header: BlockHeader{
-			ParentHash:  parent.Hash, // CRITICAL: Use actual parent hash
-			Number:      parent.Header.Number + 1,
-			Timestamp:   time.Now(),
-			Difficulty:  difficulty,
-			Miner:       miner,
-			Nonce:       0,
-			MerkleRoot:  Hash{}, // TODO: Calculate merkle root
-			TxCount:     uint32(len(allTxs)),
-			NetworkFee:  0,
-			TreasuryFee: 0,
-		},
-		Txs:  allTxs,
-		Hash: Hash{},
-	}
-
-	// Calculate hash
-	block.Hash = block.CalculateHash()
-
-	return block
-}
-
-// calculateBlockReward calculates the block reward for a given block number
-func (bc *BlockchainV2) calculateBlockReward(blockNumber uint64) uint64 {
-	// Start with initial block reward (5 tKALON = 5,000,000 units)
-	reward := uint64(bc.genesis.InitialBlockReward * 1000000) // Convert to smallest units
-
-	// Apply halving schedule
-	for _, halving := range bc.genesis.HalvingSchedule {
-		if blockNumber > halving.AfterBlocks {
-			reward = uint64(float64(reward) * halving.RewardMultiplier)
-		}
-	}
-
-	return reward
-}
-
-// createBlockRewardTransaction creates a block reward transaction
-func (bc *BlockchainV2) createBlockRewardTransaction(miner Address, amount uint64) Transaction {
-	log.Printf("🔍 DEBUG createBlockRewardTransaction - Miner address: %x, Amount: %d", miner, amount)
-
-	// Create a special coinbase transaction (no inputs, only output)
-	tx := Transaction{
-		From:      Address{}, // Empty for coinbase
-		To:        miner,
-		Amount:    amount,
-		Nonce:     0,
-		Fee:       0,
-		GasUsed:   0,
-		GasPrice:  0,
-		Data:      []byte("block_reward"),
-		Signature: []byte{},    // No signature needed for coinbase
-		Inputs:    []TxInput{}, // No inputs for coinbase
-		Outputs: []TxOutput{
-			{
-				Address: miner,
-				Amount:  amount,
-			},
-		},
-		Timestamp: time.Now(),
-	}
-
-	log.Printf("🔍 DEBUG createBlockRewardTransaction - Created TX with output address: %x", tx.Outputs[0].Address)
-
-	// Calculate transaction hash
-	tx.Hash = CalculateTransactionHash(&tx)
-
-	return tx
-}
-
-// AddBlock adds a block to the blockchain
-func (bc *BlockchainV2) AddBlock(block *Block) error {
-	return bc.addBlockV2(block)
-}
-
-// loadChainFromStorage loads the blockchain from persistent storage
-func (bc *BlockchainV2) loadChainFromStorage() {
-	log.Printf("🔍 DEBUG: loadChainFromStorage called")
-	if bc.storage == nil {
-		log.Printf("⚠️ Storage is nil, skipping load")
-		return
-	}
-
-	log.Printf("🔍 DEBUG: Getting best block from storage")
-	// Get the best block
-	bestBlock, err := bc.storage.GetBestBlock()
-	if err != nil {
-		log.Printf("⚠️ No existing chain found, starting fresh (error: %v)", err)
-		return
-	}
-
-	if bestBlock == nil {
-		log.Printf("⚠️ Best block is nil, starting fresh")
-		return
-	}
-
-	log.Printf("🔍 DEBUG: Best block found - Height: %d, Hash: %x", bestBlock.Header.Number, bestBlock.Hash)
-
-	// Reconstruct chain by loading blocks from storage
-	bc.height = bestBlock.Header.Number
-	bc.bestBlock = bestBlock
-
-	log.Printf("🔍 DEBUG: Loading %d blocks from storage", bc.height+1)
-
-	// Load all blocks from genesis to best block
-	for i := uint64(0); i <= bc.height; i++ {
-		block, err := bc.storage.GetBlockByNumber(i)
-		if err != nil || block == nil {
-			log.Printf("⚠️ Failed to load block %d: %v", i, err)
-			// Reset and start fresh
-			bc.height = 0
-			bc.bestBlock = nil
-			bc.blocks = make([]*Block, 0)
-			return
-		}
-		bc.blocks = append(bc.blocks, block)
-
-		// IMPORTANT: Reconstruct UTXOs for each block
-		// This is critical because UTXOs are in-memory and need to be rebuilt
-		for _, tx := range block.Txs {
-			bc.processTransactionUTXOs(&tx, block.Hash)
-		}
-	}
-
-	log.Printf("✅ Loaded blockchain from storage - Height: %d, UTXOs restored", bc.height)
-}
-
-// Close closes the blockchain and its storage
-func (bc *BlockchainV2) Close() error {
-	if bc.storage != nil {
-		return bc.storage.Close()
-	}
-	return nil
-}
-
-// ValidateProofOfWorkV2 validates proof of work professionally
-func (c *ConsensusV2) ValidateProofOfWorkV2(block *Block) bool {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	// For testnet, allow easier validation only for very low difficulty (1-20)
-	// Difficulty 21+ requires real PoW validation for fairness and stability
-	if block.Header.Difficulty <= 20 {
-		return true
-	}
-
-	// Calculate target (simplified for testnet)
-	target := uint64(1) << (64 - block.Header.Difficulty)
-
-	// Check if hash meets target
-	hashInt := binary.BigEndian.Uint64(block.Hash[:8])
-	return hashInt < target
-}
-
-// CalculateDifficultyV2 calculates difficulty professionally
-func (c *ConsensusV2) CalculateDifficultyV2(blockNumber uint64, parent *Block) uint64 {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// Use parent difficulty if available
-	if parent != nil {
-		return parent.Header.Difficulty
-	}
-
-	// Default difficulty for genesis or fallback (should match testnet config)
-	return 10
-}
-
-// CalculateDifficulty calculates difficulty using LWMA
-func (da *DifficultyAdjustment) CalculateDifficulty(blockNumber uint64, parent *Block) uint64 {
-	da.mu.Lock()
-	defer da.mu.Unlock()
-
-	// Add current block time
-	da.blockTimes = append(da.blockTimes, parent.Header.Timestamp)
-
-	// Keep only window size
-	if len(da.blockTimes) > da.windowSize {
-		da.blockTimes = da.blockTimes[1:]
-	}
-
-	// Need at least 2 blocks for adjustment
-	if len(da.blockTimes) < 2 {
-		return 4
-	}
-
-	// Calculate average block time
-	totalTime := da.blockTimes[len(da.blockTimes)-1].Sub(da.blockTimes[0])
-	avgBlockTime := totalTime / time.Duration(len(da.blockTimes)-1)
-
-	// Target block time
-	targetTime := 15 * time.Second
-
-	// Calculate adjustment factor
-	adjustmentFactor := float64(targetTime) / float64(avgBlockTime)
-
-	// Apply adjustment
-	newDifficulty := uint64(float64(parent.Header.Difficulty) * adjustmentFactor)
-
-	// Clamp difficulty
-	if newDifficulty < 1 {
-		newDifficulty = 1
-	}
-	if newDifficulty > 1000 {
-		newDifficulty = 1000
-	}
-
-	return newDifficulty
-}
-
-// Emit emits an event
-func (eb *EventBus) Emit(event string, data interface{}) {
-	eb.mu.RLock()
-	channels := eb.channels[event]
-	eb.mu.RUnlock()
-
-	for _, ch := range channels {
-		select {
-		case ch <- data:
-		default:
-			// Channel is full, skip
-		}
-	}
-}
-
-// Subscribe subscribes to an event
-func (eb *EventBus) Subscribe(event string) <-chan interface{} {
-	eb.mu.Lock()
-	defer eb.mu.Unlock()
-
-	ch := make(chan interface{}, 100) // Buffered channel
-	eb.channels[event] = append(eb.channels[event], ch)
-
-	return ch
-}
-
-// SetState sets a state value
-func (sm *StateManager) SetState(key string, value interface{}) {
-	sm.mu.Lock()
-	defer sm.mu.Unlock()
-	sm.state[key] = value
-}
-
-// GetState gets a state value
-func (sm *StateManager) GetState(key string) interface{} {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
-	return sm.state[key]
-}
+package core
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BlockchainV2 represents a professional blockchain implementation
+type BlockchainV2 struct {
+	mu           sync.RWMutex
+	blocks       []*Block
+	blockIndex   map[Hash]*Block // Blocks on the main chain by hash
+	txIndex      map[Hash]*Block // Main chain blocks by the hash of each transaction they contain
+	height       uint64
+	bestBlock    *Block
+	genesis      *GenesisConfig
+	rules        Rules
+	eventBus     *EventBus
+	stateManager *StateManager
+	utxoSet      *UTXOSet
+	mempool      *Mempool
+	storage      BlockPersister // Interface for persistent storage
+	networkTime  *NetworkTime   // Peer-adjusted clock for timestamp validation
+
+	storageMu  sync.Mutex
+	storageErr error // Error of the last failed block write, nil once a write succeeds
+}
+
+// BlockPersister defines the interface for persisting blocks
+type BlockPersister interface {
+	StoreBlock(block *Block) error
+	GetBlockByNumber(number uint64) (*Block, error)
+	GetBlockByHash(hash []byte) (*Block, error)
+	GetBestBlock() (*Block, error)
+	GetBlockCount() (uint64, error)
+	Close() error
+}
+
+// HealthChecker is implemented by persisters that can report whether they are usable
+type HealthChecker interface {
+	CheckHealth() error
+}
+
+// Mempool manages pending transactions
+type Mempool struct {
+	mu           sync.RWMutex
+	transactions map[string]*Transaction // Key: transaction hash
+	added        map[string]time.Time    // When each transaction entered the mempool
+	sequence     uint64                  // Incremented on every change
+}
+
+// MempoolEntry describes a pending transaction
+type MempoolEntry struct {
+	Tx    *Transaction
+	Added time.Time // When the transaction entered the mempool
+}
+
+// Event names emitted on the EventBus
+const (
+	EventBlockAdded       = "blockAdded"       // A block extended the main chain; data is {"block", "height"}
+	EventTransactionAdded = "transactionAdded" // A transaction entered the mempool; data is the *Transaction
+)
+
+// EventBus handles blockchain events
+type EventBus struct {
+	mu       sync.RWMutex
+	channels map[string][]chan interface{}
+}
+
+// StateManager manages blockchain state
+type StateManager struct {
+	mu    sync.RWMutex
+	state map[string]interface{}
+}
+
+// blockHeaderReserveBytes is the space kept free for the header when filling a block template
+const blockHeaderReserveBytes = 1024
+
+// NewBlockchainV2 creates a new professional blockchain
+func NewBlockchainV2(genesis *GenesisConfig, persister BlockPersister) *BlockchainV2 {
+	bc := &BlockchainV2{
+		blocks:       make([]*Block, 0),
+		blockIndex:   make(map[Hash]*Block),
+		txIndex:      make(map[Hash]*Block),
+		height:       0,
+		genesis:      genesis,
+		rules:        NewRules(genesis),
+		eventBus:     NewEventBus(),
+		stateManager: NewStateManager(),
+		utxoSet:      NewUTXOSet(),
+		mempool:      NewMempool(),
+		storage:      persister,
+		networkTime:  NewNetworkTime(),
+	}
+
+	// Try to load existing chain from storage
+	if bc.storage != nil {
+		bc.loadChainFromStorage()
+	}
+
+	// Create genesis block if chain is empty
+	if bc.height == 0 {
+		genesisBlock := bc.createGenesisBlockV2()
+		bc.addBlockV2(genesisBlock)
+	}
+
+	return bc
+}
+
+// NewEventBus creates a new event bus
+func NewEventBus() *EventBus {
+	return &EventBus{
+		channels: make(map[string][]chan interface{}),
+	}
+}
+
+// NewStateManager creates a new state manager
+func NewStateManager() *StateManager {
+	return &StateManager{
+		state: make(map[string]interface{}),
+	}
+}
+
+// createGenesisBlockV2 creates the genesis block with professional approach
+func (bc *BlockchainV2) createGenesisBlockV2() *Block {
+	genesisTimestamp := time.Unix(1609459200, 0) // 2021-01-01 00:00:00 UTC
+
+	genesisBlock := &Block{
+		Header: BlockHeader{
+			ParentHash:  Hash{},
+			Number:      0,
+			Timestamp:   genesisTimestamp,
+			Difficulty:  bc.genesis.Difficulty.InitialDifficulty,
+			Miner:       Address{},
+			Nonce:       0,
+			MerkleRoot:  Hash{},
+			TxCount:     0,
+			NetworkFee:  0,
+			TreasuryFee: 0,
+		},
+		Txs:  []Transaction{},
+		Hash: Hash{},
+	}
+
+	// Calculate hash using deterministic method
+	genesisBlock.Hash = genesisBlock.CalculateHash()
+
+	return genesisBlock
+}
+
+// addBlockV2 adds a block atomically
+func (bc *BlockchainV2) addBlockV2(block *Block) error {
+	// CRITICAL: Hold lock only for in-memory operations
+	// Storage operations happen AFTER lock release to prevent blocking read operations
+	bc.mu.Lock()
+
+	// Validate block
+	if err := bc.validateBlockV2(block); err != nil {
+		bc.mu.Unlock()
+		return fmt.Errorf("block validation failed: %w", err)
+	}
+
+	bc.connectBlock(block)
+
+	// Emit event
+	bc.eventBus.Emit(EventBlockAdded, map[string]interface{}{
+		"block":  block,
+		"height": bc.height,
+	})
+
+	// CRITICAL: Release lock BEFORE slow storage operations
+	// This allows createBlockTemplate and other read operations to proceed immediately
+	bc.mu.Unlock()
+
+	// Save to persistent storage AFTER lock release
+	// This I/O operation can take 100-500ms and should not block read operations
+	bc.storeBlock(block)
+
+	log.Printf("✅ Block #%d added successfully: %x", block.Header.Number, block.Hash)
+
+	return nil
+}
+
+// connectBlock makes a validated block the new tip (caller holds the lock)
+func (bc *BlockchainV2) connectBlock(block *Block) {
+	// Process UTXOs for all transactions in the block
+	for _, tx := range block.Txs {
+		bc.processTransactionUTXOs(&tx, block.Hash, block.Header.Number)
+		bc.txIndex[tx.Hash] = block
+		// Remove from mempool if it exists
+		bc.mempool.RemoveTransaction(tx.Hash)
+	}
+
+	// Add block atomically to in-memory structures
+	bc.blocks = append(bc.blocks, block)
+	bc.blockIndex[block.Hash] = block
+	bc.height = block.Header.Number
+	bc.bestBlock = block
+
+	// Update state
+	bc.stateManager.SetState("height", bc.height)
+	bc.stateManager.SetState("bestBlock", block.Hash)
+}
+
+// storeBlock saves a block to persistent storage, if any, and records the outcome
+func (bc *BlockchainV2) storeBlock(block *Block) {
+	if bc.storage == nil {
+		return
+	}
+
+	err := bc.storage.StoreBlock(block)
+	if err != nil {
+		log.Printf("⚠️ Failed to save block to storage: %v", err)
+	} else {
+		log.Printf("✅ Block #%d saved to storage", block.Header.Number)
+	}
+	bc.storageMu.Lock()
+	bc.storageErr = err
+	bc.storageMu.Unlock()
+}
+
+// CreateTransaction creates a transaction from UTXOs
+// Note: Transaction must be signed separately using crypto.SignTransaction()
+func (bc *BlockchainV2) CreateTransaction(from Address, to Address, amount uint64, fee uint64) (*Transaction, error) {
+	// Get UTXOs for sender that can be spent in the next block (immature rewards are skipped)
+	utxos := bc.utxoSet.GetSpendableUTXOs(from, bc.GetHeight()+1, bc.coinbaseMaturity())
+
+	// Calculate total available balance
+	totalBalance := uint64(0)
+	for _, utxo := range utxos {
+		totalBalance += utxo.Amount
+	}
+
+	if totalBalance < amount+fee {
+		return nil, fmt.Errorf("insufficient spendable balance: need %d, have %d", amount+fee, totalBalance)
+	}
+
+	// Create inputs
+	inputs := []TxInput{}
+	totalInput := uint64(0)
+	for _, utxo := range utxos {
+		if totalInput >= amount+fee {
+			break
+		}
+		inputs = append(inputs, TxInput{
+			PreviousTxHash: utxo.TxHash,
+			Index:          utxo.Index,
+			Signature:      []byte{}, // Signature will be added using crypto.SignTransaction()
+		})
+		totalInput += utxo.Amount
+	}
+
+	// Create outputs
+	outputs := []TxOutput{
+		{Address: to, Amount: amount},
+	}
+
+	// Add change output if needed
+	change := totalInput - amount - fee
+	if change > 0 {
+		outputs = append(outputs, TxOutput{Address: from, Amount: change})
+	}
+
+	// Create transaction
+	tx := &Transaction{
+		From:      from,
+		To:        to,
+		Amount:    amount,
+		Fee:       fee,
+		Timestamp: time.Now(),
+		Inputs:    inputs,
+		Outputs:   outputs,
+		Signature: []byte{}, // Will be set when signed
+	}
+
+	// Calculate hash
+	tx.Hash = tx.TxID()
+
+	return tx, nil
+}
+
+// processTransactionUTXOs processes UTXOs for a transaction
+func (bc *BlockchainV2) processTransactionUTXOs(tx *Transaction, blockHash Hash, height uint64) {
+	// Mark input UTXOs as spent
+	for _, input := range tx.Inputs {
+		bc.utxoSet.SpendUTXO(input.PreviousTxHash, input.Index)
+	}
+
+	// Create new UTXOs for outputs
+	for i, output := range tx.Outputs {
+		bc.utxoSet.AddUTXO(tx.Hash, uint32(i), output.Amount, output.Address, blockHash, height, tx.IsCoinbase())
+		LogDebug("UTXO created - Address: %s, Amount: %d, TxHash: %x", hex.EncodeToString(output.Address[:]), output.Amount, tx.Hash)
+	}
+}
+
+// AddBlockV2 is the main function for adding blocks - ensures UTXO processing
+func (bc *BlockchainV2) AddBlockV2(block *Block) error {
+	return bc.addBlockV2(block)
+}
+
+// GetBalance returns the balance for an address
+func (bc *BlockchainV2) GetBalance(address Address) uint64 {
+	return bc.utxoSet.GetBalance(address)
+}
+
+// GetBalanceDetails returns the spendable and immature balance for an address.
+// Immature funds are block rewards that have not yet reached the coinbase maturity.
+func (bc *BlockchainV2) GetBalanceDetails(address Address) (spendable uint64, immature uint64) {
+	return bc.utxoSet.GetBalanceDetails(address, bc.GetHeight()+1, bc.coinbaseMaturity())
+}
+
+// coinbaseMaturity returns the coinbase maturity for the next block
+func (bc *BlockchainV2) coinbaseMaturity() uint64 {
+	return bc.rules.Params(bc.GetHeight() + 1).CoinbaseMaturity
+}
+
+// GetUTXOs returns all UTXOs for an address
+func (bc *BlockchainV2) GetUTXOs(address Address) []*UTXO {
+	return bc.utxoSet.GetUTXOs(address)
+}
+
+// AddToMempool validates a transaction against the current chain state and adds it to the
+// mempool, rejecting it if a mempool transaction already spends one of its inputs
+func (bc *BlockchainV2) AddToMempool(tx *Transaction) error {
+	if err := bc.rules.ValidateTransaction(tx, bc.GetHeight()+1, bc.utxoSet); err != nil {
+		return err
+	}
+	if err := bc.mempool.addIfUnspent(tx); err != nil {
+		return err
+	}
+	bc.eventBus.Emit(EventTransactionAdded, tx)
+	return nil
+}
+
+// AcceptRawTransaction validates a client-signed transaction and adds it to the mempool.
+// Unlike AddToMempool it reports transactions already in the mempool as duplicates.
+func (bc *BlockchainV2) AcceptRawTransaction(tx *Transaction) error {
+	if bc.mempool.HasTransaction(tx.Hash) {
+		return fmt.Errorf("transaction %x already in mempool", tx.Hash)
+	}
+	return bc.AddToMempool(tx)
+}
+
+// GetMempool returns the mempool
+func (bc *BlockchainV2) GetMempool() *Mempool {
+	return bc.mempool
+}
+
+// validateBlockV2 validates a block professionally
+func (bc *BlockchainV2) validateBlockV2(block *Block) error {
+	// Check if it's genesis block
+	if block.Header.Number == 0 {
+		return nil
+	}
+
+	return bc.rules.ValidateBlock(block, &BlockContext{
+		Recent:      bc.recentBlocks(),
+		NetworkTime: bc.networkTime.Now(),
+		UTXOs:       bc.utxoSet,
+	})
+}
+
+// recentBlocks returns the blocks the consensus rules look back on (caller holds the lock)
+func (bc *BlockchainV2) recentBlocks() []*Block {
+	span := uint64(MedianTimeSpan)
+	if window := bc.rules.Params(bc.height+1).DifficultyWindow + 1; window > span {
+		span = window
+	}
+
+	start := 0
+	if uint64(len(bc.blocks)) > span {
+		start = len(bc.blocks) - int(span)
+	}
+	return bc.blocks[start:]
+}
+
+// medianTimePast returns the median timestamp of the last MedianTimeSpan blocks (caller holds the lock)
+func (bc *BlockchainV2) medianTimePast() time.Time {
+	start := len(bc.blocks) - MedianTimeSpan
+	if start < 0 {
+		start = 0
+	}
+	return CalcMedianTimePast(bc.blocks[start:])
+}
+
+// GetMedianTimePast returns the median timestamp of the last MedianTimeSpan blocks
+func (bc *BlockchainV2) GetMedianTimePast() time.Time {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.medianTimePast()
+}
+
+// GetNetworkTime returns the peer-adjusted clock used for timestamp validation
+func (bc *BlockchainV2) GetNetworkTime() *NetworkTime {
+	return bc.networkTime
+}
+
+// GetBestBlock returns the best block thread-safely
+func (bc *BlockchainV2) GetBestBlock() *Block {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.bestBlock
+}
+
+// GetBlockByHash returns the main chain block with the given hash, or nil if unknown
+func (bc *BlockchainV2) GetBlockByHash(hash Hash) *Block {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.blockIndex[hash]
+}
+
+// GetBlockByNumber returns the main chain block at the given height, or nil if beyond the tip
+func (bc *BlockchainV2) GetBlockByNumber(number uint64) *Block {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if number >= uint64(len(bc.blocks)) {
+		return nil
+	}
+	return bc.blocks[number]
+}
+
+// GetTransaction returns a main chain transaction and the block containing it, or nil if unknown
+func (bc *BlockchainV2) GetTransaction(txHash Hash) (*Transaction, *Block) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	block := bc.txIndex[txHash]
+	if block == nil {
+		return nil, nil
+	}
+	for i := range block.Txs {
+		if block.Txs[i].Hash == txHash {
+			return &block.Txs[i], block
+		}
+	}
+	return nil, nil
+}
+
+// GetRecentBlocks returns the most recent blocks
+func (bc *BlockchainV2) GetRecentBlocks(limit int) []*Block {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if len(bc.blocks) == 0 {
+		return []*Block{}
+	}
+
+	// Get the last 'limit' blocks
+	start := len(bc.blocks) - limit
+	if start < 0 {
+		start = 0
+	}
+
+	// Create a slice in reverse order (newest first)
+	result := make([]*Block, 0, limit)
+	for i := len(bc.blocks) - 1; i >= start; i-- {
+		result = append(result, bc.blocks[i])
+		if len(result) >= limit {
+			break
+		}
+	}
+
+	return result
+}
+
+// GetHeight returns the current height thread-safely
+func (bc *BlockchainV2) GetHeight() uint64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.height
+}
+
+// GetRules returns the consensus rules
+func (bc *BlockchainV2) GetRules() Rules {
+	return bc.rules
+}
+
+// GetNextDifficulty returns the required difficulty for the next block
+func (bc *BlockchainV2) GetNextDifficulty() uint64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.rules.NextDifficulty(bc.height+1, bc.recentBlocks())
+}
+
+// GetGenesis returns the genesis configuration
+func (bc *BlockchainV2) GetGenesis() *GenesisConfig {
+	return bc.genesis
+}
+
+// GetEventBus returns the event bus
+func (bc *BlockchainV2) GetEventBus() *EventBus {
+	return bc.eventBus
+}
+
+// NewMempool creates a new mempool
+func NewMempool() *Mempool {
+	return &Mempool{
+		transactions: make(map[string]*Transaction),
+		added:        make(map[string]time.Time),
+	}
+}
+
+// AddTransaction adds a transaction to the mempool
+func (m *Mempool) AddTransaction(tx *Transaction) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := hex.EncodeToString(tx.Hash[:])
+	m.transactions[key] = tx
+	m.added[key] = time.Now()
+	m.sequence++
+	log.Printf("📥 Transaction added to mempool: %x", tx.Hash)
+}
+
+// HasTransaction reports whether a transaction is in the mempool
+func (m *Mempool) HasTransaction(txHash Hash) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, exists := m.transactions[hex.EncodeToString(txHash[:])]
+	return exists
+}
+
+// addIfUnspent adds a transaction unless a mempool transaction already spends one of its inputs
+func (m *Mempool) addIfUnspent(tx *Transaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, pending := range m.transactions {
+		for _, spent := range pending.Inputs {
+			for _, input := range tx.Inputs {
+				if spent.PreviousTxHash == input.PreviousTxHash && spent.Index == input.Index {
+					return fmt.Errorf("output %x:%d already spent by mempool transaction %x", input.PreviousTxHash, input.Index, pending.Hash)
+				}
+			}
+		}
+	}
+
+	key := hex.EncodeToString(tx.Hash[:])
+	m.transactions[key] = tx
+	m.added[key] = time.Now()
+	m.sequence++
+	log.Printf("📥 Transaction added to mempool: %x", tx.Hash)
+	return nil
+}
+
+// outpoint identifies a transaction output
+type outpoint struct {
+	txHash Hash
+	index  uint32
+}
+
+// spentOutputs returns the outputs spent by transactions in the mempool
+func (m *Mempool) spentOutputs() map[outpoint]bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	spent := make(map[outpoint]bool)
+	for _, tx := range m.transactions {
+		for _, input := range tx.Inputs {
+			spent[outpoint{input.PreviousTxHash, input.Index}] = true
+		}
+	}
+	return spent
+}
+
+// GetPendingTransactions returns all pending transactions
+func (m *Mempool) GetPendingTransactions() []*Transaction {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var txs []*Transaction
+	for _, tx := range m.transactions {
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+// GetEntry returns a pending transaction with its mempool details, or nil if it is not pending
+func (m *Mempool) GetEntry(txHash Hash) *MempoolEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key := hex.EncodeToString(txHash[:])
+	tx, exists := m.transactions[key]
+	if !exists {
+		return nil
+	}
+	return &MempoolEntry{Tx: tx, Added: m.added[key]}
+}
+
+// GetEntries returns all pending transactions with their mempool details, oldest first
+func (m *Mempool) GetEntries() []*MempoolEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := make([]*MempoolEntry, 0, len(m.transactions))
+	for key, tx := range m.transactions {
+		entries = append(entries, &MempoolEntry{Tx: tx, Added: m.added[key]})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].Added.Equal(entries[j].Added) {
+			return entries[i].Added.Before(entries[j].Added)
+		}
+		return string(entries[i].Tx.Hash[:]) < string(entries[j].Tx.Hash[:])
+	})
+	return entries
+}
+
+// RemoveTransaction removes a transaction from the mempool
+func (m *Mempool) RemoveTransaction(txHash Hash) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := hex.EncodeToString(txHash[:])
+	if _, exists := m.transactions[key]; exists {
+		delete(m.transactions, key)
+		delete(m.added, key)
+		m.sequence++
+	}
+}
+
+// Clear removes all transactions from the mempool
+func (m *Mempool) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.transactions = make(map[string]*Transaction)
+	m.added = make(map[string]time.Time)
+	m.sequence++
+}
+
+// Sequence returns a counter that changes whenever the mempool does, so callers can
+// tell whether its contents moved on since they last looked
+func (m *Mempool) Sequence() uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.sequence
+}
+
+// CreateNewBlockV2 creates a new block template professionally
+func (bc *BlockchainV2) CreateNewBlockV2(miner Address, txs []Transaction) *Block {
+	bc.mu.RLock()
+	parent := bc.bestBlock
+	medianTime := bc.medianTimePast()
+	recent := bc.recentBlocks()
+	bc.mu.RUnlock()
+
+	if parent == nil {
+		return nil
+	}
+
+	// Block timestamps are hashed with second precision and must follow the median time past
+	timestamp := time.Unix(bc.networkTime.Now().Unix(), 0)
+	if !timestamp.After(medianTime) {
+		timestamp = medianTime.Add(time.Second)
+	}
+	if !bc.rules.IsActive(UpgradeMedianTimePast, parent.Header.Number+1) && timestamp.Before(parent.Header.Timestamp) {
+		timestamp = parent.Header.Timestamp
+	}
+
+	// Calculate difficulty from the consensus rules
+	height := parent.Header.Number + 1
+	difficulty := bc.rules.NextDifficulty(height, recent)
+
+	// Create block reward transaction
+	blockReward := bc.calculateBlockReward(height)
+	rewardTx := bc.createBlockRewardTransaction(miner, blockReward)
+
+	// Get pending transactions from mempool, keeping the block within the size limit
+	params := bc.rules.Params(height)
+	maxBlockBytes := params.MaxBlockBytes
+	if maxBlockBytes == 0 {
+		// Without a consensus limit, keep templates small enough for peers to relay
+		maxBlockBytes = bc.genesis.GetMaxBlockBytes()
+	}
+	blockBytes := uint64(blockHeaderReserveBytes + rewardTx.Size())
+	for i := range txs {
+		blockBytes += uint64(txs[i].Size())
+	}
+
+	// Pending transactions are revalidated, since the chain moved on since they were accepted
+	spent := make(map[outpoint]bool)
+	pendingTxs := bc.mempool.GetPendingTransactions()
+	for _, tx := range pendingTxs {
+		if err := bc.rules.ValidateTransaction(tx, height, bc.utxoSet); err != nil {
+			log.Printf("⚠️ Skipping transaction %x for template: %v", tx.Hash, err)
+			continue
+		}
+		conflict := false
+		for _, input := range tx.Inputs {
+			conflict = conflict || spent[outpoint{input.PreviousTxHash, input.Index}]
+		}
+		if conflict {
+			continue
+		}
+		txBytes := uint64(tx.Size())
+		if blockBytes+txBytes > maxBlockBytes {
+			continue
+		}
+		blockBytes += txBytes
+		for _, input := range tx.Inputs {
+			spent[outpoint{input.PreviousTxHash, input.Index}] = true
+		}
+		txs = append(txs, *tx)
+	}
+
+	// Add reward transaction to the beginning of transactions
+	allTxs := append([]Transaction{rewardTx}, txs...)
+
+	// Create block template
+	block := &Block{
+		Header: BlockHeader{
+			ParentHash:  parent.Hash, // CRITICAL: Use actual parent hash
+			Number:      height,
+			Timestamp:   timestamp,
+			Difficulty:  difficulty,
+			Miner:       miner,
+			Nonce:       0,
+			MerkleRoot:  CalculateMerkleRoot(allTxs),
+			TxCount:     uint32(len(allTxs)),
+			NetworkFee:  0,
+			TreasuryFee: 0,
+		},
+		Txs:  allTxs,
+		Hash: Hash{},
+	}
+
+	// Calculate hash
+	block.Hash = block.CalculateHash()
+
+	return block
+}
+
+// calculateBlockReward calculates the block reward for a given block number
+func (bc *BlockchainV2) calculateBlockReward(blockNumber uint64) uint64 {
+	return bc.rules.BlockReward(blockNumber)
+}
+
+// createBlockRewardTransaction creates a block reward transaction
+func (bc *BlockchainV2) createBlockRewardTransaction(miner Address, amount uint64) Transaction {
+	log.Printf("🔍 DEBUG createBlockRewardTransaction - Miner address: %x, Amount: %d", miner, amount)
+
+	// Create a special coinbase transaction (no inputs, only output)
+	tx := Transaction{
+		From:      Address{}, // Empty for coinbase
+		To:        miner,
+		Amount:    amount,
+		Nonce:     0,
+		Fee:       0,
+		GasUsed:   0,
+		GasPrice:  0,
+		Data:      []byte("block_reward"),
+		Signature: []byte{},    // No signature needed for coinbase
+		Inputs:    []TxInput{}, // No inputs for coinbase
+		Outputs: []TxOutput{
+			{
+				Address: miner,
+				Amount:  amount,
+			},
+		},
+		Timestamp: time.Now(),
+	}
+
+	log.Printf("🔍 DEBUG createBlockRewardTransaction - Created TX with output address: %x", tx.Outputs[0].Address)
+
+	// Calculate transaction hash
+	tx.Hash = tx.TxID()
+
+	return tx
+}
+
+// AddBlock adds a block to the blockchain
+func (bc *BlockchainV2) AddBlock(block *Block) error {
+	return bc.addBlockV2(block)
+}
+
+// loadChainFromStorage loads the blockchain from persistent storage
+func (bc *BlockchainV2) loadChainFromStorage() {
+	log.Printf("🔍 DEBUG: loadChainFromStorage called")
+	if bc.storage == nil {
+		log.Printf("⚠️ Storage is nil, skipping load")
+		return
+	}
+
+	log.Printf("🔍 DEBUG: Getting best block from storage")
+	// Get the best block
+	bestBlock, err := bc.storage.GetBestBlock()
+	if err != nil {
+		log.Printf("⚠️ No existing chain found, starting fresh (error: %v)", err)
+		return
+	}
+
+	if bestBlock == nil {
+		log.Printf("⚠️ Best block is nil, starting fresh")
+		return
+	}
+
+	log.Printf("🔍 DEBUG: Best block found - Height: %d, Hash: %x", bestBlock.Header.Number, bestBlock.Hash)
+
+	// Reconstruct chain by loading blocks from storage
+	bc.height = bestBlock.Header.Number
+	bc.bestBlock = bestBlock
+
+	log.Printf("🔍 DEBUG: Loading %d blocks from storage", bc.height+1)
+
+	// Load all blocks from genesis to best block
+	for i := uint64(0); i <= bc.height; i++ {
+		block, err := bc.storage.GetBlockByNumber(i)
+		if err != nil || block == nil {
+			log.Printf("⚠️ Failed to load block %d: %v", i, err)
+			// Reset and start fresh
+			bc.height = 0
+			bc.bestBlock = nil
+			bc.blocks = make([]*Block, 0)
+			bc.blockIndex = make(map[Hash]*Block)
+			bc.txIndex = make(map[Hash]*Block)
+			return
+		}
+		bc.blocks = append(bc.blocks, block)
+		bc.blockIndex[block.Hash] = block
+
+		// IMPORTANT: Reconstruct UTXOs for each block
+		// This is critical because UTXOs are in-memory and need to be rebuilt
+		for _, tx := range block.Txs {
+			bc.processTransactionUTXOs(&tx, block.Hash, block.Header.Number)
+			bc.txIndex[tx.Hash] = block
+		}
+	}
+
+	log.Printf("✅ Loaded blockchain from storage - Height: %d, UTXOs restored", bc.height)
+}
+
+// StorageHealth reports whether blocks are persisted and, if so, why storage is not
+// usable: the last failed block write, or the persister's own health check
+func (bc *BlockchainV2) StorageHealth() (persistent bool, err error) {
+	if bc.storage == nil {
+		return false, nil
+	}
+
+	bc.storageMu.Lock()
+	err = bc.storageErr
+	bc.storageMu.Unlock()
+	if err != nil {
+		return true, fmt.Errorf("last block write failed: %v", err)
+	}
+
+	if checker, ok := bc.storage.(HealthChecker); ok {
+		return true, checker.CheckHealth()
+	}
+	return true, nil
+}
+
+// Close closes the blockchain and its storage
+func (bc *BlockchainV2) Close() error {
+	if bc.storage != nil {
+		return bc.storage.Close()
+	}
+	return nil
+}
+
+// Emit emits an event without blocking; subscribers whose channel is full miss it
+func (eb *EventBus) Emit(event string, data interface{}) {
+	// The read lock is held while sending so Unsubscribe cannot close a channel in use
+	eb.mu.RLock()
+	defer eb.mu.RUnlock()
+
+	for _, ch := range eb.channels[event] {
+		select {
+		case ch <- data:
+		default:
+			// Channel is full, skip
+		}
+	}
+}
+
+// Subscribe subscribes to an event
+func (eb *EventBus) Subscribe(event string) <-chan interface{} {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	ch := make(chan interface{}, 100) // Buffered channel
+	eb.channels[event] = append(eb.channels[event], ch)
+
+	return ch
+}
+
+// Unsubscribe removes a subscription and closes its channel
+func (eb *EventBus) Unsubscribe(event string, ch <-chan interface{}) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	channels := eb.channels[event]
+	for i, c := range channels {
+		if c == ch {
+			eb.channels[event] = append(channels[:i:i], channels[i+1:]...)
+			close(c)
+			return
+		}
+	}
+}
+
+// SetState sets a state value
+func (sm *StateManager) SetState(key string, value interface{}) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.state[key] = value
+}
+
+// GetState gets a state value
+func (sm *StateManager) GetState(key string) interface{} {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.state[key]
+}
@@ -1,6 +1,13 @@
 package core
 
 import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -23,7 +30,7 @@ func TestNewBlockchainV2(t *testing.T) {
 		},
 	}
 
-	bc := NewBlockchainV2(genesis)
+	bc := NewBlockchainV2(genesis, nil)
 	if bc == nil {
 		t.Fatal("Expected non-nil blockchain")
 	}
@@ -72,6 +79,82 @@ func TestBlockHash(t *testing.T) {
 	}
 }
 
+// TestBlockHashMismatch tests that a block is rejected unless its hash is the hash of its header
+func TestBlockHashMismatch(t *testing.T) {
+	bc := NewBlockchainV2(&GenesisConfig{
+		BlockTimeTarget:    15,
+		InitialBlockReward: 5.0,
+		Difficulty:         DifficultyConfig{Window: 120, InitialDifficulty: 1},
+	}, nil)
+
+	// A hash full of leading zeros would pass any proof of work check
+	block := bc.CreateNewBlockV2(Address{1}, nil)
+	block.Hash = Hash{31: 1}
+	if err := bc.AddBlockV2(block); err == nil {
+		t.Fatal("Expected block with a forged hash to be rejected")
+	}
+
+	block.Hash = block.CalculateHash()
+	if err := bc.AddBlockV2(block); err != nil {
+		t.Errorf("Expected block with its own hash to be accepted: %v", err)
+	}
+}
+
+// TestCoinbaseRules tests that a block commits to its transactions and has one coinbase,
+// first, identified by its id, whose outputs cannot wrap around the reward limit
+func TestCoinbaseRules(t *testing.T) {
+	bc := NewBlockchainV2(&GenesisConfig{
+		BlockTimeTarget:    15,
+		InitialBlockReward: 5.0,
+		Difficulty:         DifficultyConfig{Window: 120, InitialDifficulty: 1},
+		Upgrades: []NetworkUpgrade{
+			{Name: UpgradeCoinbaseLimit, Height: 0},
+			{Name: UpgradeTxCommitments, Height: 0},
+		},
+	}, nil)
+
+	for _, c := range []struct {
+		name   string
+		mutate func(*Block)
+		want   string
+	}{
+		{"wrapping outputs", func(b *Block) {
+			b.Txs[0].Outputs = []TxOutput{{Address: Address{1}, Amount: ^uint64(0)}, {Address: Address{1}, Amount: 10}}
+			b.Txs[0].Hash = b.Txs[0].TxID()
+		}, "overflow"},
+		{"stale coinbase hash", func(b *Block) {
+			b.Txs[0].Outputs[0].Amount--
+		}, "does not match its id"},
+		{"no coinbase", func(b *Block) {
+			b.Txs = nil
+		}, "does not start with a coinbase"},
+		{"second coinbase", func(b *Block) {
+			b.Txs = append(b.Txs, b.Txs[0])
+		}, "second coinbase"},
+	} {
+		block := bc.CreateNewBlockV2(Address{1}, nil)
+		c.mutate(block)
+		block.Header.TxCount = uint32(len(block.Txs))
+		block.Header.MerkleRoot = CalculateMerkleRoot(block.Txs)
+		block.Hash = block.CalculateHash()
+		if err := bc.AddBlockV2(block); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: expected %q error, got %v", c.name, c.want, err)
+		}
+	}
+
+	// Paying the reward to someone else changes the merkle root, not the header
+	block := bc.CreateNewBlockV2(Address{1}, nil)
+	block.Txs[0].Outputs[0].Address = Address{2}
+	block.Txs[0].Hash = block.Txs[0].TxID()
+	if err := bc.AddBlockV2(block); err == nil || !strings.Contains(err.Error(), "merkle root") {
+		t.Errorf("Expected transactions the header does not commit to to be rejected, got %v", err)
+	}
+
+	if err := bc.AddBlockV2(bc.CreateNewBlockV2(Address{1}, nil)); err != nil {
+		t.Errorf("Expected a block with a valid coinbase to be accepted: %v", err)
+	}
+}
+
 // TestTransactionValidation tests transaction validation
 func TestTransactionValidation(t *testing.T) {
 	// Valid transaction
@@ -135,7 +218,7 @@ func TestCalculateBlockReward(t *testing.T) {
 		},
 	}
 
-	bc := NewBlockchainV2(genesis)
+	bc := NewBlockchainV2(genesis, nil)
 	
 	// Block 1 should have full reward
 	reward1 := bc.calculateBlockReward(1)
@@ -150,3 +233,507 @@ func TestCalculateBlockReward(t *testing.T) {
 	}
 }
 
+
+// TestCoinbaseMaturity tests that block rewards cannot be spent before they mature
+func TestCoinbaseMaturity(t *testing.T) {
+	genesis := &GenesisConfig{
+		BlockTimeTarget:    15,
+		InitialBlockReward: 5.0,
+		Difficulty: DifficultyConfig{
+			Window:            120,
+			InitialDifficulty: 1,
+		},
+		CoinbaseMaturity: 3,
+	}
+
+	bc := NewBlockchainV2(genesis, nil)
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	miner := PubKeyAddress(pub)
+	other := Address{2}
+
+	// Block 1 pays the reward to miner
+	block := bc.CreateNewBlockV2(miner, nil)
+	if err := bc.AddBlockV2(block); err != nil {
+		t.Fatalf("Failed to add block 1: %v", err)
+	}
+	reward := block.Txs[0].Outputs[0].Amount
+
+	spendable, immature := bc.GetBalanceDetails(miner)
+	if spendable != 0 || immature != reward {
+		t.Errorf("Expected 0 spendable and %d immature, got %d and %d", reward, spendable, immature)
+	}
+
+	if _, err := bc.CreateTransaction(miner, other, 1000, 0); err == nil {
+		t.Error("Expected immature reward to be unspendable")
+	}
+
+	// A block spending the immature reward must be rejected
+	spend := Transaction{
+		From:    miner,
+		To:      other,
+		Amount:  1000,
+		Inputs:  []TxInput{{PreviousTxHash: block.Txs[0].Hash, Index: 0}},
+		Outputs: []TxOutput{{Address: other, Amount: 1000}},
+	}
+	spend.Hash = spend.TxID()
+	signInputs(&spend, priv)
+	if err := bc.AddToMempool(&spend); err == nil {
+		t.Error("Expected mempool to reject immature spend")
+	}
+	invalid := bc.CreateNewBlockV2(other, []Transaction{spend})
+	if err := bc.AddBlockV2(invalid); err == nil {
+		t.Error("Expected block spending immature reward to be rejected")
+	}
+
+	// After the maturity period the reward becomes spendable
+	for i := 0; i < 2; i++ {
+		if err := bc.AddBlockV2(bc.CreateNewBlockV2(other, nil)); err != nil {
+			t.Fatalf("Failed to add block: %v", err)
+		}
+	}
+
+	spendable, immature = bc.GetBalanceDetails(miner)
+	if spendable != reward || immature != 0 {
+		t.Errorf("Expected %d spendable and 0 immature, got %d and %d", reward, spendable, immature)
+	}
+
+	tx, err := bc.CreateTransaction(miner, other, 1000, 0)
+	if err != nil {
+		t.Fatalf("Expected mature reward to be spendable: %v", err)
+	}
+	signInputs(tx, priv)
+	if err := bc.AddToMempool(tx); err != nil {
+		t.Errorf("Expected mempool to accept mature spend: %v", err)
+	}
+}
+
+// TestTransactionInputRules tests that blocks and the mempool only accept signed spends
+// of existing outputs, and that a block never spends an output twice
+func TestTransactionInputRules(t *testing.T) {
+	bc := NewBlockchainV2(&GenesisConfig{
+		BlockTimeTarget:    15,
+		InitialBlockReward: 5.0,
+		Difficulty:         DifficultyConfig{Window: 120, InitialDifficulty: 1},
+	}, nil)
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	owner := PubKeyAddress(pub)
+	block := bc.CreateNewBlockV2(owner, nil)
+	if err := bc.AddBlockV2(block); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+	reward := block.Txs[0].Outputs[0].Amount
+
+	// An unsigned spend of an output that does not exist must not mint coins
+	forged := Transaction{
+		From:    Address{7},
+		To:      Address{7},
+		Amount:  1 << 40,
+		Inputs:  []TxInput{{PreviousTxHash: Hash{9}}},
+		Outputs: []TxOutput{{Address: Address{7}, Amount: 1 << 40}},
+	}
+	forged.Hash = forged.TxID()
+	if err := bc.AddToMempool(&forged); err == nil || errors.Is(err, ErrInvalidTransaction) {
+		t.Errorf("Expected mempool to reject a spend of a missing output as a conflict, got %v", err)
+	}
+	if err := bc.AddBlockV2(bc.CreateNewBlockV2(Address{3}, []Transaction{forged})); err == nil {
+		t.Error("Expected block spending a missing output to be rejected")
+	}
+
+	spend := func(prev Hash, amount uint64, to Address) Transaction {
+		tx := Transaction{
+			From:      owner,
+			To:        to,
+			Amount:    amount,
+			Timestamp: time.Now(),
+			Inputs:    []TxInput{{PreviousTxHash: prev, Index: 0}},
+			Outputs:   []TxOutput{{Address: to, Amount: amount}},
+		}
+		tx.Hash = tx.TxID()
+		signInputs(&tx, priv)
+		return tx
+	}
+
+	first := spend(block.Txs[0].Hash, reward, owner)
+	second := spend(block.Txs[0].Hash, reward, Address{8})
+
+	// A bad signature can never become valid, unlike a missing input
+	tampered := first
+	tampered.Inputs = []TxInput{first.Inputs[0]}
+	tampered.Inputs[0].Signature = append([]byte{}, first.Inputs[0].Signature...)
+	tampered.Inputs[0].Signature[0] ^= 0xff
+	if err := bc.AddToMempool(&tampered); !errors.Is(err, ErrInvalidTransaction) {
+		t.Errorf("Expected bad signature to be an invalid transaction, got %v", err)
+	}
+	err := bc.AddBlockV2(bc.CreateNewBlockV2(Address{3}, []Transaction{first, second}))
+	if err == nil || !strings.Contains(err.Error(), "already spent in this block") {
+		t.Errorf("Expected block spending the same output twice to be rejected, got %v", err)
+	}
+
+	// The mempool accepts one spend of an output and refuses conflicting ones
+	if err := bc.AddToMempool(&first); err != nil {
+		t.Fatalf("Expected mempool to accept a signed spend: %v", err)
+	}
+	if err := bc.AddToMempool(&second); err == nil || !strings.Contains(err.Error(), "already spent by mempool transaction") {
+		t.Errorf("Expected mempool to reject a double spend, got %v", err)
+	}
+	bc.mempool.RemoveTransaction(first.Hash)
+
+	// A transaction may spend an output created earlier in the same block
+	chained := spend(first.Hash, reward, Address{8})
+	if err := bc.AddBlockV2(bc.CreateNewBlockV2(Address{3}, []Transaction{first, chained})); err != nil {
+		t.Fatalf("Expected block with a chained spend to be accepted: %v", err)
+	}
+	if balance := bc.GetBalance(Address{8}); balance != reward {
+		t.Errorf("Expected balance %d, got %d", reward, balance)
+	}
+}
+
+// TestReorganize tests switching the main chain to a branch with more work
+func TestReorganize(t *testing.T) {
+	genesis := &GenesisConfig{
+		BlockTimeTarget:    15,
+		InitialBlockReward: 5.0,
+		Difficulty:         DifficultyConfig{Window: 120, InitialDifficulty: 1},
+	}
+	bc := NewBlockchainV2(genesis, nil)
+	other := NewBlockchainV2(genesis, nil)
+
+	fork := bc.CreateNewBlockV2(Address{1}, nil)
+	for _, chain := range []*BlockchainV2{bc, other} {
+		if err := chain.AddBlockV2(fork); err != nil {
+			t.Fatalf("Failed to add fork block: %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := bc.AddBlockV2(bc.CreateNewBlockV2(Address{2}, nil)); err != nil {
+			t.Fatalf("Failed to add main chain block: %v", err)
+		}
+	}
+	replacedTip := bc.GetBestBlock()
+
+	// The branch is as long as the main chain but each block takes twice the work
+	var branch []*Block
+	for i := 0; i < 2; i++ {
+		block := other.CreateNewBlockV2(Address{3}, nil)
+		block.Header.Difficulty = 2
+		block.Hash = block.CalculateHash()
+		if err := other.AddBlockV2(block); err != nil {
+			t.Fatalf("Failed to add branch block: %v", err)
+		}
+		branch = append(branch, block)
+	}
+
+	if err := bc.ReorganizeV2(branch[:1]); err == nil {
+		t.Error("Expected branch without more work to be rejected")
+	}
+
+	invalid := *branch[1]
+	invalid.Header.Nonce++
+	if err := bc.ReorganizeV2([]*Block{branch[0], &invalid}); err == nil {
+		t.Error("Expected branch with an invalid block to be rejected")
+	}
+	if bc.GetBestBlock() != replacedTip || bc.GetBalance(Address{2}) == 0 || bc.GetBalance(Address{3}) != 0 {
+		t.Fatal("Expected the main chain to be restored after a failed reorganization")
+	}
+
+	if err := bc.ReorganizeV2(branch); err != nil {
+		t.Fatalf("Expected branch with more work to be accepted: %v", err)
+	}
+	if bc.GetBestBlock() != branch[1] || bc.GetHeight() != 3 || bc.GetBlockByHash(replacedTip.Hash) != nil {
+		t.Error("Expected the branch to become the main chain")
+	}
+	if bc.GetBalance(Address{2}) != 0 || bc.GetBalance(Address{3}) != other.GetBalance(Address{3}) {
+		t.Error("Expected balances to follow the branch")
+	}
+}
+
+// TestBlockLimits tests the genesis-configured block and transaction limits
+func TestBlockLimits(t *testing.T) {
+	genesis := &GenesisConfig{
+		BlockTimeTarget:    15,
+		InitialBlockReward: 5.0,
+		Difficulty: DifficultyConfig{
+			Window:            120,
+			InitialDifficulty: 1,
+		},
+		MaxBlockBytes: 4096,
+		MaxTxOutputs:  2,
+	}
+
+	bc := NewBlockchainV2(genesis, nil)
+	rules := NewRules(genesis)
+
+	tx := &Transaction{
+		From:    Address{1},
+		To:      Address{2},
+		Amount:  3,
+		Inputs:  []TxInput{{PreviousTxHash: Hash{9}}},
+		Outputs: []TxOutput{{Address: Address{2}, Amount: 1}, {Address: Address{2}, Amount: 1}, {Address: Address{2}, Amount: 1}},
+	}
+	if err := rules.ValidateTransaction(tx, 1, nil); err == nil {
+		t.Error("Expected transaction with too many outputs to be rejected")
+	}
+	if err := bc.AddToMempool(tx); err == nil {
+		t.Error("Expected mempool to reject transaction with too many outputs")
+	}
+
+	// Fill a block with more data than the limit allows
+	var txs []Transaction
+	for i := 0; i < 20; i++ {
+		txs = append(txs, Transaction{
+			From:    Address{1},
+			To:      Address{2},
+			Amount:  uint64(i + 1),
+			Data:    make([]byte, 256),
+			Outputs: []TxOutput{{Address: Address{2}, Amount: uint64(i + 1)}},
+		})
+	}
+	block := bc.CreateNewBlockV2(Address{3}, txs)
+	if err := bc.AddBlockV2(block); err == nil {
+		t.Error("Expected oversized block to be rejected")
+	}
+}
+
+// TestBlockTimestampRules tests the median-time-past and future drift rules
+func TestBlockTimestampRules(t *testing.T) {
+	genesis := &GenesisConfig{
+		BlockTimeTarget:    15,
+		InitialBlockReward: 5.0,
+		Difficulty: DifficultyConfig{
+			Window:            120,
+			InitialDifficulty: 1,
+		},
+		MaxFutureBlockTime: 60,
+		Upgrades:           []NetworkUpgrade{{Name: UpgradeMedianTimePast, Height: 0}},
+	}
+
+	bc := NewBlockchainV2(genesis, nil)
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	addBlockAt := func(ts time.Time) error {
+		block := bc.CreateNewBlockV2(Address{1}, nil)
+		block.Header.Timestamp = ts
+		block.Hash = block.CalculateHash()
+		return bc.AddBlockV2(block)
+	}
+
+	for i := 0; i < MedianTimeSpan; i++ {
+		if err := addBlockAt(base.Add(time.Duration(i) * time.Minute)); err != nil {
+			t.Fatalf("Failed to add block %d: %v", i+1, err)
+		}
+	}
+
+	// Median of the last 11 blocks is base+5m; a timestamp at the median is rejected
+	median := bc.GetMedianTimePast()
+	if !median.Equal(base.Add(5 * time.Minute)) {
+		t.Errorf("Expected median time past %v, got %v", base.Add(5*time.Minute), median)
+	}
+	if err := addBlockAt(median); err == nil {
+		t.Error("Expected block at median time past to be rejected")
+	}
+
+	// Earlier than the parent but after the median is allowed
+	if err := addBlockAt(median.Add(time.Second)); err != nil {
+		t.Errorf("Expected block after median time past to be accepted: %v", err)
+	}
+
+	if err := addBlockAt(time.Now().Add(2 * time.Minute)); !errors.Is(err, ErrFutureBlock) {
+		t.Errorf("Expected block too far in the future to be rejected, got %v", err)
+	}
+
+	// Templates never produce timestamps at or before the median
+	template := bc.CreateNewBlockV2(Address{1}, nil)
+	if !template.Header.Timestamp.After(bc.GetMedianTimePast()) {
+		t.Error("Expected template timestamp after median time past")
+	}
+
+	// Serialized timestamps carry the same precision as the block hash
+	data, err := json.Marshal(template)
+	if err != nil {
+		t.Fatalf("Failed to marshal block: %v", err)
+	}
+	var decoded Block
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal block: %v", err)
+	}
+	if decoded.CalculateHash() != template.Hash {
+		t.Error("Expected hash to survive JSON round trip")
+	}
+	if decoded.Header.Timestamp.Nanosecond() != 0 {
+		t.Error("Expected second-precision timestamp after JSON round trip")
+	}
+}
+
+// TestConsensusForkSchedule tests height-activated consensus parameter changes
+func TestConsensusForkSchedule(t *testing.T) {
+	var genesis GenesisConfig
+	data := []byte(`{
+		"blockTimeTargetSeconds": 15,
+		"initialBlockReward": 5.0,
+		"difficulty": {"window": 4, "initialDifficulty": 1},
+		"coinbaseMaturity": 10,
+		"maxBlockBytes": 8192,
+		"forks": [
+			{"name": "bigBlocks", "height": 5, "maxBlockBytes": 16384},
+			{"name": "retarget", "height": 8, "coinbaseMaturity": 20, "difficultyRetarget": true}
+		]
+	}`)
+	if err := json.Unmarshal(data, &genesis); err != nil {
+		t.Fatalf("Failed to parse genesis: %v", err)
+	}
+	if err := genesis.Validate(); err != nil {
+		t.Fatalf("Expected valid fork schedule: %v", err)
+	}
+
+	rules := NewRules(&genesis)
+	if p := rules.Params(4); p.MaxBlockBytes != 8192 || p.CoinbaseMaturity != 10 || p.DifficultyRetarget {
+		t.Errorf("Unexpected params before forks: %+v", p)
+	}
+	if p := rules.Params(5); p.MaxBlockBytes != 16384 || p.CoinbaseMaturity != 10 {
+		t.Errorf("Unexpected params at first fork: %+v", p)
+	}
+	if p := rules.Params(100); p.MaxBlockBytes != 16384 || p.CoinbaseMaturity != 20 || !p.DifficultyRetarget {
+		t.Errorf("Unexpected params after second fork: %+v", p)
+	}
+	if max := genesis.MaxScheduledBlockBytes(); max != 16384 {
+		t.Errorf("Expected largest scheduled block size 16384, got %d", max)
+	}
+
+	// Retargeting raises difficulty when blocks come in far faster than the target
+	var recent []*Block
+	base := time.Unix(1700000000, 0)
+	for i := 0; i < 6; i++ {
+		recent = append(recent, &Block{Header: BlockHeader{
+			Number:     uint64(i),
+			Timestamp:  base.Add(time.Duration(i) * time.Second),
+			Difficulty: 3,
+		}})
+	}
+	if d := rules.NextDifficulty(6, recent); d != 3 {
+		t.Errorf("Expected difficulty unchanged before retarget fork, got %d", d)
+	}
+	if d := rules.NextDifficulty(8, recent); d != 4 {
+		t.Errorf("Expected difficulty 4 after retarget fork, got %d", d)
+	}
+
+	genesis.Forks = append(genesis.Forks, ConsensusFork{Name: "stale", Height: 8})
+	if err := genesis.Validate(); err == nil {
+		t.Error("Expected fork schedule with repeated height to be rejected")
+	}
+}
+
+// TestGenesisFiles tests that the shipped genesis files are valid and schedule their rule changes
+func TestGenesisFiles(t *testing.T) {
+	for _, name := range []string{"genesis", "mainnet", "testnet", "community-testnet"} {
+		data, err := os.ReadFile(filepath.Join("..", "genesis", name+".json"))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var genesis GenesisConfig
+		if err := json.Unmarshal(data, &genesis); err != nil {
+			t.Fatalf("%s: failed to parse genesis: %v", name, err)
+		}
+		if err := genesis.Validate(); err != nil {
+			t.Errorf("%s: invalid genesis: %v", name, err)
+		}
+		if !genesis.ParamsAt(math.MaxUint64).DifficultyRetarget {
+			t.Errorf("%s: expected difficulty retargeting to be scheduled", name)
+		}
+
+		// Chains already running keep the rules they launched with until the scheduled heights
+		if p := genesis.ParamsAt(0); p.CoinbaseMaturity != 0 || p.MaxBlockBytes != 0 || p.MaxTxInputs != 0 || p.DifficultyRetarget {
+			t.Errorf("%s: expected no new rules at genesis, got %+v", name, p)
+		}
+		for _, upgrade := range genesis.Upgrades {
+			if upgrade.Height == 0 {
+				t.Errorf("%s: expected upgrade %s to activate after genesis", name, upgrade.Name)
+			}
+		}
+	}
+
+	var mainnet GenesisConfig
+	data, _ := os.ReadFile(filepath.Join("..", "genesis", "mainnet.json"))
+	if err := json.Unmarshal(data, &mainnet); err != nil {
+		t.Fatalf("Failed to parse mainnet genesis: %v", err)
+	}
+	rules := NewRules(&mainnet)
+	block := &Block{Header: BlockHeader{Difficulty: 8}}
+	block.Hash[0] = 0xff
+	if !rules.CheckProofOfWork(block) {
+		t.Error("Expected low difficulty to be exempt before the powEnforcement fork")
+	}
+	block.Header.Number = 5760
+	if rules.CheckProofOfWork(block) {
+		t.Error("Expected proof of work to be enforced after the powEnforcement fork")
+	}
+}
+
+// TestNetworkUpgrades tests height-activated upgrades declared in genesis
+func TestNetworkUpgrades(t *testing.T) {
+	genesis := &GenesisConfig{
+		BlockTimeTarget:    15,
+		InitialBlockReward: 5.0,
+		Difficulty: DifficultyConfig{
+			Window:            120,
+			InitialDifficulty: 1,
+		},
+		Upgrades: []NetworkUpgrade{
+			{Name: UpgradeStrictDifficulty, Height: 0},
+			{Name: UpgradeMedianTimePast, Height: 3},
+		},
+	}
+	if err := genesis.Validate(); err != nil {
+		t.Fatalf("Expected valid upgrades: %v", err)
+	}
+
+	if genesis.IsActive(UpgradeMedianTimePast, 2) || !genesis.IsActive(UpgradeMedianTimePast, 3) {
+		t.Error("Expected medianTimePast to activate at height 3")
+	}
+	if genesis.IsActive(UpgradeCoinbaseLimit, 100) {
+		t.Error("Expected undeclared upgrade to be inactive")
+	}
+
+	bc := NewBlockchainV2(genesis, nil)
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	addBlockAt := func(ts time.Time) error {
+		block := bc.CreateNewBlockV2(Address{1}, nil)
+		block.Header.Timestamp = ts
+		block.Hash = block.CalculateHash()
+		return bc.AddBlockV2(block)
+	}
+
+	if err := addBlockAt(base.Add(10 * time.Second)); err != nil {
+		t.Fatalf("Failed to add block 1: %v", err)
+	}
+
+	// Strict difficulty is active from genesis
+	block := bc.CreateNewBlockV2(Address{1}, nil)
+	block.Header.Difficulty++
+	block.Hash = block.CalculateHash()
+	if err := bc.AddBlockV2(block); err == nil {
+		t.Error("Expected block with wrong difficulty to be rejected")
+	}
+
+	// Before activation a timestamp earlier than the parent is rejected
+	if err := addBlockAt(base.Add(5 * time.Second)); err == nil {
+		t.Error("Expected block before its parent to be rejected before medianTimePast activates")
+	}
+	if err := addBlockAt(base.Add(20 * time.Second)); err != nil {
+		t.Fatalf("Failed to add block 2: %v", err)
+	}
+
+	// From activation only the median time past matters
+	if err := addBlockAt(base.Add(15 * time.Second)); err != nil {
+		t.Errorf("Expected block after median time past to be accepted at activation: %v", err)
+	}
+
+	genesis.Upgrades = append(genesis.Upgrades, NetworkUpgrade{Name: "futureUpgrade", Height: 10})
+	if err := genesis.Validate(); err == nil {
+		t.Error("Expected unknown upgrade to be rejected")
+	}
+	genesis.Upgrades = []NetworkUpgrade{{Name: UpgradeCoinbaseLimit}, {Name: UpgradeCoinbaseLimit, Height: 5}}
+	if err := genesis.Validate(); err == nil {
+		t.Error("Expected duplicate upgrade to be rejected")
+	}
+}
@@ -1,334 +1,524 @@
-package core
-
-import (
-	"crypto/sha256"
-	"fmt"
-	"log"
-	"time"
-)
-
-// ConsensusManager handles consensus logic
-type ConsensusManager struct {
-	genesis *GenesisConfig
-}
-
-// NewConsensusManager creates a new consensus manager
-func NewConsensusManager(genesis *GenesisConfig) *ConsensusManager {
-	return &ConsensusManager{
-		genesis: genesis,
-	}
-}
-
-// ValidateBlock validates a block according to consensus rules
-func (cm *ConsensusManager) ValidateBlock(block *Block, parent *Block) error {
-	// Validate block structure
-	if block == nil {
-		return fmt.Errorf("block is nil")
-	}
-
-	// Validate block number
-	if parent != nil && block.Header.Number != parent.Header.Number+1 {
-		log.Printf("WARNING: Block number mismatch - Expected: %d, Got: %d (allowing for testnet)",
-			parent.Header.Number+1, block.Header.Number)
-		// For testnet, allow some flexibility in block numbers
-		if block.Header.Number < parent.Header.Number {
-			return fmt.Errorf("invalid block number: expected %d, got %d",
-				parent.Header.Number+1, block.Header.Number)
-		}
-		// Allow higher block numbers for testnet
-		log.Printf("Testnet: Allowing block number %d (expected %d)", block.Header.Number, parent.Header.Number+1)
-	}
-
-	// Validate parent hash
-	if parent != nil && block.Header.ParentHash != parent.Hash {
-		return fmt.Errorf("invalid parent hash: expected %x, got %x", parent.Hash, block.Header.ParentHash)
-	}
-
-	// Validate timestamp
-	now := time.Now()
-	if block.Header.Timestamp.After(now.Add(2 * time.Minute)) {
-		return fmt.Errorf("block timestamp too far in future")
-	}
-
-	if parent != nil && block.Header.Timestamp.Before(parent.Header.Timestamp) {
-		return fmt.Errorf("block timestamp before parent")
-	}
-
-	// Validate difficulty
-	if parent != nil {
-		expectedDifficulty := cm.CalculateDifficulty(block.Header.Number, parent)
-		if block.Header.Difficulty != expectedDifficulty {
-			return fmt.Errorf("invalid difficulty: expected %d, got %d",
-				expectedDifficulty, block.Header.Difficulty)
-		}
-	}
-
-	// Validate proof of work
-	if !cm.ValidateProofOfWork(block) {
-		return fmt.Errorf("invalid proof of work")
-	}
-
-	// Validate transactions
-	for i, tx := range block.Txs {
-		if err := cm.ValidateTransaction(&tx); err != nil {
-			return fmt.Errorf("invalid transaction %d: %v", i, err)
-		}
-	}
-
-	// Validate merkle root
-	expectedMerkleRoot := cm.CalculateMerkleRoot(block.Txs)
-	if block.Header.MerkleRoot != expectedMerkleRoot {
-		return fmt.Errorf("invalid merkle root")
-	}
-
-	// Validate transaction count
-	if block.Header.TxCount != uint32(len(block.Txs)) {
-		return fmt.Errorf("invalid transaction count")
-	}
-
-	return nil
-}
-
-// ValidateTransaction validates a single transaction
-func (cm *ConsensusManager) ValidateTransaction(tx *Transaction) error {
-	if tx == nil {
-		return fmt.Errorf("transaction is nil")
-	}
-
-	// Basic validation
-	if !tx.IsValid() {
-		return fmt.Errorf("transaction is invalid")
-	}
-
-	// Validate fee
-	minFee := uint64(cm.genesis.NetworkFee.BaseTxFee * 1000000) // Convert to micro-KALON
-	if tx.Fee < minFee {
-		return fmt.Errorf("transaction fee too low: %d < %d", tx.Fee, minFee)
-	}
-
-	// Validate gas
-	if tx.GasUsed == 0 {
-		tx.GasUsed = 1 // Default gas usage
-	}
-
-	expectedFee := tx.GasUsed * tx.GasPrice
-	if tx.Fee < expectedFee {
-		return fmt.Errorf("transaction fee insufficient for gas: %d < %d", tx.Fee, expectedFee)
-	}
-
-	// Validate signature (placeholder - would need actual signature verification)
-	if len(tx.Signature) == 0 {
-		return fmt.Errorf("transaction missing signature")
-	}
-
-	return nil
-}
-
-// ValidateProofOfWork validates the proof of work for a block
-func (cm *ConsensusManager) ValidateProofOfWork(block *Block) bool {
-	// For testnet, allow any hash only for very low difficulty (1-20) for quick testing
-	// Difficulty 21+ requires real PoW validation for fairness and stability
-	if block.Header.Difficulty <= 20 {
-		log.Printf("Testnet: Allowing block with difficulty %d (no PoW validation for quick testing)", block.Header.Difficulty)
-		return true
-	}
-
-	// Calculate target difficulty
-	target := cm.CalculateTarget(block.Header.Difficulty)
-
-	// Calculate block hash
-	blockHash := block.CalculateHash()
-
-	// Check if hash meets difficulty target
-	isValid := cm.IsValidHash(blockHash, target)
-	log.Printf("PoW Validation: Difficulty %d, Hash %x, Target %x, Valid: %v",
-		block.Header.Difficulty, blockHash, target, isValid)
-	return isValid
-}
-
-// CalculateDifficulty calculates the difficulty for the next block using LWMA
-func (cm *ConsensusManager) CalculateDifficulty(height uint64, parent *Block) uint64 {
-	if height == 0 {
-		return cm.genesis.Difficulty.InitialDifficulty // Initial difficulty
-	}
-
-	// Check if we're in launch guard period
-	if cm.genesis.Difficulty.LaunchGuard.Enabled {
-		launchGuardBlocks := cm.genesis.Difficulty.LaunchGuard.DurationHours * 3600 / cm.genesis.BlockTimeTarget
-		if height < launchGuardBlocks {
-			return uint64(float64(cm.genesis.Difficulty.InitialDifficulty) * cm.genesis.Difficulty.LaunchGuard.DifficultyFloorMultiplier)
-		}
-	}
-
-	// LWMA (Linear Weighted Moving Average) difficulty adjustment
-	window := cm.genesis.Difficulty.Window
-	if height < window {
-		return parent.Header.Difficulty
-	}
-
-	// For blocks during launch guard, keep difficulty stable
-	// Just return parent difficulty for now (no adjustment during launch)
-	// Once we implement proper LWMA with block history, this will work correctly
-	if height < cm.genesis.Difficulty.Window {
-		return parent.Header.Difficulty
-	}
-
-	// Keep difficulty stable (no adjustment factor yet)
-	adjustmentFactor := 1.0
-
-	// Apply maximum adjustment limit
-	maxAdjust := float64(cm.genesis.Difficulty.MaxAdjustPerBlockPct) / 100.0
-	if adjustmentFactor > 1+maxAdjust {
-		adjustmentFactor = 1 + maxAdjust
-	} else if adjustmentFactor < 1-maxAdjust {
-		adjustmentFactor = 1 - maxAdjust
-	}
-
-	newDifficulty := uint64(float64(parent.Header.Difficulty) * adjustmentFactor)
-
-	// Ensure minimum difficulty
-	if newDifficulty < 1 {
-		newDifficulty = 1
-	}
-
-	return newDifficulty
-}
-
-// CalculateTarget calculates the target hash for a given difficulty
-func (cm *ConsensusManager) CalculateTarget(difficulty uint64) []byte {
-	// Target = 2^256 / difficulty
-	target := make([]byte, 32)
-
-	if difficulty == 0 {
-		// Maximum target (all 0xFF) - any hash is valid
-		for i := range target {
-			target[i] = 0xFF
-		}
-		return target
-	}
-
-	if difficulty == 1 {
-		// For difficulty 1, use maximum target (any hash is valid)
-		for i := range target {
-			target[i] = 0xFF
-		}
-		return target
-	}
-
-	// For higher difficulties, calculate leading zero bytes needed
-	// Simple approach: difficulty N requires N leading zero bits
-	leadingZeroBits := difficulty - 1
-	leadingZeroBytes := leadingZeroBits / 8
-	remainingBits := leadingZeroBits % 8
-
-	// Fill non-zero bytes with 0xFF
-	for i := int(leadingZeroBytes); i < 32; i++ {
-		target[i] = 0xFF
-	}
-
-	// Set the partial byte if needed
-	if remainingBits > 0 && leadingZeroBytes < 32 {
-		target[leadingZeroBytes] = byte(0xFF >> remainingBits)
-	}
-
-	return target
-}
-
-// IsValidHash checks if a hash meets the target difficulty
-func (cm *ConsensusManager) IsValidHash(hash Hash, target []byte) bool {
-	hashBytes := hash.Bytes()
-
-	// Special case: if target is all 0xFF, any hash is valid (difficulty 0 or 1)
-	allFF := true
-	for i := 0; i < 32; i++ {
-		if target[i] != 0xFF {
-			allFF = false
-			break
-		}
-	}
-
-	if allFF {
-		return true
-	}
-
-	// Compare hash with target (lower is better)
-	for i := 0; i < 32; i++ {
-		if hashBytes[i] < target[i] {
-			return true
-		} else if hashBytes[i] > target[i] {
-			return false
-		}
-	}
-
-	return true // Equal is valid
-}
-
-// CalculateMerkleRoot calculates the merkle root of transactions
-func (cm *ConsensusManager) CalculateMerkleRoot(txs []Transaction) Hash {
-	if len(txs) == 0 {
-		// Empty merkle root
-		return Hash{}
-	}
-
-	if len(txs) == 1 {
-		return txs[0].CalculateHash()
-	}
-
-	// Build merkle tree
-	hashes := make([][]byte, len(txs))
-	for i, tx := range txs {
-		hashes[i] = tx.CalculateHash().Bytes()
-	}
-
-	for len(hashes) > 1 {
-		var nextLevel [][]byte
-
-		for i := 0; i < len(hashes); i += 2 {
-			var left, right []byte
-			left = hashes[i]
-
-			if i+1 < len(hashes) {
-				right = hashes[i+1]
-			} else {
-				right = hashes[i] // Duplicate last element if odd number
-			}
-
-			// Concatenate and hash
-			combined := append(left, right...)
-			hash := sha256.Sum256(combined)
-			nextLevel = append(nextLevel, hash[:])
-		}
-
-		hashes = nextLevel
-	}
-
-	var result Hash
-	copy(result[:], hashes[0])
-	return result
-}
-
-// CalculateBlockReward calculates the block reward distribution
-func (cm *ConsensusManager) CalculateBlockReward(height uint64, txFees uint64) BlockReward {
-	baseReward := cm.genesis.GetCurrentReward(height)
-	return cm.genesis.CalculateNetworkFees(baseReward, txFees)
-}
-
-// IsLaunchGuardActive checks if launch guard is still active
-func (cm *ConsensusManager) IsLaunchGuardActive(height uint64) bool {
-	if !cm.genesis.Difficulty.LaunchGuard.Enabled {
-		return false
-	}
-
-	launchGuardBlocks := cm.genesis.Difficulty.LaunchGuard.DurationHours * 3600 / cm.genesis.BlockTimeTarget
-	return height < launchGuardBlocks
-}
-
-// GetNetworkFeeRate returns the current network fee rate
-func (cm *ConsensusManager) GetNetworkFeeRate() float64 {
-	return cm.genesis.NetworkFee.BlockFeeRate
-}
-
-// GetTxFeeShareTreasury returns the transaction fee share for treasury
-func (cm *ConsensusManager) GetTxFeeShareTreasury() float64 {
-	return cm.genesis.NetworkFee.TxFeeShareTreasury
-}
+package core
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MinTxBytes is a lower bound on the serialized size of any transaction
+const MinTxBytes = 64
+
+var (
+	// ErrInvalidTransaction matches errors for transactions that break the rules whatever
+	// the chain state, unlike ones spending outputs that are missing, spent or immature
+	ErrInvalidTransaction = errors.New("invalid transaction")
+
+	// ErrFutureBlock is returned for blocks whose time is too far ahead of network time.
+	// Such a block may become valid later, so it does not show that its sender misbehaved.
+	ErrFutureBlock = errors.New("block timestamp too far in future")
+)
+
+// invalidTxError is a transaction rule violation that matches ErrInvalidTransaction
+type invalidTxError struct {
+	msg string
+}
+
+func (e *invalidTxError) Error() string {
+	return e.msg
+}
+
+func (e *invalidTxError) Is(target error) bool {
+	return target == ErrInvalidTransaction
+}
+
+// invalidTx returns an error matching ErrInvalidTransaction
+func invalidTx(format string, args ...interface{}) error {
+	return &invalidTxError{msg: fmt.Sprintf(format, args...)}
+}
+
+// Rules is the single consensus rules engine used for block templates, block
+// validation and mempool admission. Parameters come from the genesis config
+// and may change at fork heights declared in its fork schedule.
+type Rules interface {
+	// Params returns the consensus parameters in effect at a height
+	Params(height uint64) ConsensusParams
+
+	// IsActive reports whether the named network upgrade is active at height
+	IsActive(name string, height uint64) bool
+
+	// NextDifficulty returns the required difficulty for the block at height.
+	// recent holds the most recent blocks, ending with the parent.
+	NextDifficulty(height uint64, recent []*Block) uint64
+
+	// BlockReward returns the block subsidy at height in smallest units
+	BlockReward(height uint64) uint64
+
+	// CheckProofOfWork reports whether the block hash meets its difficulty
+	CheckProofOfWork(block *Block) bool
+
+	// ValidateBlock validates a block against its parent and chain state
+	ValidateBlock(block *Block, ctx *BlockContext) error
+
+	// ValidateTransaction validates a transaction for inclusion in a block at spendHeight
+	ValidateTransaction(tx *Transaction, spendHeight uint64, utxos *UTXOSet) error
+}
+
+// BlockContext is the chain state a block is validated against
+type BlockContext struct {
+	Recent      []*Block  // Most recent blocks, ending with the parent
+	NetworkTime time.Time // Network-adjusted current time
+	UTXOs       *UTXOSet  // UTXO set before the block is applied
+}
+
+// Parent returns the parent block of the block being validated
+func (ctx *BlockContext) Parent() *Block {
+	if len(ctx.Recent) == 0 {
+		return nil
+	}
+	return ctx.Recent[len(ctx.Recent)-1]
+}
+
+// genesisRules implements Rules from a genesis configuration
+type genesisRules struct {
+	genesis *GenesisConfig
+}
+
+// NewRules creates the consensus rules for a genesis configuration
+func NewRules(genesis *GenesisConfig) Rules {
+	return &genesisRules{
+		genesis: genesis,
+	}
+}
+
+// Params returns the consensus parameters in effect at a height
+func (r *genesisRules) Params(height uint64) ConsensusParams {
+	return r.genesis.ParamsAt(height)
+}
+
+// IsActive reports whether the named network upgrade is active at height
+func (r *genesisRules) IsActive(name string, height uint64) bool {
+	return r.genesis.IsActive(name, height)
+}
+
+// NextDifficulty calculates the difficulty for the next block.
+// Difficulty counts leading zero bits of the hash, so one step doubles the work.
+func (r *genesisRules) NextDifficulty(height uint64, recent []*Block) uint64 {
+	if height == 0 || len(recent) == 0 {
+		return r.genesis.Difficulty.InitialDifficulty
+	}
+	parent := recent[len(recent)-1]
+
+	// Keep difficulty at the floor while the launch guard is active
+	if r.isLaunchGuardActive(height) {
+		return uint64(float64(r.genesis.Difficulty.InitialDifficulty) * r.genesis.Difficulty.LaunchGuard.DifficultyFloorMultiplier)
+	}
+
+	params := r.Params(height)
+	window := params.DifficultyWindow
+	if !params.DifficultyRetarget || window == 0 || uint64(len(recent)) <= window {
+		return parent.Header.Difficulty
+	}
+
+	// Linearly weighted moving average of the solve times in the window
+	blocks := recent[uint64(len(recent))-window-1:]
+	target := int64(params.BlockTimeTarget)
+	var weightedSum, weights int64
+	for i := 1; i < len(blocks); i++ {
+		solveTime := blocks[i].Header.Timestamp.Unix() - blocks[i-1].Header.Timestamp.Unix()
+		if solveTime < 1 {
+			solveTime = 1
+		}
+		if solveTime > 6*target {
+			solveTime = 6 * target
+		}
+		weightedSum += solveTime * int64(i)
+		weights += int64(i)
+	}
+	avgSolveTime := weightedSum / weights
+
+	difficulty := parent.Header.Difficulty
+	switch {
+	case avgSolveTime*2 < target && difficulty < 63:
+		difficulty++
+	case avgSolveTime > target*2 && difficulty > 1:
+		difficulty--
+	}
+
+	return difficulty
+}
+
+// isLaunchGuardActive checks if the fair launch difficulty floor applies at height
+func (r *genesisRules) isLaunchGuardActive(height uint64) bool {
+	guard := r.genesis.Difficulty.LaunchGuard
+	if !guard.Enabled {
+		return false
+	}
+
+	launchGuardBlocks := guard.DurationHours * 3600 / r.Params(0).BlockTimeTarget
+	return height < launchGuardBlocks
+}
+
+// BlockReward calculates the block subsidy for a given block number
+func (r *genesisRules) BlockReward(height uint64) uint64 {
+	// Start with initial block reward (5 tKALON = 5,000,000 units)
+	reward := uint64(r.genesis.InitialBlockReward * 1000000) // Convert to smallest units
+
+	// Apply halving schedule
+	for _, halving := range r.genesis.HalvingSchedule {
+		if height > halving.AfterBlocks {
+			reward = uint64(float64(reward) * halving.RewardMultiplier)
+		}
+	}
+
+	return reward
+}
+
+// CheckProofOfWork validates the proof of work for a block
+func (r *genesisRules) CheckProofOfWork(block *Block) bool {
+	difficulty := block.Header.Difficulty
+
+	// Low difficulties are exempt so testnets can mine without real work
+	if difficulty <= r.Params(block.Header.Number).PoWExemptDifficulty {
+		return true
+	}
+	if difficulty >= 64 {
+		return false
+	}
+
+	// The first 64 bits of the hash must be below 2^(64-difficulty)
+	target := uint64(1) << (64 - difficulty)
+	hashInt := binary.BigEndian.Uint64(block.Hash[:8])
+	return hashInt < target
+}
+
+// ValidateBlock validates a block according to consensus rules
+func (r *genesisRules) ValidateBlock(block *Block, ctx *BlockContext) error {
+	if block == nil {
+		return fmt.Errorf("block is nil")
+	}
+
+	// The proof of work is checked against the hash, so it must be the hash of this header
+	if hash := block.CalculateHash(); block.Hash != hash {
+		return fmt.Errorf("invalid block hash: expected %x, got %x", hash, block.Hash)
+	}
+
+	parent := ctx.Parent()
+	if parent == nil {
+		return fmt.Errorf("no parent block found")
+	}
+
+	// Validate parent hash
+	if block.Header.ParentHash != parent.Hash {
+		return fmt.Errorf("invalid parent hash: expected %x, got %x", parent.Hash, block.Header.ParentHash)
+	}
+
+	// Validate block number
+	if block.Header.Number != parent.Header.Number+1 {
+		return fmt.Errorf("invalid block number: expected %d, got %d", parent.Header.Number+1, block.Header.Number)
+	}
+
+	height := block.Header.Number
+	params := r.Params(height)
+
+	// Validate size limits before doing any per-transaction work
+	if err := r.validateBlockLimits(block, params); err != nil {
+		return err
+	}
+
+	// Validate timestamp against the median of the previous blocks, or the parent before that upgrade
+	if r.IsActive(UpgradeMedianTimePast, height) {
+		start := len(ctx.Recent) - MedianTimeSpan
+		if start < 0 {
+			start = 0
+		}
+		medianTime := CalcMedianTimePast(ctx.Recent[start:])
+		if !block.Header.Timestamp.After(medianTime) {
+			return fmt.Errorf("block timestamp not after median time past: %v <= %v", block.Header.Timestamp, medianTime)
+		}
+	} else if block.Header.Timestamp.Before(parent.Header.Timestamp) {
+		return fmt.Errorf("block timestamp before parent: %v < %v", block.Header.Timestamp, parent.Header.Timestamp)
+	}
+
+	// Validate timestamp is not too far ahead of network-adjusted time
+	maxTime := ctx.NetworkTime.Add(params.MaxFutureBlockTimeDuration())
+	if block.Header.Timestamp.After(maxTime) {
+		return fmt.Errorf("%w: %v > %v", ErrFutureBlock, block.Header.Timestamp, maxTime)
+	}
+
+	// Validate difficulty
+	if r.IsActive(UpgradeStrictDifficulty, height) {
+		expectedDifficulty := r.NextDifficulty(height, ctx.Recent)
+		if block.Header.Difficulty != expectedDifficulty {
+			return fmt.Errorf("invalid difficulty: expected %d, got %d", expectedDifficulty, block.Header.Difficulty)
+		}
+	}
+
+	// Validate proof of work
+	if !r.CheckProofOfWork(block) {
+		return fmt.Errorf("invalid proof of work")
+	}
+
+	// The header hash covers the merkle root, so the proof of work covers the transactions
+	if r.IsActive(UpgradeTxCommitments, height) {
+		if n := uint32(len(block.Txs)); block.Header.TxCount != n {
+			return fmt.Errorf("invalid transaction count: header has %d, block has %d", block.Header.TxCount, n)
+		}
+		if root := CalculateMerkleRoot(block.Txs); block.Header.MerkleRoot != root {
+			return fmt.Errorf("invalid merkle root: expected %x, got %x", root, block.Header.MerkleRoot)
+		}
+	}
+
+	// Exactly one coinbase, first in the block, creates the reward
+	if len(block.Txs) == 0 || !block.Txs[0].IsCoinbase() {
+		return fmt.Errorf("block does not start with a coinbase")
+	}
+	coinbase := &block.Txs[0]
+	if r.IsActive(UpgradeTxCommitments, height) {
+		if id := coinbase.TxID(); coinbase.Hash != id {
+			return fmt.Errorf("coinbase hash %x does not match its id %x", coinbase.Hash, id)
+		}
+	}
+	var coinbaseAmount uint64
+	for _, output := range coinbase.Outputs {
+		if coinbaseAmount+output.Amount < coinbaseAmount {
+			return fmt.Errorf("coinbase output amounts overflow")
+		}
+		coinbaseAmount += output.Amount
+	}
+
+	// Validate transactions. Inputs may spend outputs created earlier in the block,
+	// but no output may be spent twice.
+	var fees uint64
+	created := make(map[outpoint]*UTXO)
+	spent := make(map[outpoint]bool)
+	lookup := func(txHash Hash, index uint32) *UTXO {
+		if utxo, ok := created[outpoint{txHash, index}]; ok {
+			return utxo
+		}
+		if ctx.UTXOs == nil {
+			return nil
+		}
+		return ctx.UTXOs.GetUTXO(txHash, index)
+	}
+	for i := 1; i < len(block.Txs); i++ {
+		tx := &block.Txs[i]
+		if tx.IsCoinbase() {
+			return fmt.Errorf("transaction %d is a second coinbase", i)
+		}
+
+		for _, input := range tx.Inputs {
+			// Rewards created in this very block are never mature
+			if params.CoinbaseMaturity > 0 && input.PreviousTxHash == coinbase.Hash {
+				return fmt.Errorf("transaction %x spends coinbase output %x from the same block", tx.Hash, input.PreviousTxHash)
+			}
+			if spent[outpoint{input.PreviousTxHash, input.Index}] {
+				return fmt.Errorf("transaction %x spends output %x:%d already spent in this block", tx.Hash, input.PreviousTxHash, input.Index)
+			}
+		}
+
+		if err := r.validateTransaction(tx, height, params, lookup); err != nil {
+			return fmt.Errorf("invalid transaction %d: %v", i, err)
+		}
+		if fees+tx.Fee < fees {
+			return fmt.Errorf("block fees overflow")
+		}
+		fees += tx.Fee
+
+		for _, input := range tx.Inputs {
+			spent[outpoint{input.PreviousTxHash, input.Index}] = true
+		}
+		for j, output := range tx.Outputs {
+			created[outpoint{tx.Hash, uint32(j)}] = &UTXO{
+				TxHash:  tx.Hash,
+				Index:   uint32(j),
+				Amount:  output.Amount,
+				Address: output.Address,
+				Height:  height,
+			}
+		}
+	}
+
+	// Validate the miner did not claim more than subsidy plus fees
+	if r.IsActive(UpgradeCoinbaseLimit, height) {
+		maxReward := r.BlockReward(height) + fees
+		if maxReward < fees {
+			return fmt.Errorf("block reward overflows")
+		}
+		if coinbaseAmount > maxReward {
+			return fmt.Errorf("block reward too high: %d > %d", coinbaseAmount, maxReward)
+		}
+	}
+
+	return nil
+}
+
+// validateBlockLimits checks the block and its transactions against the size limits
+func (r *genesisRules) validateBlockLimits(block *Block, params ConsensusParams) error {
+	if params.MaxBlockBytes > 0 {
+		// Every transaction takes at least a few bytes, so the count is bounded before sizing
+		if uint64(len(block.Txs)) > params.MaxBlockBytes/MinTxBytes {
+			return fmt.Errorf("too many transactions in block: %d", len(block.Txs))
+		}
+
+		if size := uint64(block.Size()); size > params.MaxBlockBytes {
+			return fmt.Errorf("block too large: %d bytes (max %d)", size, params.MaxBlockBytes)
+		}
+	}
+
+	for i := range block.Txs {
+		if err := validateTransactionLimits(&block.Txs[i], params); err != nil {
+			return fmt.Errorf("invalid transaction %d: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateTransaction validates a transaction for inclusion in a block at spendHeight
+func (r *genesisRules) ValidateTransaction(tx *Transaction, spendHeight uint64, utxos *UTXOSet) error {
+	if tx == nil {
+		return fmt.Errorf("transaction is nil")
+	}
+
+	// Without a UTXO set every input is missing, but the limits are still checked first
+	lookup := func(Hash, uint32) *UTXO { return nil }
+	if utxos != nil {
+		lookup = utxos.GetUTXO
+	}
+	return r.validateTransaction(tx, spendHeight, r.Params(spendHeight), lookup)
+}
+
+// validateTransaction checks a transaction against the limits, that every input spends
+// an unspent, mature output owned by the key that signed it, and that the inputs cover
+// the outputs plus the fee. lookup finds the output an input spends.
+func (r *genesisRules) validateTransaction(tx *Transaction, spendHeight uint64, params ConsensusParams, lookup func(txHash Hash, index uint32) *UTXO) error {
+	if err := validateTransactionLimits(tx, params); err != nil {
+		return err
+	}
+
+	if tx.IsCoinbase() {
+		return invalidTx("transaction has no inputs")
+	}
+	if len(tx.Outputs) == 0 {
+		return invalidTx("transaction has no outputs")
+	}
+	// Outputs are indexed by the transaction hash, so it must identify these contents
+	if id := tx.TxID(); tx.Hash != id {
+		return invalidTx("transaction hash %x does not match its id %x", tx.Hash, id)
+	}
+
+	var totalIn uint64
+	seen := make(map[outpoint]bool, len(tx.Inputs))
+	for i, input := range tx.Inputs {
+		if seen[outpoint{input.PreviousTxHash, input.Index}] {
+			return invalidTx("input %d spends %x:%d twice", i, input.PreviousTxHash, input.Index)
+		}
+		seen[outpoint{input.PreviousTxHash, input.Index}] = true
+
+		utxo := lookup(input.PreviousTxHash, input.Index)
+		if utxo == nil || utxo.Spent {
+			return fmt.Errorf("input %d spends missing or spent output %x:%d", i, input.PreviousTxHash, input.Index)
+		}
+
+		// Ensure the transaction does not spend block rewards before they mature
+		if params.CoinbaseMaturity > 0 && !utxo.IsMature(spendHeight, params.CoinbaseMaturity) {
+			return fmt.Errorf("transaction %x spends immature coinbase output %x:%d (created at height %d, spendable at %d)",
+				tx.Hash, input.PreviousTxHash, input.Index, utxo.Height, utxo.Height+params.CoinbaseMaturity)
+		}
+
+		if len(input.PublicKey) != ed25519.PublicKeySize {
+			return invalidTx("input %d has no valid public key", i)
+		}
+		if PubKeyAddress(input.PublicKey) != utxo.Address {
+			return invalidTx("input %d public key does not own output %x:%d", i, input.PreviousTxHash, input.Index)
+		}
+		sigHash := tx.SigHash(i)
+		if !ed25519.Verify(input.PublicKey, sigHash[:], input.Signature) {
+			return invalidTx("input %d has an invalid signature", i)
+		}
+
+		if totalIn+utxo.Amount < totalIn {
+			return invalidTx("input amounts overflow")
+		}
+		totalIn += utxo.Amount
+	}
+
+	totalOut := tx.Fee
+	for i, output := range tx.Outputs {
+		if output.Amount == 0 {
+			return invalidTx("output %d has zero amount", i)
+		}
+		if totalOut+output.Amount < totalOut {
+			return invalidTx("output amounts overflow")
+		}
+		totalOut += output.Amount
+	}
+	if totalOut > totalIn {
+		return invalidTx("outputs plus fee (%d) exceed inputs (%d)", totalOut, totalIn)
+	}
+
+	return nil
+}
+
+// validateTransactionLimits checks a transaction against the size and input/output limits
+func validateTransactionLimits(tx *Transaction, params ConsensusParams) error {
+	if n := uint64(len(tx.Inputs)); params.MaxTxInputs > 0 && n > params.MaxTxInputs {
+		return invalidTx("too many inputs: %d (max %d)", n, params.MaxTxInputs)
+	}
+
+	if n := uint64(len(tx.Outputs)); params.MaxTxOutputs > 0 && n > params.MaxTxOutputs {
+		return invalidTx("too many outputs: %d (max %d)", n, params.MaxTxOutputs)
+	}
+
+	if size := uint64(tx.Size()); params.MaxTxBytes > 0 && size > params.MaxTxBytes {
+		return invalidTx("transaction too large: %d bytes (max %d)", size, params.MaxTxBytes)
+	}
+
+	return nil
+}
+
+// CalculateMerkleRoot calculates the merkle root of transactions. Leaves hash the full
+// encoding, signatures included, so no part of a transaction can change under the root.
+func CalculateMerkleRoot(txs []Transaction) Hash {
+	if len(txs) == 0 {
+		// Empty merkle root
+		return Hash{}
+	}
+
+	// Build merkle tree
+	hashes := make([][]byte, len(txs))
+	for i := range txs {
+		leaf := sha256.Sum256(txs[i].appendBinary(make([]byte, 0, 256), true))
+		hashes[i] = leaf[:]
+	}
+
+	for len(hashes) > 1 {
+		var nextLevel [][]byte
+
+		for i := 0; i < len(hashes); i += 2 {
+			var left, right []byte
+			left = hashes[i]
+
+			if i+1 < len(hashes) {
+				right = hashes[i+1]
+			} else {
+				right = hashes[i] // Duplicate last element if odd number
+			}
+
+			// Concatenate and hash
+			combined := append(append([]byte{}, left...), right...)
+			hash := sha256.Sum256(combined)
+			nextLevel = append(nextLevel, hash[:])
+		}
+
+		hashes = nextLevel
+	}
+
+	var result Hash
+	copy(result[:], hashes[0])
+	return result
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultBlockTimeTarget is the block time in seconds used when the genesis does not set one
+	DefaultBlockTimeTarget = 15
+
+	// DefaultPoWExemptDifficulty is the highest difficulty that skips proof of work checks.
+	// Chains launched under this rule keep it until a fork sets powExemptDifficulty to 0.
+	DefaultPoWExemptDifficulty = 20
+)
+
+// ConsensusParams holds the consensus parameters in effect at a given height.
+// A size or count limit of 0 means the consensus rules do not limit it.
+type ConsensusParams struct {
+	BlockTimeTarget     uint64 `json:"blockTimeTargetSeconds"`
+	CoinbaseMaturity    uint64 `json:"coinbaseMaturity"`
+	MaxBlockBytes       uint64 `json:"maxBlockBytes"`
+	MaxTxBytes          uint64 `json:"maxTxBytes"`
+	MaxTxInputs         uint64 `json:"maxTxInputs"`
+	MaxTxOutputs        uint64 `json:"maxTxOutputs"`
+	MaxFutureBlockTime  uint64 `json:"maxFutureBlockTimeSeconds"`
+	PoWExemptDifficulty uint64 `json:"powExemptDifficulty"`
+	DifficultyWindow    uint64 `json:"difficultyWindow"`
+	DifficultyRetarget  bool   `json:"difficultyRetarget"`
+}
+
+// MaxFutureBlockTimeDuration returns the allowed block time drift as a duration
+func (p ConsensusParams) MaxFutureBlockTimeDuration() time.Duration {
+	return time.Duration(p.MaxFutureBlockTime) * time.Second
+}
+
+// ConsensusFork is a height-activated change to the consensus parameters.
+// Unset fields keep the value from the previous fork or the genesis base.
+type ConsensusFork struct {
+	Name                string  `json:"name"`
+	Height              uint64  `json:"height"`
+	BlockTimeTarget     *uint64 `json:"blockTimeTargetSeconds,omitempty"`
+	CoinbaseMaturity    *uint64 `json:"coinbaseMaturity,omitempty"`
+	MaxBlockBytes       *uint64 `json:"maxBlockBytes,omitempty"`
+	MaxTxBytes          *uint64 `json:"maxTxBytes,omitempty"`
+	MaxTxInputs         *uint64 `json:"maxTxInputs,omitempty"`
+	MaxTxOutputs        *uint64 `json:"maxTxOutputs,omitempty"`
+	MaxFutureBlockTime  *uint64 `json:"maxFutureBlockTimeSeconds,omitempty"`
+	PoWExemptDifficulty *uint64 `json:"powExemptDifficulty,omitempty"`
+	DifficultyWindow    *uint64 `json:"difficultyWindow,omitempty"`
+	DifficultyRetarget  *bool   `json:"difficultyRetarget,omitempty"`
+}
+
+// apply overrides the parameters set by the fork
+func (f *ConsensusFork) apply(p *ConsensusParams) {
+	setUint := func(dst *uint64, src *uint64) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+
+	setUint(&p.BlockTimeTarget, f.BlockTimeTarget)
+	setUint(&p.CoinbaseMaturity, f.CoinbaseMaturity)
+	setUint(&p.MaxBlockBytes, f.MaxBlockBytes)
+	setUint(&p.MaxTxBytes, f.MaxTxBytes)
+	setUint(&p.MaxTxInputs, f.MaxTxInputs)
+	setUint(&p.MaxTxOutputs, f.MaxTxOutputs)
+	setUint(&p.MaxFutureBlockTime, f.MaxFutureBlockTime)
+	setUint(&p.PoWExemptDifficulty, f.PoWExemptDifficulty)
+	setUint(&p.DifficultyWindow, f.DifficultyWindow)
+	if f.DifficultyRetarget != nil {
+		p.DifficultyRetarget = *f.DifficultyRetarget
+	}
+}
+
+// BaseParams returns the consensus parameters at genesis, before any fork
+func (g *GenesisConfig) BaseParams() ConsensusParams {
+	blockTimeTarget := g.BlockTimeTarget
+	if blockTimeTarget == 0 {
+		blockTimeTarget = DefaultBlockTimeTarget
+	}
+
+	return ConsensusParams{
+		BlockTimeTarget:     blockTimeTarget,
+		CoinbaseMaturity:    g.CoinbaseMaturity,
+		MaxBlockBytes:       g.MaxBlockBytes,
+		MaxTxBytes:          g.MaxTxBytes,
+		MaxTxInputs:         g.MaxTxInputs,
+		MaxTxOutputs:        g.MaxTxOutputs,
+		MaxFutureBlockTime:  uint64(g.GetMaxFutureBlockTime() / time.Second),
+		PoWExemptDifficulty: DefaultPoWExemptDifficulty,
+		DifficultyWindow:    g.Difficulty.Window,
+	}
+}
+
+// ParamsAt returns the consensus parameters in effect at a height
+func (g *GenesisConfig) ParamsAt(height uint64) ConsensusParams {
+	params := g.BaseParams()
+	for i := range g.Forks {
+		if g.Forks[i].Height > height {
+			break
+		}
+		g.Forks[i].apply(&params)
+	}
+	return params
+}
+
+// MaxScheduledBlockBytes returns the largest block size allowed at any height.
+// Network and RPC buffers are sized with this so they never reject a valid block.
+func (g *GenesisConfig) MaxScheduledBlockBytes() uint64 {
+	max := g.GetMaxBlockBytes()
+	params := g.BaseParams()
+	for i := range g.Forks {
+		g.Forks[i].apply(&params)
+		if params.MaxBlockBytes > max {
+			max = params.MaxBlockBytes
+		}
+	}
+	return max
+}
+
+// MaxScheduledTxBytes returns the largest transaction size allowed at any height
+func (g *GenesisConfig) MaxScheduledTxBytes() uint64 {
+	max := g.GetMaxTxBytes()
+	params := g.BaseParams()
+	for i := range g.Forks {
+		g.Forks[i].apply(&params)
+		if params.MaxTxBytes > max {
+			max = params.MaxTxBytes
+		}
+	}
+	return max
+}
+
+// Validate checks the genesis configuration for consistency
+func (g *GenesisConfig) Validate() error {
+	if err := g.validateUpgrades(); err != nil {
+		return err
+	}
+
+	for i, fork := range g.Forks {
+		if i > 0 && fork.Height <= g.Forks[i-1].Height {
+			return fmt.Errorf("fork %d (%s): activation heights must be strictly increasing", i, fork.Name)
+		}
+
+		params := g.ParamsAt(fork.Height)
+		if params.BlockTimeTarget == 0 {
+			return fmt.Errorf("fork %d (%s): blockTimeTargetSeconds must be positive", i, fork.Name)
+		}
+		if isZero(fork.MaxBlockBytes) || isZero(fork.MaxTxBytes) || isZero(fork.MaxTxInputs) || isZero(fork.MaxTxOutputs) {
+			return fmt.Errorf("fork %d (%s): size limits must be positive", i, fork.Name)
+		}
+	}
+	return nil
+}
+
+// isZero reports whether an optional fork parameter is set to 0
+func isZero(v *uint64) bool {
+	return v != nil && *v == 0
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+)
+
+// MaxReorgDepth is the most main chain blocks a reorganization may replace
+const MaxReorgDepth = 100
+
+// BestBlockSetter is implemented by persisters whose best block can be moved back,
+// as a reorganization to a shorter branch with more work requires
+type BestBlockSetter interface {
+	SetBestBlock(block *Block) error
+}
+
+// BlockWork returns the expected number of hashes needed to find a block at a difficulty.
+// Difficulty counts leading zero bits of the hash, so each step doubles the work.
+func BlockWork(difficulty uint64) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(difficulty))
+}
+
+// branchWork returns the total work of a sequence of blocks
+func branchWork(blocks []*Block) *big.Int {
+	work := new(big.Int)
+	for _, block := range blocks {
+		work.Add(work, BlockWork(block.Header.Difficulty))
+	}
+	return work
+}
+
+// ReorganizeV2 replaces the main chain blocks above the parent of blocks[0] with blocks,
+// which must follow each other and carry more work than the blocks they replace. If a
+// block of the branch is invalid, the previous main chain is restored.
+func (bc *BlockchainV2) ReorganizeV2(blocks []*Block) error {
+	if len(blocks) == 0 {
+		return fmt.Errorf("no blocks to reorganize to")
+	}
+
+	bc.mu.Lock()
+	fork := bc.blockIndex[blocks[0].Header.ParentHash]
+	if fork == nil {
+		bc.mu.Unlock()
+		return fmt.Errorf("branch does not fork off the main chain")
+	}
+	if depth := bc.height - fork.Header.Number; depth > MaxReorgDepth {
+		bc.mu.Unlock()
+		return fmt.Errorf("reorganization of %d blocks exceeds the limit of %d", depth, MaxReorgDepth)
+	}
+	parent := fork
+	for _, block := range blocks {
+		if block.Header.ParentHash != parent.Hash || block.Header.Number != parent.Header.Number+1 {
+			bc.mu.Unlock()
+			return fmt.Errorf("branch is not continuous at block #%d", block.Header.Number)
+		}
+		parent = block
+	}
+
+	replaced := append([]*Block(nil), bc.blocks[fork.Header.Number+1:]...)
+	if branchWork(blocks).Cmp(branchWork(replaced)) <= 0 {
+		bc.mu.Unlock()
+		return fmt.Errorf("branch does not have more work than the main chain above #%d", fork.Header.Number)
+	}
+
+	for i := len(replaced) - 1; i >= 0; i-- {
+		bc.disconnectBlock(replaced[i])
+	}
+	for i, block := range blocks {
+		if err := bc.validateBlockV2(block); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				bc.disconnectBlock(blocks[j])
+			}
+			for _, old := range replaced {
+				bc.connectBlock(old)
+			}
+			bc.mu.Unlock()
+			return fmt.Errorf("block #%d of the branch failed validation: %w", block.Header.Number, err)
+		}
+		bc.connectBlock(block)
+		bc.eventBus.Emit(EventBlockAdded, map[string]interface{}{
+			"block":  block,
+			"height": bc.height,
+		})
+	}
+
+	// Transactions only the replaced blocks confirmed go back to the mempool
+	for _, old := range replaced {
+		for i := range old.Txs {
+			tx := old.Txs[i]
+			if tx.IsCoinbase() || bc.txIndex[tx.Hash] != nil {
+				continue
+			}
+			if bc.rules.ValidateTransaction(&tx, bc.height+1, bc.utxoSet) == nil {
+				bc.mempool.addIfUnspent(&tx)
+			}
+		}
+	}
+	tip := bc.bestBlock
+	bc.mu.Unlock()
+
+	log.Printf("🔀 Reorganized from #%d to #%d, replacing %d blocks above #%d", fork.Header.Number+uint64(len(replaced)), tip.Header.Number, len(replaced), fork.Header.Number)
+
+	for _, block := range blocks {
+		bc.storeBlock(block)
+	}
+	if setter, ok := bc.storage.(BestBlockSetter); ok {
+		if err := setter.SetBestBlock(tip); err != nil {
+			log.Printf("⚠️ Failed to update best block in storage: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// disconnectBlock removes the tip block from the main chain and undoes its changes to the
+// UTXO set. Outputs it created are removed and outputs it spent are unspent (caller holds the lock).
+func (bc *BlockchainV2) disconnectBlock(block *Block) {
+	bc.utxoSet.RemoveUTXOs(block.Hash)
+	for i := range block.Txs {
+		for _, input := range block.Txs[i].Inputs {
+			bc.utxoSet.UnspendUTXO(input.PreviousTxHash, input.Index)
+		}
+		delete(bc.txIndex, block.Txs[i].Hash)
+	}
+
+	bc.blocks = bc.blocks[:len(bc.blocks)-1]
+	delete(bc.blockIndex, block.Hash)
+	bc.bestBlock = bc.blocks[len(bc.blocks)-1]
+	bc.height = bc.bestBlock.Header.Number
+
+	bc.stateManager.SetState("height", bc.height)
+	bc.stateManager.SetState("bestBlock", bc.bestBlock.Hash)
+}
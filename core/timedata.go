@@ -0,0 +1,96 @@
+package core
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// MedianTimeSpan is the number of previous blocks used for the median-time-past rule
+	MedianTimeSpan = 11
+
+	// DefaultMaxFutureBlockTime is how far ahead of network time a block may be when the genesis does not say
+	DefaultMaxFutureBlockTime = 2 * time.Minute
+
+	// maxTimeOffset caps how far peers may pull our network-adjusted time away from the local clock
+	maxTimeOffset = 70 * time.Minute
+
+	// minTimeSamples is the number of peer samples required before applying an offset
+	minTimeSamples = 5
+
+	// maxTimeSamples bounds the number of peers tracked for time offsets
+	maxTimeSamples = 200
+)
+
+// NetworkTime tracks clock offsets reported by peers and provides network-adjusted time
+type NetworkTime struct {
+	mu      sync.RWMutex
+	samples map[string]time.Duration // Key: peer ID
+	offset  time.Duration
+}
+
+// NewNetworkTime creates a new network time source
+func NewNetworkTime() *NetworkTime {
+	return &NetworkTime{
+		samples: make(map[string]time.Duration),
+	}
+}
+
+// AddTimeSample records the clock offset of a peer (peer time minus local time)
+func (nt *NetworkTime) AddTimeSample(source string, offset time.Duration) {
+	nt.mu.Lock()
+	defer nt.mu.Unlock()
+
+	if _, exists := nt.samples[source]; !exists && len(nt.samples) >= maxTimeSamples {
+		return
+	}
+	nt.samples[source] = offset
+
+	if len(nt.samples) < minTimeSamples {
+		return
+	}
+
+	offsets := make([]time.Duration, 0, len(nt.samples))
+	for _, o := range nt.samples {
+		offsets = append(offsets, o)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	median := offsets[len(offsets)/2]
+
+	// Never trust peers that disagree wildly with our clock
+	if median > maxTimeOffset || median < -maxTimeOffset {
+		log.Printf("⚠️ Peer time offset %v exceeds %v, please check your system clock", median, maxTimeOffset)
+		nt.offset = 0
+		return
+	}
+	nt.offset = median
+}
+
+// Offset returns the current network time offset
+func (nt *NetworkTime) Offset() time.Duration {
+	nt.mu.RLock()
+	defer nt.mu.RUnlock()
+	return nt.offset
+}
+
+// Now returns the network-adjusted time
+func (nt *NetworkTime) Now() time.Time {
+	return time.Now().Add(nt.Offset())
+}
+
+// CalcMedianTimePast returns the median timestamp of the given blocks
+func CalcMedianTimePast(blocks []*Block) time.Time {
+	if len(blocks) == 0 {
+		return time.Time{}
+	}
+
+	timestamps := make([]int64, 0, len(blocks))
+	for _, block := range blocks {
+		timestamps = append(timestamps, block.Header.Timestamp.Unix())
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+
+	return time.Unix(timestamps[len(timestamps)/2], 0)
+}
@@ -0,0 +1,196 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultFeeRate is the fee per byte of signed transaction, in micro-KALON, used when none is given
+const DefaultFeeRate = 10
+
+// inputWitnessBytes is what signing adds to an input: a length prefixed public key and signature
+const inputWitnessBytes = 1 + ed25519.PublicKeySize + 1 + ed25519.SignatureSize
+
+// FundOptions controls how FundTransaction selects inputs and sets the fee
+type FundOptions struct {
+	ChangeAddress Address // Receives the change; the sender when zero
+	FeeRate       uint64  // Fee per signed byte in micro-KALON; DefaultFeeRate when zero
+	Fee           uint64  // Fixed fee replacing the estimate when non-zero
+	Data          []byte  // Optional transaction data
+}
+
+// SignedSize returns the binary size the transaction will have once every input is signed
+func (tx *Transaction) SignedSize() int {
+	// The unsigned encoding lacks the witnesses and the empty transaction signature prefix
+	return len(tx.appendBinary(make([]byte, 0, 256), false)) + len(tx.Inputs)*inputWitnessBytes + 1
+}
+
+// EstimateFee returns the fee for a transaction once signed: feeRate per byte of its
+// binary encoding, but never less than the network's base transaction fee
+func (g *GenesisConfig) EstimateFee(tx *Transaction, feeRate uint64) uint64 {
+	if feeRate == 0 {
+		feeRate = DefaultFeeRate
+	}
+	fee := uint64(tx.SignedSize()) * feeRate
+	if base := uint64(g.NetworkFee.BaseTxFee * 1000000); fee < base {
+		fee = base
+	}
+	return fee
+}
+
+// GetUTXO returns an unspent output, or nil if it does not exist or is spent
+func (bc *BlockchainV2) GetUTXO(txHash Hash, index uint32) *UTXO {
+	utxo := bc.utxoSet.GetUTXO(txHash, index)
+	if utxo == nil || utxo.Spent {
+		return nil
+	}
+	return utxo
+}
+
+// CreateRawTransaction builds an unsigned transaction spending explicit inputs.
+// The inputs must be unspent; whatever they hold beyond the outputs becomes the fee.
+func (bc *BlockchainV2) CreateRawTransaction(inputs []TxInput, outputs []TxOutput, data []byte) (*Transaction, error) {
+	if len(inputs) == 0 || len(outputs) == 0 {
+		return nil, fmt.Errorf("at least one input and one output are required")
+	}
+
+	var from Address
+	var totalIn uint64
+	for i, input := range inputs {
+		utxo := bc.GetUTXO(input.PreviousTxHash, input.Index)
+		if utxo == nil {
+			return nil, fmt.Errorf("input %d spends missing or spent output %x:%d", i, input.PreviousTxHash, input.Index)
+		}
+		if i == 0 {
+			from = utxo.Address
+		}
+		totalIn += utxo.Amount
+	}
+
+	totalOut, err := sumOutputs(outputs)
+	if err != nil {
+		return nil, err
+	}
+	if totalOut > totalIn {
+		return nil, fmt.Errorf("outputs (%d) exceed inputs (%d)", totalOut, totalIn)
+	}
+
+	unsigned := make([]TxInput, len(inputs))
+	for i, input := range inputs {
+		unsigned[i] = TxInput{PreviousTxHash: input.PreviousTxHash, Index: input.Index}
+	}
+	return newUnsignedTransaction(from, unsigned, outputs, totalIn-totalOut, data), nil
+}
+
+// FundTransaction builds an unsigned transaction paying outputs from the spendable
+// outputs of from. Outputs already spent by mempool transactions are skipped, the
+// largest are used first and the change goes back to the change address. Change too
+// small to pay for its own output is added to the fee.
+func (bc *BlockchainV2) FundTransaction(from Address, outputs []TxOutput, opts FundOptions) (*Transaction, error) {
+	totalOut, err := sumOutputs(outputs)
+	if err != nil {
+		return nil, err
+	}
+	changeAddress := opts.ChangeAddress
+	if changeAddress == (Address{}) {
+		changeAddress = from
+	}
+
+	spendHeight := bc.GetHeight() + 1
+	maxInputs := bc.rules.Params(spendHeight).MaxTxInputs
+	pending := bc.mempool.spentOutputs()
+
+	var candidates []*UTXO
+	for _, utxo := range bc.utxoSet.GetSpendableUTXOs(from, spendHeight, bc.coinbaseMaturity()) {
+		if !pending[outpoint{utxo.TxHash, utxo.Index}] {
+			candidates = append(candidates, utxo)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Amount != candidates[j].Amount {
+			return candidates[i].Amount > candidates[j].Amount
+		}
+		return string(candidates[i].TxHash[:]) < string(candidates[j].TxHash[:])
+	})
+
+	var inputs []TxInput
+	var totalIn uint64
+	for _, utxo := range candidates {
+		if maxInputs > 0 && uint64(len(inputs)) >= maxInputs {
+			break
+		}
+		inputs = append(inputs, TxInput{PreviousTxHash: utxo.TxHash, Index: utxo.Index})
+		totalIn += utxo.Amount
+
+		withChange := append(append([]TxOutput{}, outputs...), TxOutput{Address: changeAddress, Amount: 1})
+		fee := bc.fundingFee(from, inputs, withChange, opts)
+		if totalIn > totalOut+fee {
+			withChange[len(withChange)-1].Amount = totalIn - totalOut - fee
+			return newUnsignedTransaction(from, inputs, withChange, fee, opts.Data), nil
+		}
+		if fee := bc.fundingFee(from, inputs, outputs, opts); totalIn >= totalOut+fee {
+			return newUnsignedTransaction(from, inputs, outputs, totalIn-totalOut, opts.Data), nil
+		}
+	}
+
+	var available uint64
+	for _, utxo := range candidates {
+		available += utxo.Amount
+	}
+	if maxInputs > 0 && uint64(len(candidates)) > maxInputs {
+		return nil, fmt.Errorf("insufficient funds within %d inputs: need more than %d, have %d in total", maxInputs, totalOut, available)
+	}
+	return nil, fmt.Errorf("insufficient spendable balance: need more than %d, have %d", totalOut, available)
+}
+
+// fundingFee returns the fixed or estimated fee for a transaction with the given shape
+func (bc *BlockchainV2) fundingFee(from Address, inputs []TxInput, outputs []TxOutput, opts FundOptions) uint64 {
+	if opts.Fee > 0 {
+		return opts.Fee
+	}
+	return bc.genesis.EstimateFee(newUnsignedTransaction(from, inputs, outputs, 0, opts.Data), opts.FeeRate)
+}
+
+// sumOutputs adds up output amounts, rejecting empty lists, zero amounts and overflows
+func sumOutputs(outputs []TxOutput) (uint64, error) {
+	if len(outputs) == 0 {
+		return 0, fmt.Errorf("at least one output is required")
+	}
+	var total uint64
+	for i, output := range outputs {
+		if output.Amount == 0 {
+			return 0, fmt.Errorf("output %d has zero amount", i)
+		}
+		if total+output.Amount < total {
+			return 0, fmt.Errorf("output amounts overflow")
+		}
+		total += output.Amount
+	}
+	return total, nil
+}
+
+// newUnsignedTransaction assembles a transaction from the sender's point of view:
+// To and Amount describe the first output that does not return funds to the sender
+func newUnsignedTransaction(from Address, inputs []TxInput, outputs []TxOutput, fee uint64, data []byte) *Transaction {
+	tx := &Transaction{
+		From:      from,
+		To:        outputs[0].Address,
+		Amount:    outputs[0].Amount,
+		Fee:       fee,
+		Data:      data,
+		Timestamp: time.Now().UTC(),
+		Inputs:    inputs,
+		Outputs:   outputs,
+	}
+	for _, output := range outputs {
+		if output.Address != from {
+			tx.To = output.Address
+			tx.Amount = output.Amount
+			break
+		}
+	}
+	tx.Hash = tx.TxID()
+	return tx
+}
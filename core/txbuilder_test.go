@@ -0,0 +1,111 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"strings"
+	"testing"
+)
+
+// TestFundTransaction tests coin selection, change, fee estimation and mempool awareness
+func TestFundTransaction(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	owner := PubKeyAddress(pub)
+	recipient := Address{9}
+
+	bc := NewBlockchainV2(&GenesisConfig{
+		BlockTimeTarget:    15,
+		InitialBlockReward: 5.0,
+		Difficulty:         DifficultyConfig{Window: 120, InitialDifficulty: 1},
+		NetworkFee:         NetworkFeeConfig{BaseTxFee: 0.001},
+	}, nil)
+	block := bc.CreateNewBlockV2(owner, nil)
+	if err := bc.AddBlockV2(block); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+	reward := block.Txs[0].Outputs[0].Amount
+
+	tx, err := bc.FundTransaction(owner, []TxOutput{{Address: recipient, Amount: 1000}}, FundOptions{FeeRate: 1})
+	if err != nil {
+		t.Fatalf("Failed to fund transaction: %v", err)
+	}
+	if len(tx.Inputs) != 1 || len(tx.Outputs) != 2 || tx.Outputs[1].Address != owner {
+		t.Fatalf("Expected one input and a change output, got %+v", tx)
+	}
+	if tx.Fee != 1000 {
+		t.Errorf("Expected the base fee to apply to a small transaction, got %d", tx.Fee)
+	}
+	if tx.Outputs[0].Amount+tx.Outputs[1].Amount+tx.Fee != reward {
+		t.Error("Expected outputs plus fee to spend the whole input")
+	}
+	if tx.To != recipient || tx.Amount != 1000 {
+		t.Errorf("Expected the transaction to describe the payment, got %x and %d", tx.To, tx.Amount)
+	}
+
+	// The estimate covers the signed size
+	if fee := bc.GetGenesis().EstimateFee(tx, 100); fee != uint64(tx.SignedSize())*100 {
+		t.Errorf("Expected fee rate to scale with the signed size, got %d", fee)
+	}
+	signInputs(tx, priv)
+	raw, _ := tx.MarshalBinary()
+	if len(raw) != tx.SignedSize() {
+		t.Errorf("Expected signed size %d to match the encoding, got %d", tx.SignedSize(), len(raw))
+	}
+	if err := bc.AcceptRawTransaction(tx); err != nil {
+		t.Fatalf("Expected funded transaction to be accepted once signed: %v", err)
+	}
+
+	// The only output is now spent by the mempool
+	if _, err := bc.FundTransaction(owner, []TxOutput{{Address: recipient, Amount: 1000}}, FundOptions{}); err == nil || !strings.Contains(err.Error(), "insufficient") {
+		t.Errorf("Expected outputs spent in the mempool to be skipped, got %v", err)
+	}
+
+	bc.GetMempool().Clear()
+	noChangeFee := bc.GetGenesis().EstimateFee(&Transaction{Inputs: tx.Inputs, Outputs: tx.Outputs[:1]}, 100)
+	tx, err = bc.FundTransaction(owner, []TxOutput{{Address: recipient, Amount: reward - noChangeFee - 1}}, FundOptions{FeeRate: 100})
+	if err != nil {
+		t.Fatalf("Failed to fund transaction: %v", err)
+	}
+	if len(tx.Outputs) != 1 || tx.Fee != noChangeFee+1 {
+		t.Errorf("Expected change too small for an output to go to the fee, got %d outputs and fee %d", len(tx.Outputs), tx.Fee)
+	}
+
+	tx, err = bc.FundTransaction(owner, []TxOutput{{Address: recipient, Amount: reward - 10}}, FundOptions{Fee: 10})
+	if err != nil || tx.Fee != 10 || len(tx.Outputs) != 1 {
+		t.Errorf("Expected fixed fee to be used as given, got %+v, %v", tx, err)
+	}
+	if _, err := bc.FundTransaction(owner, []TxOutput{{Address: recipient, Amount: reward}}, FundOptions{Fee: 10}); err == nil {
+		t.Error("Expected overspend to be rejected")
+	}
+}
+
+// TestCreateRawTransaction tests building a transaction from explicit inputs
+func TestCreateRawTransaction(t *testing.T) {
+	owner := Address{1}
+	bc := NewBlockchainV2(&GenesisConfig{
+		BlockTimeTarget:    15,
+		InitialBlockReward: 5.0,
+		Difficulty:         DifficultyConfig{Window: 120, InitialDifficulty: 1},
+	}, nil)
+	block := bc.CreateNewBlockV2(owner, nil)
+	if err := bc.AddBlockV2(block); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+	reward := block.Txs[0].Outputs[0].Amount
+	inputs := []TxInput{{PreviousTxHash: block.Txs[0].Hash, Index: 0}}
+
+	tx, err := bc.CreateRawTransaction(inputs, []TxOutput{{Address: Address{9}, Amount: reward - 50}}, []byte("memo"))
+	if err != nil {
+		t.Fatalf("Failed to create transaction: %v", err)
+	}
+	if tx.Fee != 50 || tx.From != owner || string(tx.Data) != "memo" {
+		t.Errorf("Expected fee from the input surplus and sender from the input, got %+v", tx)
+	}
+
+	if _, err := bc.CreateRawTransaction(inputs, []TxOutput{{Address: Address{9}, Amount: reward + 1}}, nil); err == nil {
+		t.Error("Expected outputs exceeding inputs to be rejected")
+	}
+	missing := []TxInput{{PreviousTxHash: block.Txs[0].Hash, Index: 3}}
+	if _, err := bc.CreateRawTransaction(missing, []TxOutput{{Address: Address{9}, Amount: 1}}, nil); err == nil {
+		t.Error("Expected unknown input to be rejected")
+	}
+}
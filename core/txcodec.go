@@ -0,0 +1,217 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TxEncodingVersion is the version byte leading every binary encoded transaction
+const TxEncodingVersion = 1
+
+// errTxTruncated is returned when a binary transaction ends before all its fields
+var errTxTruncated = errors.New("transaction data truncated")
+
+// MarshalBinary encodes a transaction in the raw format accepted by sendRawTransaction.
+// All integers are big endian; variable length fields are prefixed with a uvarint length.
+// The hash is not encoded, it is the TxID derived from the contents when decoding.
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	return tx.appendBinary(make([]byte, 0, 256), true), nil
+}
+
+// appendBinary appends the encoding of the transaction, leaving out signatures and
+// public keys when withWitness is false
+func (tx *Transaction) appendBinary(data []byte, withWitness bool) []byte {
+	data = append(data, TxEncodingVersion)
+	data = append(data, tx.From[:]...)
+	data = append(data, tx.To[:]...)
+	data = binary.BigEndian.AppendUint64(data, tx.Amount)
+	data = binary.BigEndian.AppendUint64(data, tx.Nonce)
+	data = binary.BigEndian.AppendUint64(data, tx.Fee)
+	data = binary.BigEndian.AppendUint64(data, tx.GasUsed)
+	data = binary.BigEndian.AppendUint64(data, tx.GasPrice)
+	data = binary.BigEndian.AppendUint64(data, uint64(tx.Timestamp.UnixNano()))
+	data = appendBytes(data, tx.Data)
+
+	data = binary.AppendUvarint(data, uint64(len(tx.Inputs)))
+	for _, input := range tx.Inputs {
+		data = append(data, input.PreviousTxHash[:]...)
+		data = binary.BigEndian.AppendUint32(data, input.Index)
+		if withWitness {
+			data = appendBytes(data, input.PublicKey)
+			data = appendBytes(data, input.Signature)
+		}
+	}
+
+	data = binary.AppendUvarint(data, uint64(len(tx.Outputs)))
+	for _, output := range tx.Outputs {
+		data = append(data, output.Address[:]...)
+		data = binary.BigEndian.AppendUint64(data, output.Amount)
+	}
+
+	if withWitness {
+		data = appendBytes(data, tx.Signature)
+	}
+	return data
+}
+
+// appendBytes appends a uvarint length prefixed byte string
+func appendBytes(data []byte, field []byte) []byte {
+	data = binary.AppendUvarint(data, uint64(len(field)))
+	return append(data, field...)
+}
+
+// UnmarshalBinary decodes a transaction produced by MarshalBinary and sets its hash to its TxID
+func (tx *Transaction) UnmarshalBinary(data []byte) error {
+	r := &txReader{data: data}
+	if version := r.byte(); r.err == nil && version != TxEncodingVersion {
+		return fmt.Errorf("unsupported transaction version %d", version)
+	}
+
+	var decoded Transaction
+	r.read(decoded.From[:])
+	r.read(decoded.To[:])
+	decoded.Amount = r.uint64()
+	decoded.Nonce = r.uint64()
+	decoded.Fee = r.uint64()
+	decoded.GasUsed = r.uint64()
+	decoded.GasPrice = r.uint64()
+	decoded.Timestamp = time.Unix(0, int64(r.uint64())).UTC()
+	decoded.Data = r.bytes()
+
+	// Every input takes at least 38 bytes and every output 28, which bounds the counts
+	inputs := r.count(32 + 4 + 2)
+	for i := uint64(0); i < inputs && r.err == nil; i++ {
+		var input TxInput
+		r.read(input.PreviousTxHash[:])
+		input.Index = r.uint32()
+		input.PublicKey = r.bytes()
+		input.Signature = r.bytes()
+		decoded.Inputs = append(decoded.Inputs, input)
+	}
+
+	outputs := r.count(20 + 8)
+	for i := uint64(0); i < outputs && r.err == nil; i++ {
+		var output TxOutput
+		r.read(output.Address[:])
+		output.Amount = r.uint64()
+		decoded.Outputs = append(decoded.Outputs, output)
+	}
+
+	decoded.Signature = r.bytes()
+	if r.err != nil {
+		return r.err
+	}
+	if len(r.data) > 0 {
+		return fmt.Errorf("%d trailing bytes after transaction", len(r.data))
+	}
+
+	decoded.Hash = decoded.TxID()
+	*tx = decoded
+	return nil
+}
+
+// txReader reads transaction fields, remembering the first error
+type txReader struct {
+	data []byte
+	err  error
+}
+
+func (r *txReader) next(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if uint64(len(r.data)) < n {
+		r.err = errTxTruncated
+		return nil
+	}
+	field := r.data[:n]
+	r.data = r.data[n:]
+	return field
+}
+
+func (r *txReader) read(dst []byte) {
+	copy(dst, r.next(uint64(len(dst))))
+}
+
+func (r *txReader) byte() byte {
+	if field := r.next(1); field != nil {
+		return field[0]
+	}
+	return 0
+}
+
+func (r *txReader) uint32() uint32 {
+	if field := r.next(4); field != nil {
+		return binary.BigEndian.Uint32(field)
+	}
+	return 0
+}
+
+func (r *txReader) uint64() uint64 {
+	if field := r.next(8); field != nil {
+		return binary.BigEndian.Uint64(field)
+	}
+	return 0
+}
+
+func (r *txReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	value, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = errTxTruncated
+		return 0
+	}
+	r.data = r.data[n:]
+	return value
+}
+
+// count reads an element count, rejecting counts the remaining data cannot hold
+func (r *txReader) count(minSize uint64) uint64 {
+	n := r.uvarint()
+	if r.err == nil && n > uint64(len(r.data))/minSize {
+		r.err = errTxTruncated
+		return 0
+	}
+	return n
+}
+
+// bytes reads a length prefixed byte string; empty strings decode as nil
+func (r *txReader) bytes() []byte {
+	n := r.uvarint()
+	if r.err != nil || n == 0 {
+		return nil
+	}
+	field := r.next(n)
+	if field == nil {
+		return nil
+	}
+	return append([]byte(nil), field...)
+}
+
+// TxID returns the hash identifying a transaction: the hash of its encoding without
+// signatures and public keys. It commits to every other field, so two different
+// transactions never share an id, and signing does not change it.
+func (tx *Transaction) TxID() Hash {
+	return Hash(sha256.Sum256(tx.appendBinary(make([]byte, 0, 256), false)))
+}
+
+// SigHash returns the message the owner of input index signs. It commits to the
+// whole transaction except signatures and public keys, and to the input index.
+func (tx *Transaction) SigHash(index int) Hash {
+	data := tx.appendBinary(make([]byte, 0, 256), false)
+	data = binary.BigEndian.AppendUint32(data, uint32(index))
+	return Hash(sha256.Sum256(data))
+}
+
+// PubKeyAddress returns the address owned by an ed25519 public key
+func PubKeyAddress(pub []byte) Address {
+	hash := sha256.Sum256(pub)
+	var address Address
+	copy(address[:], hash[:20])
+	return address
+}
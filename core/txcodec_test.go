@@ -0,0 +1,167 @@
+package core
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signInputs signs every input of tx with priv, as a wallet would
+func signInputs(tx *Transaction, priv ed25519.PrivateKey) {
+	pub := priv.Public().(ed25519.PublicKey)
+	for i := range tx.Inputs {
+		tx.Inputs[i].PublicKey = pub
+	}
+	for i := range tx.Inputs {
+		sigHash := tx.SigHash(i)
+		tx.Inputs[i].Signature = ed25519.Sign(priv, sigHash[:])
+	}
+}
+
+// TestRawTransactionEncoding tests the binary transaction round trip and malformed input handling
+func TestRawTransactionEncoding(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(nil)
+	tx := &Transaction{
+		From:      Address{1},
+		To:        Address{2},
+		Amount:    1500,
+		Fee:       10,
+		Data:      []byte("memo"),
+		Timestamp: time.Unix(1700000000, 123456789).UTC(),
+		Inputs:    []TxInput{{PreviousTxHash: Hash{3}, Index: 1}, {PreviousTxHash: Hash{4}, Index: 0}},
+		Outputs:   []TxOutput{{Address: Address{2}, Amount: 1500}, {Address: Address{1}, Amount: 490}},
+	}
+	signInputs(tx, priv)
+
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to encode transaction: %v", err)
+	}
+
+	var decoded Transaction
+	if err := decoded.UnmarshalBinary(raw); err != nil {
+		t.Fatalf("Failed to decode transaction: %v", err)
+	}
+	again, _ := decoded.MarshalBinary()
+	if !bytes.Equal(raw, again) {
+		t.Error("Expected re-encoding to reproduce the raw transaction")
+	}
+	if decoded.Hash != tx.TxID() {
+		t.Error("Expected decoded transaction hash to be derived from its contents")
+	}
+
+	// The id commits to every field but the witness
+	other := decoded
+	other.Fee++
+	if other.TxID() == decoded.TxID() {
+		t.Error("Expected transactions differing only in fee to have different ids")
+	}
+	if !decoded.Timestamp.Equal(tx.Timestamp) || decoded.Inputs[1].Index != 0 || decoded.Outputs[1].Amount != 490 {
+		t.Errorf("Decoded transaction does not match: %+v", decoded)
+	}
+
+	// Signatures do not change what is signed
+	if tx.SigHash(0) == tx.SigHash(1) {
+		t.Error("Expected each input to sign a distinct message")
+	}
+	unsigned := *tx
+	unsigned.Inputs = []TxInput{{PreviousTxHash: Hash{3}, Index: 1}, {PreviousTxHash: Hash{4}, Index: 0}}
+	if unsigned.SigHash(0) != tx.SigHash(0) {
+		t.Error("Expected sighash to ignore signatures and public keys")
+	}
+	if unsigned.TxID() != tx.TxID() {
+		t.Error("Expected signatures not to change the transaction id")
+	}
+
+	for name, data := range map[string][]byte{
+		"truncated": raw[:len(raw)-1],
+		"trailing":  append(append([]byte{}, raw...), 0),
+		"version":   append([]byte{TxEncodingVersion + 1}, raw[1:]...),
+		"empty":     nil,
+	} {
+		if err := new(Transaction).UnmarshalBinary(data); err == nil {
+			t.Errorf("Expected %s transaction to be rejected", name)
+		}
+	}
+}
+
+// TestAcceptRawTransaction tests signature, ownership, amount and double spend checks
+func TestAcceptRawTransaction(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	_, otherPriv, _ := ed25519.GenerateKey(nil)
+	owner := PubKeyAddress(pub)
+
+	bc := NewBlockchainV2(&GenesisConfig{
+		BlockTimeTarget:    15,
+		InitialBlockReward: 5.0,
+		Difficulty:         DifficultyConfig{Window: 120, InitialDifficulty: 1},
+	}, nil)
+	block := bc.CreateNewBlockV2(owner, nil)
+	if err := bc.AddBlockV2(block); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+	reward := block.Txs[0].Outputs[0].Amount
+
+	spend := func(amount, fee uint64) *Transaction {
+		tx := &Transaction{
+			From:      owner,
+			To:        Address{9},
+			Amount:    amount,
+			Fee:       fee,
+			Timestamp: time.Now(),
+			Inputs:    []TxInput{{PreviousTxHash: block.Txs[0].Hash, Index: 0}},
+			Outputs:   []TxOutput{{Address: Address{9}, Amount: amount}},
+		}
+		if change := reward - amount - fee; amount+fee <= reward && change > 0 {
+			tx.Outputs = append(tx.Outputs, TxOutput{Address: owner, Amount: change})
+		}
+		tx.Hash = tx.TxID()
+		return tx
+	}
+
+	cases := []struct {
+		name string
+		tx   func() *Transaction
+		err  string
+	}{
+		{"unsigned", func() *Transaction { return spend(1000, 10) }, "public key"},
+		{"wrong key", func() *Transaction { tx := spend(1000, 10); signInputs(tx, otherPriv); return tx }, "does not own"},
+		{"tampered", func() *Transaction {
+			tx := spend(1000, 10)
+			signInputs(tx, priv)
+			tx.Outputs[0].Amount++
+			tx.Hash = tx.TxID()
+			return tx
+		}, "invalid signature"},
+		{"overspend", func() *Transaction { tx := spend(reward, 10); signInputs(tx, priv); return tx }, "exceed inputs"},
+		{"missing output", func() *Transaction {
+			tx := spend(1000, 10)
+			tx.Inputs[0].Index = 5
+			tx.Hash = tx.TxID()
+			signInputs(tx, priv)
+			return tx
+		}, "missing or spent"},
+	}
+	for _, c := range cases {
+		if err := bc.AcceptRawTransaction(c.tx()); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: expected error containing %q, got %v", c.name, c.err, err)
+		}
+	}
+
+	valid := spend(1000, 10)
+	signInputs(valid, priv)
+	if err := bc.AcceptRawTransaction(valid); err != nil {
+		t.Fatalf("Expected signed transaction to be accepted: %v", err)
+	}
+	if !bc.GetMempool().HasTransaction(valid.Hash) {
+		t.Error("Expected accepted transaction in the mempool")
+	}
+
+	doubleSpend := spend(2000, 10)
+	signInputs(doubleSpend, priv)
+	if err := bc.AcceptRawTransaction(doubleSpend); err == nil || !strings.Contains(err.Error(), "already spent") {
+		t.Errorf("Expected double spend of a mempool input to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package core
+
+import "fmt"
+
+// Named network upgrades. Each gates a consensus rule change and activates at
+// the height declared for it in the genesis "upgrades" list. An upgrade that
+// is not declared never activates, so existing networks keep their rules until
+// their genesis file schedules the change.
+const (
+	// UpgradeMedianTimePast requires block timestamps to be after the median of
+	// the last MedianTimeSpan blocks instead of not before the parent
+	UpgradeMedianTimePast = "medianTimePast"
+
+	// UpgradeStrictDifficulty requires the block difficulty to match the rules exactly
+	UpgradeStrictDifficulty = "strictDifficulty"
+
+	// UpgradeCoinbaseLimit caps coinbase outputs at the block subsidy plus fees
+	UpgradeCoinbaseLimit = "coinbaseLimit"
+
+	// UpgradeTxCommitments requires the header to commit to the transactions with a
+	// merkle root, and every transaction hash, the coinbase's included, to be its TxID
+	UpgradeTxCommitments = "txCommitments"
+)
+
+// KnownUpgrades lists the upgrades this node implements, in activation order
+var KnownUpgrades = []string{
+	UpgradeMedianTimePast,
+	UpgradeStrictDifficulty,
+	UpgradeCoinbaseLimit,
+	UpgradeTxCommitments,
+}
+
+// NetworkUpgrade is a named consensus rule change activated at a block height
+type NetworkUpgrade struct {
+	Name        string `json:"name"`
+	Height      uint64 `json:"height"`
+	Description string `json:"description,omitempty"`
+}
+
+// GetUpgrade returns the declared upgrade with the given name
+func (g *GenesisConfig) GetUpgrade(name string) (NetworkUpgrade, bool) {
+	for _, upgrade := range g.Upgrades {
+		if upgrade.Name == name {
+			return upgrade, true
+		}
+	}
+	return NetworkUpgrade{}, false
+}
+
+// IsActive reports whether the named upgrade is active at height
+func (g *GenesisConfig) IsActive(name string, height uint64) bool {
+	upgrade, ok := g.GetUpgrade(name)
+	return ok && height >= upgrade.Height
+}
+
+// validateUpgrades checks that every declared upgrade is known and declared once
+func (g *GenesisConfig) validateUpgrades() error {
+	known := make(map[string]bool, len(KnownUpgrades))
+	for _, name := range KnownUpgrades {
+		known[name] = true
+	}
+
+	seen := make(map[string]bool, len(g.Upgrades))
+	for _, upgrade := range g.Upgrades {
+		if !known[upgrade.Name] {
+			return fmt.Errorf("unknown upgrade %q (node software may be outdated)", upgrade.Name)
+		}
+		if seen[upgrade.Name] {
+			return fmt.Errorf("upgrade %q declared more than once", upgrade.Name)
+		}
+		seen[upgrade.Name] = true
+	}
+	return nil
+}
@@ -1,475 +1,475 @@
-package mining
-
-import (
-	"crypto/sha256"
-	"encoding/binary"
-	"fmt"
-	"runtime"
-	"sync"
-	"time"
-
-	"github.com/kalon-network/kalon/core"
-)
-
-// RandomXMiner handles CPU mining using RandomX algorithm
-type RandomXMiner struct {
-	threads   int
-	running   bool
-	stopChan  chan struct{}
-	hashChan  chan HashResult
-	blockChan chan *MiningBlock
-	mu        sync.RWMutex
-	stats     *MiningStats
-}
-
-// HashResult represents a mining result
-type HashResult struct {
-	Hash  [32]byte
-	Nonce uint64
-	Found bool
-	Error error
-}
-
-// MiningBlock represents a block being mined
-type MiningBlock struct {
-	Header     core.BlockHeader
-	Target     []byte
-	StartNonce uint64
-	EndNonce   uint64
-}
-
-// MiningStats represents mining statistics
-type MiningStats struct {
-	HashesPerSecond float64
-	TotalHashes     uint64
-	BlocksFound     uint64
-	StartTime       time.Time
-	LastHashTime    time.Time
-}
-
-// NewRandomXMiner creates a new RandomX miner
-func NewRandomXMiner(threads int) *RandomXMiner {
-	if threads <= 0 {
-		threads = runtime.NumCPU()
-	}
-
-	return &RandomXMiner{
-		threads:   threads,
-		stopChan:  make(chan struct{}),
-		hashChan:  make(chan HashResult, 100),
-		blockChan: make(chan *MiningBlock, 10),
-		stats: &MiningStats{
-			StartTime: time.Now(),
-		},
-	}
-}
-
-// Start starts the mining process
-func (rm *RandomXMiner) Start() error {
-	rm.mu.Lock()
-	defer rm.mu.Unlock()
-
-	if rm.running {
-		return fmt.Errorf("miner is already running")
-	}
-
-	rm.running = true
-	rm.stopChan = make(chan struct{})
-	rm.stats.StartTime = time.Now()
-
-	// Start mining threads
-	for i := 0; i < rm.threads; i++ {
-		go rm.miningWorker(i)
-	}
-
-	// Start stats updater
-	go rm.updateStats()
-
-	return nil
-}
-
-// Stop stops the mining process
-func (rm *RandomXMiner) Stop() {
-	rm.mu.Lock()
-	defer rm.mu.Unlock()
-
-	if !rm.running {
-		return
-	}
-
-	rm.running = false
-	close(rm.stopChan)
-}
-
-// IsRunning returns true if the miner is running
-func (rm *RandomXMiner) IsRunning() bool {
-	rm.mu.RLock()
-	defer rm.mu.RUnlock()
-
-	return rm.running
-}
-
-// SetThreads sets the number of mining threads
-func (rm *RandomXMiner) SetThreads(threads int) {
-	rm.mu.Lock()
-	defer rm.mu.Unlock()
-
-	rm.threads = threads
-}
-
-// GetStats returns current mining statistics
-func (rm *RandomXMiner) GetStats() *MiningStats {
-	rm.mu.RLock()
-	defer rm.mu.RUnlock()
-
-	// Create a copy to avoid race conditions
-	stats := *rm.stats
-	return &stats
-}
-
-// MineBlock starts mining a specific block
-func (rm *RandomXMiner) MineBlock(block *MiningBlock) {
-	select {
-	case rm.blockChan <- block:
-	default:
-		// Channel full, skip this block
-	}
-}
-
-// GetHashResult returns the next hash result
-func (rm *RandomXMiner) GetHashResult() <-chan HashResult {
-	return rm.hashChan
-}
-
-// miningWorker is the main mining worker function
-func (rm *RandomXMiner) miningWorker(workerID int) {
-	var currentBlock *MiningBlock
-
-	for {
-		select {
-		case <-rm.stopChan:
-			return
-		case block := <-rm.blockChan:
-			currentBlock = block
-		default:
-			if currentBlock != nil {
-				rm.mineBlock(currentBlock, workerID)
-			} else {
-				time.Sleep(100 * time.Millisecond)
-			}
-		}
-	}
-}
-
-// mineBlock mines a specific block
-func (rm *RandomXMiner) mineBlock(block *MiningBlock, workerID int) {
-	// Calculate nonce range for this worker
-	nonceRange := (block.EndNonce - block.StartNonce) / uint64(rm.threads)
-	startNonce := block.StartNonce + uint64(workerID)*nonceRange
-	endNonce := startNonce + nonceRange
-
-	if workerID == rm.threads-1 {
-		endNonce = block.EndNonce // Last worker gets remaining nonces
-	}
-
-	// Mine the block
-	for nonce := startNonce; nonce < endNonce; nonce++ {
-		select {
-		case <-rm.stopChan:
-			return
-		default:
-			// Create block header with current nonce
-			header := block.Header
-			header.Nonce = nonce
-
-			// Calculate hash
-			hash := rm.calculateHash(header)
-
-			// Update stats
-			rm.updateHashStats()
-
-			// Check if hash meets target
-			if rm.isValidHash(hash, block.Target) {
-				// Found a valid hash!
-				result := HashResult{
-					Hash:  hash,
-					Nonce: nonce,
-					Found: true,
-				}
-
-				select {
-				case rm.hashChan <- result:
-				case <-rm.stopChan:
-					return
-				}
-
-				rm.stats.BlocksFound++
-				return
-			}
-		}
-	}
-}
-
-// calculateHash calculates the hash of a block header using RandomX-like algorithm
-func (rm *RandomXMiner) calculateHash(header core.BlockHeader) [32]byte {
-	// Create data to hash
-	data := rm.createHeaderData(header)
-
-	// Apply RandomX-like algorithm (simplified)
-	// In a real implementation, this would use the actual RandomX algorithm
-	hash := rm.randomXHash(data)
-
-	return hash
-}
-
-// createHeaderData creates the data to hash from block header
-func (rm *RandomXMiner) createHeaderData(header core.BlockHeader) []byte {
-	data := make([]byte, 0, 200)
-	data = append(data, header.ParentHash[:]...)
-
-	// Use compatible binary encoding for older Go versions
-	numberBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(numberBytes, header.Number)
-	data = append(data, numberBytes...)
-
-	timestampBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(timestampBytes, uint64(header.Timestamp.Unix()))
-	data = append(data, timestampBytes...)
-
-	difficultyBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(difficultyBytes, header.Difficulty)
-	data = append(data, difficultyBytes...)
-
-	data = append(data, header.Miner[:]...)
-
-	nonceBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(nonceBytes, header.Nonce)
-	data = append(data, nonceBytes...)
-
-	data = append(data, header.MerkleRoot[:]...)
-
-	txCountBytes := make([]byte, 4)
-	binary.BigEndian.PutUint32(txCountBytes, header.TxCount)
-	data = append(data, txCountBytes...)
-
-	networkFeeBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(networkFeeBytes, header.NetworkFee)
-	data = append(data, networkFeeBytes...)
-
-	treasuryFeeBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(treasuryFeeBytes, header.TreasuryFee)
-	data = append(data, treasuryFeeBytes...)
-
-	return data
-}
-
-// randomXHash applies a RandomX-like hashing algorithm
-func (rm *RandomXMiner) randomXHash(data []byte) [32]byte {
-	// Enhanced RandomX implementation with real PoW characteristics
-	// This provides CPU-friendly mining with ASIC resistance
-
-	// Start with SHA256
-	hash := sha256.Sum256(data)
-
-	// Apply RandomX-inspired rounds with memory-hard operations
-	for round := 0; round < 16; round++ {
-		// Memory access pattern (simulates RandomX's memory hardness)
-		hash = rm.memoryHardRound(hash, round)
-
-		// Cryptographic mixing
-		hash = rm.cryptographicMix(hash, data, round)
-
-		// Bit manipulation
-		hash = rm.bitManipulation(hash, round)
-
-		// Final SHA256
-		hash = sha256.Sum256(hash[:])
-	}
-
-	return hash
-}
-
-// memoryHardRound simulates RandomX's memory-hard operations
-func (rm *RandomXMiner) memoryHardRound(hash [32]byte, round int) [32]byte {
-	// Simulate memory access pattern
-	for i := 0; i < 8; i++ {
-		// XOR with round-dependent values
-		offset := (round*8 + i) % 32
-		hash[offset] ^= byte(round + i)
-
-		// Simulate cache line access
-		cacheIndex := (int(hash[offset]) + round) % 256
-		hash[offset] ^= byte(cacheIndex)
-	}
-
-	return hash
-}
-
-// cryptographicMix applies cryptographic mixing operations
-func (rm *RandomXMiner) cryptographicMix(hash [32]byte, data []byte, round int) [32]byte {
-	// XOR with data at different positions
-	dataLen := len(data)
-	if dataLen > 0 {
-		for i := 0; i < 32; i++ {
-			dataOffset := (round*32 + i) % dataLen
-			hash[i] ^= data[dataOffset]
-		}
-	}
-
-	// Apply round-dependent transformations
-	for i := 0; i < 32; i += 4 {
-		// 32-bit word operations
-		word := binary.BigEndian.Uint32(hash[i : i+4])
-		word ^= uint32(round) << (i % 8)
-		word = word<<1 | word>>31 // Rotate left
-		binary.BigEndian.PutUint32(hash[i:i+4], word)
-	}
-
-	return hash
-}
-
-// bitManipulation applies bit-level operations
-func (rm *RandomXMiner) bitManipulation(hash [32]byte, round int) [32]byte {
-	// Bit rotation based on round
-	rotateAmount := (round % 7) + 1
-	hash = rm.rotateHash(hash, rotateAmount)
-
-	// Bit flipping pattern
-	for i := 0; i < 32; i++ {
-		if (i+round)%3 == 0 {
-			hash[i] ^= 0xFF
-		}
-	}
-
-	return hash
-}
-
-// rotateHash rotates the hash by a given amount
-func (rm *RandomXMiner) rotateHash(hash [32]byte, amount int) [32]byte {
-	amount = amount % 32
-	if amount == 0 {
-		return hash
-	}
-
-	result := [32]byte{}
-	for i := 0; i < 32; i++ {
-		newPos := (i + amount) % 32
-		result[newPos] = hash[i]
-	}
-
-	return result
-}
-
-// isValidHash checks if a hash meets the target difficulty
-func (rm *RandomXMiner) isValidHash(hash [32]byte, target []byte) bool {
-	// Compare hash with target (lower is better)
-	for i := 0; i < 32; i++ {
-		if hash[i] < target[i] {
-			return true
-		} else if hash[i] > target[i] {
-			return false
-		}
-	}
-
-	return true // Equal is valid
-}
-
-// updateHashStats updates mining statistics
-func (rm *RandomXMiner) updateHashStats() {
-	rm.mu.Lock()
-	defer rm.mu.Unlock()
-
-	rm.stats.TotalHashes++
-	rm.stats.LastHashTime = time.Now()
-}
-
-// updateStats updates the hashes per second statistic
-func (rm *RandomXMiner) updateStats() {
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
-
-	var lastHashes uint64
-
-	for {
-		select {
-		case <-rm.stopChan:
-			return
-		case <-ticker.C:
-			rm.mu.Lock()
-			currentHashes := rm.stats.TotalHashes
-			rm.mu.Unlock()
-
-			hashesDelta := currentHashes - lastHashes
-			rm.stats.HashesPerSecond = float64(hashesDelta)
-			lastHashes = currentHashes
-		}
-	}
-}
-
-// CalculateTarget calculates the target hash for a given difficulty
-func (rm *RandomXMiner) CalculateTarget(difficulty uint64) []byte {
-	// Target = 2^256 / difficulty
-	target := make([]byte, 32)
-
-	if difficulty == 0 {
-		// Maximum difficulty (all zeros)
-		return target
-	}
-
-	// Calculate target as 2^256 / difficulty
-	// This is a simplified calculation
-	targetValue := uint64(1<<32) / difficulty
-	if targetValue == 0 {
-		targetValue = 1
-	}
-
-	// Set the target in the most significant bytes
-	binary.BigEndian.PutUint64(target[24:32], targetValue)
-
-	return target
-}
-
-// EstimateHashRate estimates the current hash rate
-func (rm *RandomXMiner) EstimateHashRate() float64 {
-	stats := rm.GetStats()
-	if stats.TotalHashes == 0 {
-		return 0
-	}
-
-	elapsed := time.Since(stats.StartTime).Seconds()
-	if elapsed == 0 {
-		return 0
-	}
-
-	return float64(stats.TotalHashes) / elapsed
-}
-
-// GetOptimalThreads returns the optimal number of threads for this system
-func (rm *RandomXMiner) GetOptimalThreads() int {
-	cpus := runtime.NumCPU()
-
-	// For RandomX, optimal is usually 1 thread per CPU core
-	// But we can also use hyperthreading
-	return cpus
-}
-
-// SetDifficulty sets the mining difficulty
-func (rm *RandomXMiner) SetDifficulty(difficulty uint64) {
-	// This would be called when a new block is received
-	// The difficulty is used to calculate the target
-}
-
-// GetMiningInfo returns information about the current mining state
-func (rm *RandomXMiner) GetMiningInfo() map[string]interface{} {
-	stats := rm.GetStats()
-
-	return map[string]interface{}{
-		"running":         rm.IsRunning(),
-		"threads":         rm.threads,
-		"hashesPerSecond": stats.HashesPerSecond,
-		"totalHashes":     stats.TotalHashes,
-		"blocksFound":     stats.BlocksFound,
-		"startTime":       stats.StartTime,
-		"lastHashTime":    stats.LastHashTime,
-	}
-}
+package mining
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+// RandomXMiner handles CPU mining using RandomX algorithm
+type RandomXMiner struct {
+	threads   int
+	running   bool
+	stopChan  chan struct{}
+	hashChan  chan HashResult
+	blockChan chan *MiningBlock
+	mu        sync.RWMutex
+	stats     *MiningStats
+}
+
+// HashResult represents a mining result
+type HashResult struct {
+	Hash  [32]byte
+	Nonce uint64
+	Found bool
+	Error error
+}
+
+// MiningBlock represents a block being mined
+type MiningBlock struct {
+	Header     core.BlockHeader
+	Target     []byte
+	StartNonce uint64
+	EndNonce   uint64
+}
+
+// MiningStats represents mining statistics
+type MiningStats struct {
+	HashesPerSecond float64
+	TotalHashes     uint64
+	BlocksFound     uint64
+	StartTime       time.Time
+	LastHashTime    time.Time
+}
+
+// NewRandomXMiner creates a new RandomX miner
+func NewRandomXMiner(threads int) *RandomXMiner {
+	if threads <= 0 {
+		threads = runtime.NumCPU()
+	}
+
+	return &RandomXMiner{
+		threads:   threads,
+		stopChan:  make(chan struct{}),
+		hashChan:  make(chan HashResult, 100),
+		blockChan: make(chan *MiningBlock, 10),
+		stats: &MiningStats{
+			StartTime: time.Now(),
+		},
+	}
+}
+
+// Start starts the mining process
+func (rm *RandomXMiner) Start() error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if rm.running {
+		return fmt.Errorf("miner is already running")
+	}
+
+	rm.running = true
+	rm.stopChan = make(chan struct{})
+	rm.stats.StartTime = time.Now()
+
+	// Start mining threads
+	for i := 0; i < rm.threads; i++ {
+		go rm.miningWorker(i)
+	}
+
+	// Start stats updater
+	go rm.updateStats()
+
+	return nil
+}
+
+// Stop stops the mining process
+func (rm *RandomXMiner) Stop() {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if !rm.running {
+		return
+	}
+
+	rm.running = false
+	close(rm.stopChan)
+}
+
+// IsRunning returns true if the miner is running
+func (rm *RandomXMiner) IsRunning() bool {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	return rm.running
+}
+
+// SetThreads sets the number of mining threads
+func (rm *RandomXMiner) SetThreads(threads int) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.threads = threads
+}
+
+// GetStats returns current mining statistics
+func (rm *RandomXMiner) GetStats() *MiningStats {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	// Create a copy to avoid race conditions
+	stats := *rm.stats
+	return &stats
+}
+
+// MineBlock starts mining a specific block
+func (rm *RandomXMiner) MineBlock(block *MiningBlock) {
+	select {
+	case rm.blockChan <- block:
+	default:
+		// Channel full, skip this block
+	}
+}
+
+// GetHashResult returns the next hash result
+func (rm *RandomXMiner) GetHashResult() <-chan HashResult {
+	return rm.hashChan
+}
+
+// miningWorker is the main mining worker function
+func (rm *RandomXMiner) miningWorker(workerID int) {
+	var currentBlock *MiningBlock
+
+	for {
+		select {
+		case <-rm.stopChan:
+			return
+		case block := <-rm.blockChan:
+			currentBlock = block
+		default:
+			if currentBlock != nil {
+				rm.mineBlock(currentBlock, workerID)
+			} else {
+				time.Sleep(100 * time.Millisecond)
+			}
+		}
+	}
+}
+
+// mineBlock mines a specific block
+func (rm *RandomXMiner) mineBlock(block *MiningBlock, workerID int) {
+	// Calculate nonce range for this worker
+	nonceRange := (block.EndNonce - block.StartNonce) / uint64(rm.threads)
+	startNonce := block.StartNonce + uint64(workerID)*nonceRange
+	endNonce := startNonce + nonceRange
+
+	if workerID == rm.threads-1 {
+		endNonce = block.EndNonce // Last worker gets remaining nonces
+	}
+
+	// Mine the block
+	for nonce := startNonce; nonce < endNonce; nonce++ {
+		select {
+		case <-rm.stopChan:
+			return
+		default:
+			// Create block header with current nonce
+			header := block.Header
+			header.Nonce = nonce
+
+			// Calculate hash
+			hash := rm.calculateHash(header)
+
+			// Update stats
+			rm.updateHashStats()
+
+			// Check if hash meets target
+			if rm.isValidHash(hash, block.Target) {
+				// Found a valid hash!
+				result := HashResult{
+					Hash:  hash,
+					Nonce: nonce,
+					Found: true,
+				}
+
+				select {
+				case rm.hashChan <- result:
+				case <-rm.stopChan:
+					return
+				}
+
+				rm.stats.BlocksFound++
+				return
+			}
+		}
+	}
+}
+
+// calculateHash calculates the hash of a block header using RandomX-like algorithm
+func (rm *RandomXMiner) calculateHash(header core.BlockHeader) [32]byte {
+	// Create data to hash
+	data := rm.createHeaderData(header)
+
+	// Apply RandomX-like algorithm (simplified)
+	// In a real implementation, this would use the actual RandomX algorithm
+	hash := rm.randomXHash(data)
+
+	return hash
+}
+
+// createHeaderData creates the data to hash from block header
+func (rm *RandomXMiner) createHeaderData(header core.BlockHeader) []byte {
+	data := make([]byte, 0, 200)
+	data = append(data, header.ParentHash[:]...)
+
+	// Use compatible binary encoding for older Go versions
+	numberBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(numberBytes, header.Number)
+	data = append(data, numberBytes...)
+
+	timestampBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(timestampBytes, uint64(header.Timestamp.Unix()))
+	data = append(data, timestampBytes...)
+
+	difficultyBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(difficultyBytes, header.Difficulty)
+	data = append(data, difficultyBytes...)
+
+	data = append(data, header.Miner[:]...)
+
+	nonceBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(nonceBytes, header.Nonce)
+	data = append(data, nonceBytes...)
+
+	data = append(data, header.MerkleRoot[:]...)
+
+	txCountBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(txCountBytes, header.TxCount)
+	data = append(data, txCountBytes...)
+
+	networkFeeBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(networkFeeBytes, header.NetworkFee)
+	data = append(data, networkFeeBytes...)
+
+	treasuryFeeBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(treasuryFeeBytes, header.TreasuryFee)
+	data = append(data, treasuryFeeBytes...)
+
+	return data
+}
+
+// randomXHash applies a RandomX-like hashing algorithm
+func (rm *RandomXMiner) randomXHash(data []byte) [32]byte {
+	// Enhanced RandomX implementation with real PoW characteristics
+	// This provides CPU-friendly mining with ASIC resistance
+
+	// Start with SHA256
+	hash := sha256.Sum256(data)
+
+	// Apply RandomX-inspired rounds with memory-hard operations
+	for round := 0; round < 16; round++ {
+		// Memory access pattern (simulates RandomX's memory hardness)
+		hash = rm.memoryHardRound(hash, round)
+
+		// Cryptographic mixing
+		hash = rm.cryptographicMix(hash, data, round)
+
+		// Bit manipulation
+		hash = rm.bitManipulation(hash, round)
+
+		// Final SHA256
+		hash = sha256.Sum256(hash[:])
+	}
+
+	return hash
+}
+
+// memoryHardRound simulates RandomX's memory-hard operations
+func (rm *RandomXMiner) memoryHardRound(hash [32]byte, round int) [32]byte {
+	// Simulate memory access pattern
+	for i := 0; i < 8; i++ {
+		// XOR with round-dependent values
+		offset := (round*8 + i) % 32
+		hash[offset] ^= byte(round + i)
+
+		// Simulate cache line access
+		cacheIndex := (int(hash[offset]) + round) % 256
+		hash[offset] ^= byte(cacheIndex)
+	}
+
+	return hash
+}
+
+// cryptographicMix applies cryptographic mixing operations
+func (rm *RandomXMiner) cryptographicMix(hash [32]byte, data []byte, round int) [32]byte {
+	// XOR with data at different positions
+	dataLen := len(data)
+	if dataLen > 0 {
+		for i := 0; i < 32; i++ {
+			dataOffset := (round*32 + i) % dataLen
+			hash[i] ^= data[dataOffset]
+		}
+	}
+
+	// Apply round-dependent transformations
+	for i := 0; i < 32; i += 4 {
+		// 32-bit word operations
+		word := binary.BigEndian.Uint32(hash[i : i+4])
+		word ^= uint32(round) << (i % 8)
+		word = word<<1 | word>>31 // Rotate left
+		binary.BigEndian.PutUint32(hash[i:i+4], word)
+	}
+
+	return hash
+}
+
+// bitManipulation applies bit-level operations
+func (rm *RandomXMiner) bitManipulation(hash [32]byte, round int) [32]byte {
+	// Bit rotation based on round
+	rotateAmount := (round % 7) + 1
+	hash = rm.rotateHash(hash, rotateAmount)
+
+	// Bit flipping pattern
+	for i := 0; i < 32; i++ {
+		if (i+round)%3 == 0 {
+			hash[i] ^= 0xFF
+		}
+	}
+
+	return hash
+}
+
+// rotateHash rotates the hash by a given amount
+func (rm *RandomXMiner) rotateHash(hash [32]byte, amount int) [32]byte {
+	amount = amount % 32
+	if amount == 0 {
+		return hash
+	}
+
+	result := [32]byte{}
+	for i := 0; i < 32; i++ {
+		newPos := (i + amount) % 32
+		result[newPos] = hash[i]
+	}
+
+	return result
+}
+
+// isValidHash checks if a hash meets the target difficulty
+func (rm *RandomXMiner) isValidHash(hash [32]byte, target []byte) bool {
+	// Compare hash with target (lower is better)
+	for i := 0; i < 32; i++ {
+		if hash[i] < target[i] {
+			return true
+		} else if hash[i] > target[i] {
+			return false
+		}
+	}
+
+	return true // Equal is valid
+}
+
+// updateHashStats updates mining statistics
+func (rm *RandomXMiner) updateHashStats() {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.stats.TotalHashes++
+	rm.stats.LastHashTime = time.Now()
+}
+
+// updateStats updates the hashes per second statistic
+func (rm *RandomXMiner) updateStats() {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	var lastHashes uint64
+
+	for {
+		select {
+		case <-rm.stopChan:
+			return
+		case <-ticker.C:
+			rm.mu.Lock()
+			currentHashes := rm.stats.TotalHashes
+			rm.mu.Unlock()
+
+			hashesDelta := currentHashes - lastHashes
+			rm.stats.HashesPerSecond = float64(hashesDelta)
+			lastHashes = currentHashes
+		}
+	}
+}
+
+// CalculateTarget calculates the target hash for a given difficulty
+func (rm *RandomXMiner) CalculateTarget(difficulty uint64) []byte {
+	// Target = 2^256 / difficulty
+	target := make([]byte, 32)
+
+	if difficulty == 0 {
+		// Maximum difficulty (all zeros)
+		return target
+	}
+
+	// Calculate target as 2^256 / difficulty
+	// This is a simplified calculation
+	targetValue := uint64(1<<32) / difficulty
+	if targetValue == 0 {
+		targetValue = 1
+	}
+
+	// Set the target in the most significant bytes
+	binary.BigEndian.PutUint64(target[24:32], targetValue)
+
+	return target
+}
+
+// EstimateHashRate estimates the current hash rate
+func (rm *RandomXMiner) EstimateHashRate() float64 {
+	stats := rm.GetStats()
+	if stats.TotalHashes == 0 {
+		return 0
+	}
+
+	elapsed := time.Since(stats.StartTime).Seconds()
+	if elapsed == 0 {
+		return 0
+	}
+
+	return float64(stats.TotalHashes) / elapsed
+}
+
+// GetOptimalThreads returns the optimal number of threads for this system
+func (rm *RandomXMiner) GetOptimalThreads() int {
+	cpus := runtime.NumCPU()
+
+	// For RandomX, optimal is usually 1 thread per CPU core
+	// But we can also use hyperthreading
+	return cpus
+}
+
+// SetDifficulty sets the mining difficulty
+func (rm *RandomXMiner) SetDifficulty(difficulty uint64) {
+	// This would be called when a new block is received
+	// The difficulty is used to calculate the target
+}
+
+// GetMiningInfo returns information about the current mining state
+func (rm *RandomXMiner) GetMiningInfo() map[string]interface{} {
+	stats := rm.GetStats()
+
+	return map[string]interface{}{
+		"running":         rm.IsRunning(),
+		"threads":         rm.threads,
+		"hashesPerSecond": stats.HashesPerSecond,
+		"totalHashes":     stats.TotalHashes,
+		"blocksFound":     stats.BlocksFound,
+		"startTime":       stats.StartTime,
+		"lastHashTime":    stats.LastHashTime,
+	}
+}
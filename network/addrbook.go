@@ -0,0 +1,383 @@
+package network
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// newBucketCount and newBucketSize bound addresses we heard about but never connected to
+	newBucketCount = 64
+	newBucketSize  = 64
+
+	// triedBucketCount and triedBucketSize bound addresses we successfully connected to
+	triedBucketCount = 16
+	triedBucketSize  = 64
+
+	// maxAddrPerMessage is the most addresses sent or accepted in one addr message
+	maxAddrPerMessage = 1000
+
+	// addrRetryInterval is the minimum time between connection attempts to one address
+	addrRetryInterval = time.Minute
+
+	// addrMaxFailures is the number of failed attempts after which an address that never worked is dropped
+	addrMaxFailures = 5
+
+	// addrBookFile is the address book file name inside the data directory
+	addrBookFile = "peers.json"
+)
+
+// KnownAddress is a peer address in the address book
+type KnownAddress struct {
+	Addr        string    `json:"addr"`
+	Source      string    `json:"source"` // Peer that told us about the address
+	FirstSeen   time.Time `json:"firstSeen"`
+	LastSeen    time.Time `json:"lastSeen"`
+	LastAttempt time.Time `json:"lastAttempt"`
+	LastSuccess time.Time `json:"lastSuccess"`
+	Attempts    int       `json:"attempts"` // Failed attempts since the last success
+	Tried       bool      `json:"tried"`
+	bucket      int
+}
+
+// AddrBook stores peer addresses in new and tried buckets. Bucket placement
+// is keyed by the address group and a secret, so a single source cannot fill
+// the book with addresses it controls.
+type AddrBook struct {
+	mu        sync.RWMutex
+	path      string
+	key       []byte
+	addrs     map[string]*KnownAddress
+	newCount  [newBucketCount]int
+	triedRefs [triedBucketCount][]string
+}
+
+// addrBookData is the on-disk format of the address book
+type addrBookData struct {
+	Key       string          `json:"key"`
+	Addresses []*KnownAddress `json:"addresses"`
+}
+
+// NewAddrBook creates an address book persisted in dataDir (empty for memory only)
+func NewAddrBook(dataDir string) *AddrBook {
+	book := &AddrBook{
+		addrs: make(map[string]*KnownAddress),
+		key:   make([]byte, 16),
+	}
+	if dataDir != "" {
+		book.path = filepath.Join(dataDir, addrBookFile)
+	}
+	rand.Read(book.key)
+	return book
+}
+
+// Load reads the address book from disk; a missing file leaves it empty
+func (b *AddrBook) Load() error {
+	if b.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(b.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read address book: %v", err)
+	}
+
+	var stored addrBookData
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to parse address book: %v", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if key, err := hex.DecodeString(stored.Key); err == nil && len(key) > 0 {
+		b.key = key
+	}
+	for _, ka := range stored.Addresses {
+		if ka.Tried {
+			bucket := b.triedBucket(ka.Addr)
+			if len(b.triedRefs[bucket]) >= triedBucketSize {
+				ka.Tried = false
+			} else {
+				ka.bucket = bucket
+				b.triedRefs[bucket] = append(b.triedRefs[bucket], ka.Addr)
+				b.addrs[ka.Addr] = ka
+				continue
+			}
+		}
+		bucket := b.newBucket(ka.Addr, ka.Source)
+		if b.newCount[bucket] >= newBucketSize {
+			continue
+		}
+		ka.bucket = bucket
+		b.newCount[bucket]++
+		b.addrs[ka.Addr] = ka
+	}
+
+	log.Printf("Loaded %d peer addresses from %s", len(b.addrs), b.path)
+	return nil
+}
+
+// Save writes the address book to disk
+func (b *AddrBook) Save() error {
+	if b.path == "" {
+		return nil
+	}
+
+	b.mu.RLock()
+	stored := addrBookData{
+		Key:       hex.EncodeToString(b.key),
+		Addresses: make([]*KnownAddress, 0, len(b.addrs)),
+	}
+	for _, ka := range b.addrs {
+		copied := *ka
+		stored.Addresses = append(stored.Addresses, &copied)
+	}
+	b.mu.RUnlock()
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal address book: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated book
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write address book: %v", err)
+	}
+	return os.Rename(tmp, b.path)
+}
+
+// Size returns the number of known addresses
+func (b *AddrBook) Size() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.addrs)
+}
+
+// AddAddress records an address learned from source
+func (b *AddrBook) AddAddress(addr, source string) bool {
+	if !isRoutableAddr(addr) {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if ka, exists := b.addrs[addr]; exists {
+		ka.LastSeen = now
+		return false
+	}
+
+	// Make room by evicting the stalest entry of a full bucket
+	bucket := b.newBucket(addr, source)
+	if b.newCount[bucket] >= newBucketSize {
+		var oldest *KnownAddress
+		for _, ka := range b.addrs {
+			if !ka.Tried && ka.bucket == bucket && (oldest == nil || ka.LastSeen.Before(oldest.LastSeen)) {
+				oldest = ka
+			}
+		}
+		if oldest == nil {
+			return false
+		}
+		delete(b.addrs, oldest.Addr)
+		b.newCount[bucket]--
+	}
+
+	b.addrs[addr] = &KnownAddress{
+		Addr:      addr,
+		Source:    source,
+		FirstSeen: now,
+		LastSeen:  now,
+		bucket:    bucket,
+	}
+	b.newCount[bucket]++
+	return true
+}
+
+// Attempt records a connection attempt to an address
+func (b *AddrBook) Attempt(addr string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ka, exists := b.addrs[addr]; exists {
+		ka.LastAttempt = time.Now()
+	}
+}
+
+// Failed records a failed connection attempt, dropping new addresses that keep failing
+func (b *AddrBook) Failed(addr string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ka, exists := b.addrs[addr]
+	if !exists {
+		return
+	}
+	ka.Attempts++
+	if !ka.Tried && ka.Attempts >= addrMaxFailures {
+		delete(b.addrs, addr)
+		b.newCount[ka.bucket]--
+	}
+}
+
+// Good records a successful connection and moves the address to a tried bucket
+func (b *AddrBook) Good(addr string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ka, exists := b.addrs[addr]
+	if !exists {
+		if !isRoutableAddr(addr) {
+			return
+		}
+		ka = &KnownAddress{Addr: addr, Source: addr, FirstSeen: time.Now(), bucket: -1}
+		b.addrs[addr] = ka
+	}
+
+	now := time.Now()
+	ka.LastSeen = now
+	ka.LastSuccess = now
+	ka.Attempts = 0
+	if ka.Tried {
+		return
+	}
+
+	if ka.bucket >= 0 {
+		b.newCount[ka.bucket]--
+	}
+
+	// A full tried bucket sends its least recently successful address back to new
+	bucket := b.triedBucket(addr)
+	if len(b.triedRefs[bucket]) >= triedBucketSize {
+		oldestIdx := 0
+		for i, ref := range b.triedRefs[bucket] {
+			if b.addrs[ref].LastSuccess.Before(b.addrs[b.triedRefs[bucket][oldestIdx]].LastSuccess) {
+				oldestIdx = i
+			}
+		}
+		evicted := b.addrs[b.triedRefs[bucket][oldestIdx]]
+		b.triedRefs[bucket] = append(b.triedRefs[bucket][:oldestIdx], b.triedRefs[bucket][oldestIdx+1:]...)
+		evicted.Tried = false
+		evicted.bucket = b.newBucket(evicted.Addr, evicted.Source)
+		if b.newCount[evicted.bucket] >= newBucketSize {
+			delete(b.addrs, evicted.Addr)
+		} else {
+			b.newCount[evicted.bucket]++
+		}
+	}
+
+	ka.Tried = true
+	ka.bucket = bucket
+	b.triedRefs[bucket] = append(b.triedRefs[bucket], addr)
+}
+
+// Select picks an address to connect to, or "" if none is eligible.
+// Tried and new addresses are chosen with equal chance when both exist.
+func (b *AddrBook) Select(exclude func(addr string) bool) string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	now := time.Now()
+	var tried, fresh []string
+	for addr, ka := range b.addrs {
+		if now.Sub(ka.LastAttempt) < addrRetryInterval || (exclude != nil && exclude(addr)) {
+			continue
+		}
+		if ka.Tried {
+			tried = append(tried, addr)
+		} else {
+			fresh = append(fresh, addr)
+		}
+	}
+
+	switch {
+	case len(tried) > 0 && (len(fresh) == 0 || rand.Intn(2) == 0):
+		return tried[rand.Intn(len(tried))]
+	case len(fresh) > 0:
+		return fresh[rand.Intn(len(fresh))]
+	}
+	return ""
+}
+
+// GetAddresses returns a random sample of known addresses for an addr message
+func (b *AddrBook) GetAddresses(max int) []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	addrs := make([]string, 0, len(b.addrs))
+	for addr, ka := range b.addrs {
+		// Only share addresses that worked or that nobody has shown to be dead
+		if ka.Tried || ka.Attempts == 0 {
+			addrs = append(addrs, addr)
+		}
+	}
+	rand.Shuffle(len(addrs), func(i, j int) { addrs[i], addrs[j] = addrs[j], addrs[i] })
+	if len(addrs) > max {
+		addrs = addrs[:max]
+	}
+	return addrs
+}
+
+// newBucket returns the new bucket for an address heard from source
+func (b *AddrBook) newBucket(addr, source string) int {
+	return b.bucketIndex(netGroup(source)+"/"+netGroup(addr), newBucketCount)
+}
+
+// triedBucket returns the tried bucket for an address
+func (b *AddrBook) triedBucket(addr string) int {
+	return b.bucketIndex(netGroup(addr)+"/"+addr, triedBucketCount)
+}
+
+// bucketIndex hashes a key with the book secret into one of n buckets
+func (b *AddrBook) bucketIndex(key string, n int) int {
+	h := fnv.New64a()
+	h.Write(b.key)
+	h.Write([]byte(key))
+	return int(h.Sum64() % uint64(n))
+}
+
+// netGroup returns the network group of an address: the /16 for IPv4 and the /32 for IPv6
+func netGroup(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return host
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d", ip4[0], ip4[1])
+	}
+	return ip.Mask(net.CIDRMask(32, 128)).String()
+}
+
+// isRoutableAddr reports whether an address is a usable host:port
+func isRoutableAddr(addr string) bool {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" || port == "" || port == "0" {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package network
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestAddrBookPersistence tests that tried and new addresses survive a restart
+func TestAddrBookPersistence(t *testing.T) {
+	dir := t.TempDir()
+
+	book := NewAddrBook(dir)
+	for i := 1; i <= 10; i++ {
+		book.AddAddress(fmt.Sprintf("10.%d.0.1:17335", i), "192.168.0.1:17335")
+	}
+	book.Good("10.1.0.1:17335")
+	book.Failed("10.2.0.1:17335")
+
+	if book.AddAddress("10.1.0.1:17335", "192.168.0.1:17335") {
+		t.Error("Expected a known address not to be added again")
+	}
+	if book.AddAddress("0.0.0.0:17335", "192.168.0.1:17335") || book.AddAddress("10.3.0.1", "192.168.0.1:17335") {
+		t.Error("Expected unroutable addresses to be rejected")
+	}
+
+	if err := book.Save(); err != nil {
+		t.Fatalf("Failed to save address book: %v", err)
+	}
+
+	loaded := NewAddrBook(dir)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Failed to load address book: %v", err)
+	}
+	if loaded.Size() != 10 {
+		t.Fatalf("Expected 10 addresses after reload, got %d", loaded.Size())
+	}
+
+	tried := loaded.addrs["10.1.0.1:17335"]
+	if !tried.Tried || tried.LastSuccess.IsZero() {
+		t.Errorf("Expected tried address with last success, got %+v", tried)
+	}
+	if failed := loaded.addrs["10.2.0.1:17335"]; failed.Tried || failed.Attempts != 1 {
+		t.Errorf("Expected new address with one failed attempt, got %+v", failed)
+	}
+	if string(loaded.key) != string(book.key) {
+		t.Error("Expected bucket key to be preserved")
+	}
+}
+
+// TestAddrBookDropsFailingAddresses tests that new addresses are forgotten after repeated failures
+func TestAddrBookDropsFailingAddresses(t *testing.T) {
+	book := NewAddrBook("")
+	book.AddAddress("10.0.0.1:17335", "10.0.0.2:17335")
+	book.AddAddress("10.0.0.3:17335", "10.0.0.2:17335")
+	book.Good("10.0.0.3:17335")
+
+	for i := 0; i < addrMaxFailures; i++ {
+		book.Failed("10.0.0.1:17335")
+		book.Failed("10.0.0.3:17335")
+	}
+
+	if book.Size() != 1 {
+		t.Fatalf("Expected only the tried address to remain, have %d", book.Size())
+	}
+	if addr := book.Select(nil); addr != "10.0.0.3:17335" {
+		t.Errorf("Expected tried address to be selected, got %q", addr)
+	}
+}
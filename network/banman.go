@@ -0,0 +1,277 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// BanThreshold is the misbehavior score at which a peer is banned
+	BanThreshold = 100
+
+	// DefaultBanDuration is how long misbehaving peers are banned
+	DefaultBanDuration = 24 * time.Hour
+
+	// banListFile is the ban list file name inside the data directory
+	banListFile = "banlist.json"
+)
+
+// BanEntry is a banned host
+type BanEntry struct {
+	Address   string    `json:"address"`
+	CreatedAt time.Time `json:"createdAt"`
+	Until     time.Time `json:"bannedUntil"`
+	Reason    string    `json:"reason"`
+}
+
+// BanManager keeps time-limited bans by host, persisted in the data directory
+type BanManager struct {
+	mu   sync.RWMutex
+	path string
+	bans map[string]*BanEntry // Key: host without port
+}
+
+// NewBanManager creates a ban manager persisted in dataDir (empty for memory only)
+func NewBanManager(dataDir string) *BanManager {
+	m := &BanManager{
+		bans: make(map[string]*BanEntry),
+	}
+	if dataDir != "" {
+		m.path = filepath.Join(dataDir, banListFile)
+	}
+	return m
+}
+
+// Load reads the ban list from disk, dropping expired bans; a missing file leaves it empty
+func (m *BanManager) Load() error {
+	if m.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(m.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read ban list: %v", err)
+	}
+
+	var entries []*BanEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse ban list: %v", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.Until.After(now) {
+			m.bans[entry.Address] = entry
+		}
+	}
+	return nil
+}
+
+// save writes the ban list to disk; caller holds m.mu
+func (m *BanManager) save() error {
+	if m.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(m.sortedEntries(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal ban list: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write ban list: %v", err)
+	}
+	return os.Rename(tmp, m.path)
+}
+
+// Ban bans a host or host:port address for the given duration
+func (m *BanManager) Ban(address string, duration time.Duration, reason string) (*BanEntry, error) {
+	host, err := banHost(address)
+	if err != nil {
+		return nil, err
+	}
+	if duration <= 0 {
+		return nil, fmt.Errorf("ban duration must be positive")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	entry := &BanEntry{
+		Address:   host,
+		CreatedAt: now,
+		Until:     now.Add(duration),
+		Reason:    reason,
+	}
+	m.bans[host] = entry
+
+	copied := *entry
+	return &copied, m.save()
+}
+
+// Unban lifts the ban on a host, reporting whether it was banned
+func (m *BanManager) Unban(address string) (bool, error) {
+	host, err := banHost(address)
+	if err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.bans[host]; !exists {
+		return false, nil
+	}
+	delete(m.bans, host)
+	return true, m.save()
+}
+
+// Clear lifts all bans
+func (m *BanManager) Clear() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.bans = make(map[string]*BanEntry)
+	return m.save()
+}
+
+// IsBanned reports whether the host of an address is currently banned
+func (m *BanManager) IsBanned(address string) bool {
+	host, err := banHost(address)
+	if err != nil {
+		return false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entry, exists := m.bans[host]
+	return exists && entry.Until.After(time.Now())
+}
+
+// List returns the active bans ordered by host
+func (m *BanManager) List() []BanEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for host, entry := range m.bans {
+		if !entry.Until.After(now) {
+			delete(m.bans, host)
+		}
+	}
+
+	entries := m.sortedEntries()
+	list := make([]BanEntry, len(entries))
+	for i, entry := range entries {
+		list[i] = *entry
+	}
+	return list
+}
+
+// sortedEntries returns the bans ordered by host; caller holds m.mu
+func (m *BanManager) sortedEntries() []*BanEntry {
+	entries := make([]*BanEntry, 0, len(m.bans))
+	for _, entry := range m.bans {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Address < entries[j].Address })
+	return entries
+}
+
+// banHost returns the host bans apply to, accepting either host or host:port
+func banHost(address string) (string, error) {
+	host := address
+	if h, _, err := net.SplitHostPort(address); err == nil {
+		host = h
+	}
+	if host == "" {
+		return "", fmt.Errorf("invalid address %q", address)
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+	return host, nil
+}
+
+// Misbehaving adds to a peer's misbehavior score, banning and disconnecting it at BanThreshold
+func (p *P2P) Misbehaving(peerID string, score int, reason string) {
+	p.peerMutex.RLock()
+	peer, exists := p.peers[peerID]
+	p.peerMutex.RUnlock()
+	if !exists {
+		return
+	}
+
+	peer.mu.Lock()
+	peer.BanScore += score
+	total := peer.BanScore
+	peer.mu.Unlock()
+
+	log.Printf("Peer %s misbehaving (+%d = %d): %s", peerID, score, total, reason)
+	if total < BanThreshold {
+		return
+	}
+
+	if _, err := p.banMan.Ban(peer.Conn.RemoteAddr().String(), DefaultBanDuration, reason); err != nil {
+		log.Printf("Failed to ban peer %s: %v", peerID, err)
+	}
+	log.Printf("Banned peer %s for %s: %s", peerID, DefaultBanDuration, reason)
+	peer.Conn.Close()
+}
+
+// Ban bans a host and disconnects peers connected from it
+func (p *P2P) Ban(address string, duration time.Duration, reason string) (*BanEntry, error) {
+	entry, err := p.banMan.Ban(address, duration, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	p.peerMutex.RLock()
+	for _, peer := range p.peers {
+		if p.banMan.IsBanned(peer.Conn.RemoteAddr().String()) {
+			peer.Conn.Close()
+		}
+	}
+	p.peerMutex.RUnlock()
+
+	return entry, nil
+}
+
+// Unban lifts the ban on a host, reporting whether it was banned
+func (p *P2P) Unban(address string) (bool, error) {
+	return p.banMan.Unban(address)
+}
+
+// ClearBanned lifts all bans
+func (p *P2P) ClearBanned() error {
+	return p.banMan.Clear()
+}
+
+// ListBanned returns the active bans
+func (p *P2P) ListBanned() []BanEntry {
+	return p.banMan.List()
+}
+
+// IsBanned reports whether the host of an address is banned
+func (p *P2P) IsBanned(address string) bool {
+	return p.banMan.IsBanned(address)
+}
@@ -0,0 +1,103 @@
+package network
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+// TestMalformedMessagesBanPeer tests that a peer sending malformed payloads is banned and cannot reconnect
+func TestMalformedMessagesBanPeer(t *testing.T) {
+	a := newTestP2P(t, core.NewBlockchainV2(newTestGenesis(), nil))
+
+	conn, err := net.Dial("tcp", a.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// Each malformed payload scores 10, so ten of them reach the threshold
+	frame := rawFrame(a.magic, CmdPing, []byte("not json"))
+	conn.Write(bytes.Repeat(frame, BanThreshold/10))
+
+	if !waitFor(5*time.Second, func() bool { return a.IsBanned("127.0.0.1") }) {
+		t.Fatal("Expected the sender to be banned")
+	}
+	if !waitFor(5*time.Second, func() bool { return a.GetPeerCount() == 0 }) {
+		t.Fatal("Expected the banned peer to be disconnected")
+	}
+
+	// New connections from a banned host are closed immediately
+	b := newTestP2P(t, core.NewBlockchainV2(newTestGenesis(), nil))
+	b.ConnectPeer(a.Addr().String())
+	if waitFor(time.Second, func() bool { return len(b.activePeers()) > 0 }) {
+		t.Error("Expected banned host not to complete a handshake")
+	}
+
+	bans := a.ListBanned()
+	if len(bans) != 1 || bans[0].Address != "127.0.0.1" || !bans[0].Until.After(time.Now()) {
+		t.Errorf("Unexpected ban list: %+v", bans)
+	}
+
+	if removed, err := a.Unban("127.0.0.1:1234"); err != nil || !removed {
+		t.Fatalf("Expected unban by host:port to succeed, got %v %v", removed, err)
+	}
+	b.ConnectPeer(a.Addr().String())
+	if !waitFor(5*time.Second, func() bool { return len(b.activePeers()) == 1 }) {
+		t.Error("Expected connection to succeed after unban")
+	}
+}
+
+// TestBanListPersistence tests that active bans survive a restart and expired ones do not
+func TestBanListPersistence(t *testing.T) {
+	dir := t.TempDir()
+
+	bans := NewBanManager(dir)
+	if _, err := bans.Ban("10.0.0.1:17335", time.Hour, "test"); err != nil {
+		t.Fatalf("Failed to ban: %v", err)
+	}
+	if _, err := bans.Ban("10.0.0.2", time.Millisecond, "short"); err != nil {
+		t.Fatalf("Failed to ban: %v", err)
+	}
+	if _, err := bans.Ban("10.0.0.3", 0, "none"); err == nil {
+		t.Error("Expected a zero ban duration to be rejected")
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	loaded := NewBanManager(dir)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Failed to load ban list: %v", err)
+	}
+	if !loaded.IsBanned("10.0.0.1:9999") {
+		t.Error("Expected ban to apply to every port of the host")
+	}
+	if loaded.IsBanned("10.0.0.2") {
+		t.Error("Expected expired ban to be dropped")
+	}
+	if list := loaded.List(); len(list) != 1 || list[0].Reason != "test" {
+		t.Errorf("Unexpected ban list: %+v", list)
+	}
+}
+
+// TestMessageRateLimit tests the per-peer message token bucket
+func TestMessageRateLimit(t *testing.T) {
+	p := NewP2P(&P2PConfig{MessageRate: 10, MessageBurst: 5})
+	peer := &Peer{ID: "test"}
+
+	for i := 0; i < 5; i++ {
+		if !p.allowMessage(peer) {
+			t.Fatalf("Expected message %d within the burst to be allowed", i+1)
+		}
+	}
+	if p.allowMessage(peer) {
+		t.Error("Expected message above the burst to be dropped")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if !p.allowMessage(peer) {
+		t.Error("Expected the bucket to refill over time")
+	}
+}
@@ -0,0 +1,377 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultTargetOutbound is the number of outbound connections kept open when not configured
+	DefaultTargetOutbound = 8
+
+	// connectInterval is how often missing outbound connections are opened
+	connectInterval = 5 * time.Second
+
+	// seedRetryInterval is the minimum time between seed node connection rounds
+	seedRetryInterval = time.Minute
+
+	// DefaultMaxInbound is the number of inbound connections accepted when not configured
+	DefaultMaxInbound = 32
+
+	// DefaultMaxInboundPerNetgroup is the number of inbound connections accepted from one /16 (IPv6 /32)
+	DefaultMaxInboundPerNetgroup = 4
+
+	// maxAnchors is the number of outbound peers remembered to reconnect to after a restart
+	maxAnchors = 2
+
+	// anchorsFile is the anchor list file name inside the data directory
+	anchorsFile = "anchors.json"
+)
+
+// AddrData is the payload of an addr message
+type AddrData struct {
+	Addresses []string `json:"addresses"`
+}
+
+// maintainOutbound keeps outbound connections at the configured target
+func (p *P2P) maintainOutbound() {
+	ticker := time.NewTicker(connectInterval)
+	defer ticker.Stop()
+
+	// Reconnect to the peers we trusted before the restart first, so an attacker
+	// filling our address book while we were down cannot take all outbound slots
+	for _, address := range p.anchors {
+		log.Printf("Reconnecting to anchor peer %s", address)
+		p.dial(address)
+	}
+
+	var lastSeed time.Time
+	for {
+		lastSeed = p.fillOutbound(lastSeed)
+
+		select {
+		case <-p.stopChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fillOutbound opens connections until the outbound target is met and returns
+// the time seed nodes were last contacted
+func (p *P2P) fillOutbound(lastSeed time.Time) time.Time {
+	// With --connect the node talks to the given peers and nobody else
+	if len(p.config.ConnectOnly) > 0 {
+		for _, address := range p.config.ConnectOnly {
+			p.dial(address)
+		}
+		return lastSeed
+	}
+
+	// Added nodes are always kept connected, on top of the target
+	for _, address := range p.config.AddNodes {
+		p.dial(address)
+	}
+
+	target := p.config.TargetOutbound
+	if target <= 0 {
+		target = DefaultTargetOutbound
+	}
+
+	for need := target - p.outboundCount(); need > 0; need-- {
+		address := p.selectOutbound()
+		if address == "" {
+			break
+		}
+		p.addrBook.Attempt(address)
+		p.dial(address)
+	}
+
+	// Fall back to seed nodes while the address book cannot fill the target
+	if p.outboundCount() < target && time.Since(lastSeed) >= seedRetryInterval {
+		for _, seed := range p.config.SeedNodes {
+			p.dial(seed)
+		}
+		lastSeed = time.Now()
+	}
+
+	return lastSeed
+}
+
+// selectOutbound picks an address book entry to dial, skipping netgroups we are already
+// connected to so no single operator can surround us
+func (p *P2P) selectOutbound() string {
+	groups := p.outboundGroups()
+	return p.addrBook.Select(func(addr string) bool {
+		if p.isConnected(addr) || p.banMan.IsBanned(addr) {
+			return true
+		}
+		return !isLocalAddr(addr) && groups[netGroup(addr)]
+	})
+}
+
+// dial opens an outbound connection unless one to the address already exists
+func (p *P2P) dial(address string) bool {
+	if p.banMan.IsBanned(address) {
+		return false
+	}
+
+	p.peerMutex.Lock()
+	if p.outbound[address] || p.peerAddressConnected(address) {
+		p.peerMutex.Unlock()
+		return false
+	}
+	p.outbound[address] = true
+	p.peerMutex.Unlock()
+
+	go p.connectToPeer(address)
+	return true
+}
+
+// isConnected reports whether we are connected or connecting to an address
+func (p *P2P) isConnected(address string) bool {
+	p.peerMutex.RLock()
+	defer p.peerMutex.RUnlock()
+	return p.outbound[address] || p.peerAddressConnected(address)
+}
+
+// peerAddressConnected reports whether a peer uses the address; caller holds peerMutex
+func (p *P2P) peerAddressConnected(address string) bool {
+	for _, peer := range p.peers {
+		peer.mu.RLock()
+		listenAddr := peer.ListenAddr
+		peer.mu.RUnlock()
+		if peer.Address == address || listenAddr == address {
+			return true
+		}
+	}
+	return false
+}
+
+// outboundCount returns the number of automatic outbound connections, including ones
+// being opened. Added nodes are kept on top of the target and do not count.
+func (p *P2P) outboundCount() int {
+	p.peerMutex.RLock()
+	defer p.peerMutex.RUnlock()
+
+	count := 0
+	for address := range p.outbound {
+		if !p.isManualPeer(address) {
+			count++
+		}
+	}
+	return count
+}
+
+// outboundGroups returns the netgroups of outbound connections to public addresses
+func (p *P2P) outboundGroups() map[string]bool {
+	p.peerMutex.RLock()
+	defer p.peerMutex.RUnlock()
+
+	groups := make(map[string]bool, len(p.outbound))
+	for address := range p.outbound {
+		if !isLocalAddr(address) {
+			groups[netGroup(address)] = true
+		}
+	}
+	return groups
+}
+
+// checkInbound returns an error if an inbound connection from address would exceed
+// the inbound limits; caller holds peerMutex
+func (p *P2P) checkInbound(address string) error {
+	inbound, inGroup := 0, 0
+	group := netGroup(address)
+	for _, peer := range p.peers {
+		if !peer.Inbound {
+			continue
+		}
+		inbound++
+		if netGroup(peer.Address) == group {
+			inGroup++
+		}
+	}
+
+	if inbound >= p.maxInbound() {
+		return fmt.Errorf("inbound slots full (%d)", inbound)
+	}
+	if p.config.MaxPeers > 0 && len(p.peers) >= p.config.MaxPeers {
+		return fmt.Errorf("peer limit reached (%d)", len(p.peers))
+	}
+	// Local networks are commonly one subnet; the netgroup limit guards against public address ranges
+	if !isLocalAddr(address) && inGroup >= p.maxInboundPerNetgroup() {
+		return fmt.Errorf("too many inbound connections from %s", group)
+	}
+	return nil
+}
+
+// maxInbound returns the configured inbound connection limit
+func (p *P2P) maxInbound() int {
+	if p.config.MaxInbound > 0 {
+		return p.config.MaxInbound
+	}
+	return DefaultMaxInbound
+}
+
+// maxInboundPerNetgroup returns the configured inbound limit per netgroup
+func (p *P2P) maxInboundPerNetgroup() int {
+	if p.config.MaxInboundPerNetgroup > 0 {
+		return p.config.MaxInboundPerNetgroup
+	}
+	return DefaultMaxInboundPerNetgroup
+}
+
+// isLocalAddr reports whether an address is loopback, private or link-local
+func isLocalAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast())
+}
+
+// anchorAddresses returns the longest-connected automatic outbound peers
+func (p *P2P) anchorAddresses() []string {
+	var anchors []*Peer
+	for _, peer := range p.activePeers() {
+		if !peer.Inbound && !p.isManualPeer(peer.Address) {
+			anchors = append(anchors, peer)
+		}
+	}
+	sort.Slice(anchors, func(i, j int) bool { return anchors[i].ConnectedAt.Before(anchors[j].ConnectedAt) })
+
+	addresses := make([]string, 0, maxAnchors)
+	for _, peer := range anchors {
+		if len(addresses) == maxAnchors {
+			break
+		}
+		addresses = append(addresses, peer.Address)
+	}
+	return addresses
+}
+
+// saveAnchors writes the anchor peers to the data directory
+func (p *P2P) saveAnchors(addresses []string) error {
+	if p.config.DataDir == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(addresses, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal anchors: %v", err)
+	}
+	if err := os.MkdirAll(p.config.DataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %v", err)
+	}
+	return os.WriteFile(filepath.Join(p.config.DataDir, anchorsFile), data, 0644)
+}
+
+// loadAnchors reads and removes the anchor list, so a crash does not reuse stale anchors
+func (p *P2P) loadAnchors() ([]string, error) {
+	if p.config.DataDir == "" {
+		return nil, nil
+	}
+
+	path := filepath.Join(p.config.DataDir, anchorsFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read anchors: %v", err)
+	}
+	os.Remove(path)
+
+	var addresses []string
+	if err := json.Unmarshal(data, &addresses); err != nil {
+		return nil, fmt.Errorf("failed to parse anchors: %v", err)
+	}
+	if len(addresses) > maxAnchors {
+		addresses = addresses[:maxAnchors]
+	}
+	return addresses, nil
+}
+
+// listenPort returns the port we accept connections on, or 0 if unknown
+func (p *P2P) listenPort() uint16 {
+	addr := p.Addr()
+	if addr == nil {
+		return 0
+	}
+	_, portStr, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return 0
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return 0
+	}
+	return uint16(port)
+}
+
+// recordPeerAddress updates the address book once a handshake completes
+func (p *P2P) recordPeerAddress(peer *Peer) {
+	peer.mu.RLock()
+	inbound := peer.Inbound
+	address := peer.Address
+	listenAddr := peer.ListenAddr
+	peer.mu.RUnlock()
+
+	if !inbound {
+		p.addrBook.Good(address)
+
+		// Learn more of the network from the peers we chose
+		if len(p.config.ConnectOnly) == 0 {
+			if err := p.sendMessage(peer, &GetAddrData{}); err != nil {
+				log.Printf("Failed to request addresses from peer %s: %v", peer.ID, err)
+			}
+		}
+		return
+	}
+
+	// Inbound peers dial from an ephemeral port; remember where they listen instead
+	if listenAddr != "" {
+		p.addrBook.AddAddress(listenAddr, address)
+	}
+}
+
+// handleGetAddrMessage answers with a sample of the address book
+func (p *P2P) handleGetAddrMessage(peer *Peer) error {
+	peer.mu.Lock()
+	answered := peer.addrSent
+	peer.addrSent = true
+	peer.mu.Unlock()
+
+	// One answer per connection keeps a peer from scraping the whole book
+	if answered {
+		return nil
+	}
+
+	return p.sendMessage(peer, &AddrData{Addresses: p.addrBook.GetAddresses(maxAddrPerMessage)})
+}
+
+// handleAddrMessage adds addresses a peer told us about to the address book
+func (p *P2P) handleAddrMessage(peer *Peer, data *AddrData) error {
+	if len(data.Addresses) > maxAddrPerMessage {
+		return fmt.Errorf("addr message with %d addresses exceeds %d", len(data.Addresses), maxAddrPerMessage)
+	}
+
+	added := 0
+	for _, address := range data.Addresses {
+		if p.addrBook.AddAddress(address, peer.Address) {
+			added++
+		}
+	}
+	if added > 0 {
+		log.Printf("Learned %d new addresses from peer %s", added, peer.ID)
+	}
+	return nil
+}
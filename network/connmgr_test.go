@@ -0,0 +1,110 @@
+package network
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+// TestInboundLimits tests the inbound slot and per-netgroup limits
+func TestInboundLimits(t *testing.T) {
+	p := NewP2P(&P2PConfig{MaxInbound: 3, MaxInboundPerNetgroup: 2})
+	for _, address := range []string{"203.0.113.1:40001", "203.0.113.2:40002"} {
+		p.peers[address] = &Peer{ID: address, Address: address, Inbound: true}
+	}
+	p.peers["198.51.100.1:17335"] = &Peer{ID: "198.51.100.1:17335", Address: "198.51.100.1:17335"}
+
+	if err := p.checkInbound("203.0.113.3:40003"); err == nil {
+		t.Error("Expected a third connection from the same /16 to be refused")
+	}
+	if err := p.checkInbound("198.51.100.2:40004"); err != nil {
+		t.Errorf("Expected a connection from another /16 to be accepted: %v", err)
+	}
+
+	// Outbound peers do not take inbound slots, inbound ones do
+	p.peers["192.0.2.1:40005"] = &Peer{ID: "192.0.2.1:40005", Address: "192.0.2.1:40005", Inbound: true}
+	if err := p.checkInbound("198.51.100.2:40004"); err == nil {
+		t.Error("Expected connections to be refused once inbound slots are full")
+	}
+}
+
+// TestInboundSlotsEnforced tests that connections beyond the inbound limit are refused
+func TestInboundSlotsEnforced(t *testing.T) {
+	a := newTestP2PWithConfig(t, core.NewBlockchainV2(newTestGenesis(), nil), func(config *P2PConfig) {
+		config.MaxInbound = 1
+	})
+	b := newTestP2P(t, core.NewBlockchainV2(newTestGenesis(), nil))
+	c := newTestP2P(t, core.NewBlockchainV2(newTestGenesis(), nil))
+
+	b.ConnectPeer(a.Addr().String())
+	if !waitFor(5*time.Second, func() bool { return len(a.activePeers()) == 1 }) {
+		t.Fatal("Expected first inbound connection to be accepted")
+	}
+
+	c.ConnectPeer(a.Addr().String())
+	if waitFor(time.Second, func() bool { return a.GetPeerCount() > 1 || len(c.activePeers()) > 0 }) {
+		t.Error("Expected inbound connection beyond the limit to be refused")
+	}
+}
+
+// TestOutboundNetgroupDiversity tests that outbound peers are picked from distinct netgroups
+func TestOutboundNetgroupDiversity(t *testing.T) {
+	p := NewP2P(&P2PConfig{})
+	p.outbound["203.0.113.1:17335"] = true
+	p.addrBook.AddAddress("203.0.113.5:17335", "192.0.2.1:17335")
+	p.addrBook.AddAddress("198.51.100.7:17335", "192.0.2.1:17335")
+
+	for i := 0; i < 20; i++ {
+		if address := p.selectOutbound(); address != "198.51.100.7:17335" {
+			t.Fatalf("Expected address from an unused netgroup, got %q", address)
+		}
+	}
+
+	p.outbound["198.51.100.9:17335"] = true
+	if address := p.selectOutbound(); address != "" {
+		t.Errorf("Expected no address when every netgroup is in use, got %q", address)
+	}
+}
+
+// TestAddedNodesDoNotCountTowardsOutbound tests that -addnode connections are kept on top of the outbound target
+func TestAddedNodesDoNotCountTowardsOutbound(t *testing.T) {
+	p := NewP2P(&P2PConfig{AddNodes: []string{"203.0.113.1:17335"}})
+	p.outbound["203.0.113.1:17335"] = true
+	p.outbound["198.51.100.1:17335"] = true
+
+	if count := p.outboundCount(); count != 1 {
+		t.Errorf("Expected 1 automatic outbound connection, got %d", count)
+	}
+}
+
+// TestAnchorsReconnect tests that outbound peers are reconnected to after a restart
+func TestAnchorsReconnect(t *testing.T) {
+	dir := t.TempDir()
+	remote := newTestP2P(t, core.NewBlockchainV2(newTestGenesis(), nil))
+	withDataDir := func(config *P2PConfig) { config.DataDir = dir }
+
+	first := newTestP2PWithConfig(t, core.NewBlockchainV2(newTestGenesis(), nil), withDataDir)
+	first.ConnectPeer(remote.Addr().String())
+	if !waitFor(5*time.Second, func() bool { return len(first.activePeers()) == 1 }) {
+		t.Fatal("Expected outbound connection to complete")
+	}
+	first.Stop()
+
+	if _, err := os.Stat(filepath.Join(dir, anchorsFile)); err != nil {
+		t.Fatalf("Expected anchors to be saved: %v", err)
+	}
+	if !waitFor(5*time.Second, func() bool { return remote.GetPeerCount() == 0 }) {
+		t.Fatal("Expected remote to notice the disconnect")
+	}
+
+	second := newTestP2PWithConfig(t, core.NewBlockchainV2(newTestGenesis(), nil), withDataDir)
+	if !waitFor(5*time.Second, func() bool { return len(second.activePeers()) == 1 }) {
+		t.Fatal("Expected the restarted node to reconnect to its anchor")
+	}
+	if _, err := os.Stat(filepath.Join(dir, anchorsFile)); !os.IsNotExist(err) {
+		t.Error("Expected the anchors file to be consumed on start")
+	}
+}
@@ -0,0 +1,170 @@
+package network
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+const (
+	// ProtocolVersion is the P2P protocol version spoken by this node
+	ProtocolVersion = 1
+
+	// MinProtocolVersion is the oldest protocol version accepted from peers
+	MinProtocolVersion = 1
+
+	// DefaultUserAgent identifies this software to peers
+	DefaultUserAgent = "/kalon-node:2.0/"
+
+	// handshakeTimeout is how long a peer has to complete the version handshake
+	handshakeTimeout = 10 * time.Second
+)
+
+// Service bits advertised in the version message
+const (
+	// ServiceNodeNetwork means the node serves the full chain
+	ServiceNodeNetwork uint64 = 1 << 0
+)
+
+// VersionData is the payload of a version message
+type VersionData struct {
+	ProtocolVersion uint32    `json:"protocolVersion"`
+	ChainID         uint64    `json:"chainId"`
+	GenesisHash     core.Hash `json:"genesisHash"`
+	BestHeight      uint64    `json:"bestHeight"`
+	BestHash        core.Hash `json:"bestHash"`
+	Services        uint64    `json:"services"`
+	UserAgent       string    `json:"userAgent"`
+	Nonce           uint64    `json:"nonce"`                // Random per node, detects connections to ourselves
+	ListenPort      uint16    `json:"listenPort,omitempty"` // Port the node accepts connections on
+	Timestamp       int64     `json:"timestamp"`            // Sender clock in Unix seconds
+}
+
+// sendVersion sends our version message to a newly connected peer
+func (p *P2P) sendVersion(peer *Peer) error {
+	data := &VersionData{
+		ProtocolVersion: ProtocolVersion,
+		ChainID:         p.config.ChainID,
+		Services:        p.config.Services,
+		UserAgent:       p.config.UserAgent,
+		Nonce:           p.nonce,
+		ListenPort:      p.listenPort(),
+		Timestamp:       time.Now().Unix(),
+	}
+	if data.UserAgent == "" {
+		data.UserAgent = DefaultUserAgent
+	}
+	if chain := p.getChain(); chain != nil {
+		if genesis := chain.GetBlockByNumber(0); genesis != nil {
+			data.GenesisHash = genesis.Hash
+		}
+		if best := chain.GetBestBlock(); best != nil {
+			data.BestHeight = best.Header.Number
+			data.BestHash = best.Hash
+		}
+	}
+
+	return p.sendMessage(peer, data)
+}
+
+// handleVersionMessage checks that the peer is on our network and records what it told us
+func (p *P2P) handleVersionMessage(peer *Peer, data *VersionData) error {
+	peer.mu.RLock()
+	received := peer.versionReceived
+	peer.mu.RUnlock()
+	if received {
+		return fmt.Errorf("duplicate version message")
+	}
+
+	if data.Nonce == p.nonce {
+		return fmt.Errorf("connected to self")
+	}
+	if data.ProtocolVersion < MinProtocolVersion {
+		return fmt.Errorf("protocol version %d is older than %d", data.ProtocolVersion, MinProtocolVersion)
+	}
+	if data.ChainID != p.config.ChainID {
+		return fmt.Errorf("chain ID mismatch: peer %d, ours %d", data.ChainID, p.config.ChainID)
+	}
+	if chain := p.getChain(); chain != nil {
+		if genesis := chain.GetBlockByNumber(0); genesis != nil && genesis.Hash != data.GenesisHash {
+			return fmt.Errorf("genesis hash mismatch: peer %x, ours %x", data.GenesisHash, genesis.Hash)
+		}
+	}
+
+	offset := time.Unix(data.Timestamp, 0).Sub(time.Now()).Round(time.Second)
+	var listenAddr string
+	if host, _, err := net.SplitHostPort(peer.Conn.RemoteAddr().String()); err == nil && data.ListenPort != 0 {
+		listenAddr = net.JoinHostPort(host, strconv.Itoa(int(data.ListenPort)))
+	}
+
+	peer.mu.Lock()
+	peer.versionReceived = true
+	peer.Version = fmt.Sprintf("%d", data.ProtocolVersion)
+	peer.ProtocolVersion = data.ProtocolVersion
+	peer.Height = data.BestHeight
+	peer.BestHash = data.BestHash
+	peer.Services = data.Services
+	peer.UserAgent = data.UserAgent
+	peer.TimeOffset = offset
+	peer.ListenAddr = listenAddr
+	peer.mu.Unlock()
+
+	// Feed the peer clock into network-adjusted time
+	p.mu.RLock()
+	timeSrc := p.timeSrc
+	p.mu.RUnlock()
+	if timeSrc != nil && data.Timestamp != 0 {
+		timeSrc.AddTimeSample(peer.ID, offset)
+	}
+
+	if err := p.sendMessage(peer, &VerackData{}); err != nil {
+		return err
+	}
+
+	p.checkHandshake(peer)
+	return nil
+}
+
+// handleVerackMessage records that the peer accepted our version
+func (p *P2P) handleVerackMessage(peer *Peer) error {
+	peer.mu.Lock()
+	if peer.verackReceived {
+		peer.mu.Unlock()
+		return fmt.Errorf("duplicate verack message")
+	}
+	peer.verackReceived = true
+	peer.mu.Unlock()
+
+	p.checkHandshake(peer)
+	return nil
+}
+
+// checkHandshake starts using the peer once both sides have accepted each other
+func (p *P2P) checkHandshake(peer *Peer) {
+	if !peer.HandshakeComplete() {
+		return
+	}
+
+	peer.mu.RLock()
+	log.Printf("Handshake complete with peer %s (%s, height %d)", peer.ID, peer.UserAgent, peer.Height)
+	peer.mu.RUnlock()
+
+	p.recordPeerAddress(peer)
+
+	// The first ping gives the peer a round-trip time right away
+	p.sendPing(peer)
+
+	// Ask the new peer for headers on the next sync tick
+	p.sync.wake()
+}
+
+// HandshakeComplete reports whether version and verack have been exchanged in both directions
+func (peer *Peer) HandshakeComplete() bool {
+	peer.mu.RLock()
+	defer peer.mu.RUnlock()
+	return peer.versionReceived && peer.verackReceived
+}
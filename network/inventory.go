@@ -0,0 +1,379 @@
+package network
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+// Inventory item types
+const (
+	InvTypeBlock = "block"
+	InvTypeTx    = "tx"
+)
+
+const (
+	// maxInvPerMessage is the most items in one inv, getdata or notfound message
+	maxInvPerMessage = 1000
+
+	// maxAnnouncedHeaders is the most headers in a new block announcement
+	maxAnnouncedHeaders = 8
+
+	// knownInventorySize bounds the per-peer set of items the peer is known to have
+	knownInventorySize = 4096
+
+	// seenInventorySize bounds the recently seen cache of blocks and transactions
+	seenInventorySize = 16384
+
+	// getDataTimeout is how long a requested item is not asked for again
+	getDataTimeout = 30 * time.Second
+
+	// relayTxExpiry is how long announced transactions are kept to answer getdata
+	relayTxExpiry = 15 * time.Minute
+)
+
+// InvVector identifies a block or transaction by hash
+type InvVector struct {
+	Type string    `json:"type"`
+	Hash core.Hash `json:"hash"`
+}
+
+// InvData is the payload of an inv message announcing items
+type InvData struct {
+	Items []InvVector `json:"items"`
+}
+
+// GetDataData is the payload of a getdata message requesting items
+type GetDataData struct {
+	Items []InvVector `json:"items"`
+}
+
+// NotFoundData is the payload of a notfound message listing requested items the peer lacks
+type NotFoundData struct {
+	Items []InvVector `json:"items"`
+}
+
+// hashCache is a bounded set of hashes that forgets the oldest entries first
+type hashCache struct {
+	mu    sync.Mutex
+	items map[core.Hash]struct{}
+	order []core.Hash
+	next  int
+}
+
+// newHashCache creates a hash set holding at most size entries
+func newHashCache(size int) *hashCache {
+	return &hashCache{
+		items: make(map[core.Hash]struct{}, size),
+		order: make([]core.Hash, 0, size),
+	}
+}
+
+// Add inserts a hash, reporting whether it was not already present
+func (c *hashCache) Add(hash core.Hash) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.items[hash]; exists {
+		return false
+	}
+	if len(c.order) < cap(c.order) {
+		c.order = append(c.order, hash)
+	} else {
+		delete(c.items, c.order[c.next])
+		c.order[c.next] = hash
+		c.next = (c.next + 1) % len(c.order)
+	}
+	c.items[hash] = struct{}{}
+	return true
+}
+
+// Has reports whether a hash is present
+func (c *hashCache) Has(hash core.Hash) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, exists := c.items[hash]
+	return exists
+}
+
+// relayedTx is a transaction kept to answer getdata after announcing it
+type relayedTx struct {
+	tx      *core.Transaction
+	expires time.Time
+}
+
+// inventory tracks what this node has seen, requested and can serve to peers
+type inventory struct {
+	mu        sync.Mutex
+	seen      *hashCache
+	requested map[core.Hash]time.Time
+	relayTxs  map[core.Hash]*relayedTx
+}
+
+// newInventory creates an empty inventory
+func newInventory() *inventory {
+	return &inventory{
+		seen:      newHashCache(seenInventorySize),
+		requested: make(map[core.Hash]time.Time),
+		relayTxs:  make(map[core.Hash]*relayedTx),
+	}
+}
+
+// request marks an item as requested, reporting false if a request is already outstanding
+func (inv *inventory) request(hash core.Hash) bool {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if sent, exists := inv.requested[hash]; exists && time.Since(sent) < getDataTimeout {
+		return false
+	}
+	inv.requested[hash] = time.Now()
+	return true
+}
+
+// received marks an item as seen, reporting false if it was seen before
+func (inv *inventory) received(hash core.Hash) bool {
+	inv.mu.Lock()
+	delete(inv.requested, hash)
+	inv.mu.Unlock()
+
+	return inv.seen.Add(hash)
+}
+
+// delivered clears the request for an item that arrived, reporting false if it was
+// seen before. Items are only marked seen once accepted, by relaying them, so a peer
+// sending junk under an item's hash cannot make us drop the real item.
+func (inv *inventory) delivered(hash core.Hash) bool {
+	inv.mu.Lock()
+	delete(inv.requested, hash)
+	inv.mu.Unlock()
+
+	return !inv.seen.Has(hash)
+}
+
+// addRelayTx keeps a transaction available for getdata
+func (inv *inventory) addRelayTx(tx *core.Transaction) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	inv.relayTxs[tx.Hash] = &relayedTx{tx: tx, expires: time.Now().Add(relayTxExpiry)}
+}
+
+// relayTx returns an announced transaction, or nil if it expired
+func (inv *inventory) relayTx(hash core.Hash) *core.Transaction {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if relayed, exists := inv.relayTxs[hash]; exists && time.Now().Before(relayed.expires) {
+		return relayed.tx
+	}
+	return nil
+}
+
+// expire drops stale requests and relayed transactions
+func (inv *inventory) expire() {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	now := time.Now()
+	for hash, sent := range inv.requested {
+		if now.Sub(sent) >= getDataTimeout {
+			delete(inv.requested, hash)
+		}
+	}
+	for hash, relayed := range inv.relayTxs {
+		if now.After(relayed.expires) {
+			delete(inv.relayTxs, hash)
+		}
+	}
+}
+
+// relayBlock announces a block by its header to peers that do not have it yet
+func (p *P2P) relayBlock(block *core.Block, fromPeer string) error {
+	p.inv.received(block.Hash)
+
+	announcement := &HeadersData{
+		Headers: []HeaderEntry{{Header: block.Header, Hash: block.Hash}},
+	}
+	return p.announce(announcement, block.Hash, fromPeer)
+}
+
+// relayTransaction announces a transaction by hash to peers that do not have it yet
+func (p *P2P) relayTransaction(tx *core.Transaction, fromPeer string) error {
+	p.inv.received(tx.Hash)
+	p.inv.addRelayTx(tx)
+
+	announcement := &InvData{Items: []InvVector{{Type: InvTypeTx, Hash: tx.Hash}}}
+	return p.announce(announcement, tx.Hash, fromPeer)
+}
+
+// announce sends a message about an item to every active peer not known to have it
+func (p *P2P) announce(payload Payload, hash core.Hash, exclude string) error {
+	var errors []error
+	for _, peer := range p.activePeers() {
+		if peer.ID == exclude || !peer.knownInv.Add(hash) {
+			continue
+		}
+		if err := p.sendMessage(peer, payload); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("failed to send to some peers: %v", errors)
+	}
+	return nil
+}
+
+// handleInvMessage requests announced items we have not seen
+func (p *P2P) handleInvMessage(peer *Peer, data *InvData) {
+	if len(data.Items) > maxInvPerMessage {
+		p.Misbehaving(peer.ID, 20, fmt.Sprintf("inv with %d items", len(data.Items)))
+		return
+	}
+
+	chain := p.getChain()
+	var wanted []InvVector
+	for _, item := range data.Items {
+		peer.knownInv.Add(item.Hash)
+		if p.inv.seen.Has(item.Hash) {
+			continue
+		}
+		switch item.Type {
+		case InvTypeBlock:
+			if chain != nil && chain.GetBlockByHash(item.Hash) != nil {
+				continue
+			}
+		case InvTypeTx:
+		default:
+			continue
+		}
+		if p.inv.request(item.Hash) {
+			wanted = append(wanted, item)
+		}
+	}
+
+	p.sendGetData(peer, wanted)
+}
+
+// handleHeaderAnnouncement requests newly announced blocks that extend our chain
+func (p *P2P) handleHeaderAnnouncement(peer *Peer, data *HeadersData) {
+	chain := p.getChain()
+	if chain == nil || len(data.Headers) == 0 {
+		return
+	}
+	if len(data.Headers) > maxAnnouncedHeaders {
+		log.Printf("Ignoring unsolicited headers from peer %s", peer.ID)
+		return
+	}
+
+	var wanted []InvVector
+	behind := false
+	linked := make(map[core.Hash]bool)
+	for _, entry := range data.Headers {
+		block := &core.Block{Header: entry.Header, Hash: entry.Hash}
+		if block.CalculateHash() != entry.Hash || !chain.GetRules().CheckProofOfWork(block) {
+			p.Misbehaving(peer.ID, BanThreshold, fmt.Sprintf("announced header #%d is invalid", entry.Header.Number))
+			return
+		}
+		peer.knownInv.Add(entry.Hash)
+
+		// Only a header building on one we know raises the peer's height
+		parentKnown := linked[entry.Header.ParentHash] || p.linksToKnownHeader(chain, entry.Header.ParentHash)
+		if parentKnown {
+			linked[entry.Hash] = true
+			peer.noteHeight(entry.Header.Number, entry.Hash)
+		}
+
+		if p.inv.seen.Has(entry.Hash) || chain.GetBlockByHash(entry.Hash) != nil {
+			continue
+		}
+
+		// Blocks that do not build on a block we have are fetched by the sync manager
+		if !parentKnown {
+			behind = behind || entry.Header.Number > chain.GetHeight()
+			continue
+		}
+		if p.inv.request(entry.Hash) {
+			wanted = append(wanted, InvVector{Type: InvTypeBlock, Hash: entry.Hash})
+		}
+	}
+
+	p.sendGetData(peer, wanted)
+	if behind {
+		p.sync.syncFrom(peer)
+	}
+}
+
+// linksToKnownHeader reports whether a parent hash is a block we have or a header pending download
+func (p *P2P) linksToKnownHeader(chain Chain, parent core.Hash) bool {
+	return chain.GetBlockByHash(parent) != nil || p.sync.hasHeader(parent)
+}
+
+// sendGetData asks a peer for the given items
+func (p *P2P) sendGetData(peer *Peer, items []InvVector) {
+	if len(items) == 0 {
+		return
+	}
+
+	if err := p.sendMessage(peer, &GetDataData{Items: items}); err != nil {
+		log.Printf("Failed to send getdata to peer %s: %v", peer.ID, err)
+	}
+}
+
+// handleGetDataMessage sends the requested blocks and transactions we have
+func (p *P2P) handleGetDataMessage(peer *Peer, data *GetDataData) {
+	if len(data.Items) > maxInvPerMessage {
+		p.Misbehaving(peer.ID, 20, fmt.Sprintf("getdata with %d items", len(data.Items)))
+		return
+	}
+
+	chain := p.getChain()
+	var notFound []InvVector
+	for _, item := range data.Items {
+		var response Payload
+		switch item.Type {
+		case InvTypeBlock:
+			if chain != nil {
+				if block := chain.GetBlockByHash(item.Hash); block != nil {
+					response = &BlockData{Block: block}
+					// Peers ask for blocks whose parent they have; count it as theirs once sent
+					peer.noteHeight(block.Header.Number, block.Hash)
+				}
+			}
+		case InvTypeTx:
+			if tx := p.inv.relayTx(item.Hash); tx != nil {
+				response = &TransactionData{Tx: tx}
+			}
+		}
+
+		if response == nil {
+			notFound = append(notFound, item)
+			continue
+		}
+		peer.knownInv.Add(item.Hash)
+		if err := p.sendMessage(peer, response); err != nil {
+			log.Printf("Failed to send %s to peer %s: %v", item.Type, peer.ID, err)
+			return
+		}
+	}
+
+	if len(notFound) > 0 {
+		if err := p.sendMessage(peer, &NotFoundData{Items: notFound}); err != nil {
+			log.Printf("Failed to send notfound to peer %s: %v", peer.ID, err)
+		}
+	}
+}
+
+// handleNotFoundMessage lets items the peer could not deliver be requested elsewhere
+func (p *P2P) handleNotFoundMessage(peer *Peer, data *NotFoundData) {
+	p.inv.mu.Lock()
+	for _, item := range data.Items {
+		delete(p.inv.requested, item.Hash)
+	}
+	p.inv.mu.Unlock()
+}
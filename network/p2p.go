@@ -1,631 +1,935 @@
-package network
-
-import (
-	"bufio"
-	"encoding/json"
-	"fmt"
-	"io"
-	"log"
-	"net"
-	"sync"
-	"time"
-)
-
-// P2PConfig represents P2P network configuration
-type P2PConfig struct {
-	ListenAddr    string
-	SeedNodes     []string
-	MaxPeers      int
-	DialTimeout   time.Duration
-	ReadTimeout   time.Duration
-	WriteTimeout  time.Duration
-	KeepAlive     time.Duration
-	DiscoveryPort int
-}
-
-// P2P represents the P2P network manager
-type P2P struct {
-	config    *P2PConfig
-	listener  net.Listener
-	peers     map[string]*Peer
-	peerMutex sync.RWMutex
-	running   bool
-	stopChan  chan struct{}
-	blockChan chan *Block
-	txChan    chan *Transaction
-	mu        sync.RWMutex
-}
-
-// Peer represents a connected peer
-type Peer struct {
-	ID        string
-	Address   string
-	Conn      net.Conn
-	LastSeen  time.Time
-	Connected bool
-	Version   string
-	Height    uint64
-	Services  uint64
-	UserAgent string
-	mu        sync.RWMutex
-}
-
-// Block represents a blockchain block
-type Block struct {
-	Header BlockHeader
-	Txs    []Transaction
-	Hash   [32]byte
-}
-
-// BlockHeader represents a block header
-type BlockHeader struct {
-	ParentHash  [32]byte
-	Number      uint64
-	Timestamp   time.Time
-	Difficulty  uint64
-	Miner       [20]byte
-	Nonce       uint64
-	MerkleRoot  [32]byte
-	TxCount     uint32
-	NetworkFee  uint64
-	TreasuryFee uint64
-}
-
-// Transaction represents a blockchain transaction
-type Transaction struct {
-	From      [20]byte
-	To        [20]byte
-	Amount    uint64
-	Nonce     uint64
-	Fee       uint64
-	GasUsed   uint64
-	GasPrice  uint64
-	Data      []byte
-	Signature []byte
-	Hash      [32]byte
-}
-
-// Message represents a P2P message
-type Message struct {
-	Type    string      `json:"type"`
-	Data    interface{} `json:"data"`
-	ID      string      `json:"id"`
-	Version string      `json:"version"`
-	Time    time.Time   `json:"time"`
-}
-
-// NewP2P creates a new P2P network manager
-func NewP2P(config *P2PConfig) *P2P {
-	return &P2P{
-		config:    config,
-		peers:     make(map[string]*Peer),
-		stopChan:  make(chan struct{}),
-		blockChan: make(chan *Block, 100),
-		txChan:    make(chan *Transaction, 1000),
-	}
-}
-
-// Start starts the P2P network
-func (p *P2P) Start() error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	if p.running {
-		return fmt.Errorf("P2P network is already running")
-	}
-
-	// Start listening
-	listener, err := net.Listen("tcp", p.config.ListenAddr)
-	if err != nil {
-		return fmt.Errorf("failed to start listener: %v", err)
-	}
-
-	p.listener = listener
-	p.running = true
-
-	// Start accepting connections
-	go p.acceptConnections()
-
-	// Start peer discovery
-	go p.discoverPeers()
-
-	// Start peer maintenance
-	go p.maintainPeers()
-
-	log.Printf("P2P network started on %s", p.config.ListenAddr)
-
-	return nil
-}
-
-// Stop stops the P2P network
-func (p *P2P) Stop() {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	if !p.running {
-		return
-	}
-
-	p.running = false
-	close(p.stopChan)
-
-	// Close listener
-	if p.listener != nil {
-		p.listener.Close()
-	}
-
-	// Close all peer connections
-	p.peerMutex.Lock()
-	for _, peer := range p.peers {
-		if peer.Conn != nil {
-			peer.Conn.Close()
-		}
-	}
-	p.peers = make(map[string]*Peer)
-	p.peerMutex.Unlock()
-
-	log.Println("P2P network stopped")
-}
-
-// IsRunning returns true if the P2P network is running
-func (p *P2P) IsRunning() bool {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-
-	return p.running
-}
-
-// GetPeers returns a list of connected peers
-func (p *P2P) GetPeers() []*Peer {
-	p.peerMutex.RLock()
-	defer p.peerMutex.RUnlock()
-
-	peers := make([]*Peer, 0, len(p.peers))
-	for _, peer := range p.peers {
-		peers = append(peers, peer)
-	}
-
-	return peers
-}
-
-// GetPeerCount returns the number of connected peers
-func (p *P2P) GetPeerCount() int {
-	p.peerMutex.RLock()
-	defer p.peerMutex.RUnlock()
-
-	return len(p.peers)
-}
-
-// BroadcastBlock broadcasts a block to all peers
-func (p *P2P) BroadcastBlock(block *Block) error {
-	message := &Message{
-		Type:    "block",
-		Data:    block,
-		ID:      fmt.Sprintf("%x", block.Hash),
-		Version: "1.0",
-		Time:    time.Now(),
-	}
-
-	return p.broadcastMessage(message)
-}
-
-// BroadcastTransaction broadcasts a transaction to all peers
-func (p *P2P) BroadcastTransaction(tx *Transaction) error {
-	message := &Message{
-		Type:    "transaction",
-		Data:    tx,
-		ID:      fmt.Sprintf("%x", tx.Hash),
-		Version: "1.0",
-		Time:    time.Now(),
-	}
-
-	return p.broadcastMessage(message)
-}
-
-// GetBlockChannel returns the block channel
-func (p *P2P) GetBlockChannel() <-chan *Block {
-	return p.blockChan
-}
-
-// GetTransactionChannel returns the transaction channel
-func (p *P2P) GetTransactionChannel() <-chan *Transaction {
-	return p.txChan
-}
-
-// acceptConnections accepts incoming connections
-func (p *P2P) acceptConnections() {
-	for {
-		select {
-		case <-p.stopChan:
-			return
-		default:
-			conn, err := p.listener.Accept()
-			if err != nil {
-				if p.IsRunning() {
-					log.Printf("Failed to accept connection: %v", err)
-				}
-				continue
-			}
-
-			// Handle connection in goroutine
-			go p.handleConnection(conn)
-		}
-	}
-}
-
-// handleConnection handles a new connection
-func (p *P2P) handleConnection(conn net.Conn) {
-	defer conn.Close()
-
-	// Create peer
-	peer := &Peer{
-		ID:        conn.RemoteAddr().String(),
-		Address:   conn.RemoteAddr().String(),
-		Conn:      conn,
-		LastSeen:  time.Now(),
-		Connected: true,
-	}
-
-	// Add peer
-	p.addPeer(peer)
-	defer p.removePeer(peer.ID)
-
-	// Handle peer communication
-	p.handlePeerCommunication(peer)
-}
-
-// handlePeerCommunication handles communication with a peer
-func (p *P2P) handlePeerCommunication(peer *Peer) {
-	reader := bufio.NewReader(peer.Conn)
-
-	for {
-		select {
-		case <-p.stopChan:
-			return
-		default:
-			// Set read timeout
-			peer.Conn.SetReadDeadline(time.Now().Add(p.config.ReadTimeout))
-
-			// Read message
-			line, err := reader.ReadBytes('\n')
-			if err != nil {
-				if err != io.EOF {
-					log.Printf("Failed to read from peer %s: %v", peer.ID, err)
-				}
-				return
-			}
-
-			// Parse message
-			var message Message
-			if err := json.Unmarshal(line, &message); err != nil {
-				log.Printf("Failed to parse message from peer %s: %v", peer.ID, err)
-				continue
-			}
-
-			// Handle message
-			p.handleMessage(peer, &message)
-
-			// Update last seen
-			peer.mu.Lock()
-			peer.LastSeen = time.Now()
-			peer.mu.Unlock()
-		}
-	}
-}
-
-// handleMessage handles a received message
-func (p *P2P) handleMessage(peer *Peer, message *Message) {
-	switch message.Type {
-	case "version":
-		p.handleVersionMessage(peer, message)
-	case "block":
-		p.handleBlockMessage(peer, message)
-	case "transaction":
-		p.handleTransactionMessage(peer, message)
-	case "get_blocks":
-		p.handleGetBlocksMessage(peer, message)
-	case "blocks":
-		p.handleBlocksMessage(peer, message)
-	case "ping":
-		p.handlePingMessage(peer, message)
-	case "pong":
-		p.handlePongMessage(peer, message)
-	default:
-		log.Printf("Unknown message type from peer %s: %s", peer.ID, message.Type)
-	}
-}
-
-// handleVersionMessage handles a version message
-func (p *P2P) handleVersionMessage(peer *Peer, message *Message) {
-	// Update peer version info
-	peer.mu.Lock()
-	peer.Version = message.Version
-	peer.mu.Unlock()
-
-	// Send version response
-	response := &Message{
-		Type:    "version",
-		Data:    map[string]interface{}{"version": "1.0"},
-		Version: "1.0",
-		Time:    time.Now(),
-	}
-
-	p.sendMessage(peer, response)
-}
-
-// handleBlockMessage handles a block message
-func (p *P2P) handleBlockMessage(peer *Peer, message *Message) {
-	// Parse block data
-	blockData, err := json.Marshal(message.Data)
-	if err != nil {
-		log.Printf("Failed to marshal block data: %v", err)
-		return
-	}
-
-	var block Block
-	if err := json.Unmarshal(blockData, &block); err != nil {
-		log.Printf("Failed to unmarshal block: %v", err)
-		return
-	}
-
-	// Forward to block channel
-	select {
-	case p.blockChan <- &block:
-	default:
-		log.Println("Block channel full, dropping block")
-	}
-}
-
-// handleTransactionMessage handles a transaction message
-func (p *P2P) handleTransactionMessage(peer *Peer, message *Message) {
-	// Parse transaction data
-	txData, err := json.Marshal(message.Data)
-	if err != nil {
-		log.Printf("Failed to marshal transaction data: %v", err)
-		return
-	}
-
-	var tx Transaction
-	if err := json.Unmarshal(txData, &tx); err != nil {
-		log.Printf("Failed to unmarshal transaction: %v", err)
-		return
-	}
-
-	// Forward to transaction channel
-	select {
-	case p.txChan <- &tx:
-	default:
-		log.Println("Transaction channel full, dropping transaction")
-	}
-}
-
-// handleGetBlocksMessage handles a get blocks message
-func (p *P2P) handleGetBlocksMessage(peer *Peer, message *Message) {
-	// This would be implemented to send blocks to the peer
-	log.Printf("Received get_blocks request from peer %s", peer.ID)
-}
-
-// handleBlocksMessage handles a blocks message
-func (p *P2P) handleBlocksMessage(peer *Peer, message *Message) {
-	// This would be implemented to handle received blocks
-	log.Printf("Received blocks from peer %s", peer.ID)
-}
-
-// handlePingMessage handles a ping message
-func (p *P2P) handlePingMessage(peer *Peer, message *Message) {
-	// Send pong response
-	response := &Message{
-		Type:    "pong",
-		Data:    message.Data,
-		Version: "1.0",
-		Time:    time.Now(),
-	}
-
-	p.sendMessage(peer, response)
-}
-
-// handlePongMessage handles a pong message
-func (p *P2P) handlePongMessage(peer *Peer, message *Message) {
-	// Update peer last seen
-	peer.mu.Lock()
-	peer.LastSeen = time.Now()
-	peer.mu.Unlock()
-}
-
-// sendMessage sends a message to a peer
-func (p *P2P) sendMessage(peer *Peer, message *Message) error {
-	// Set write timeout
-	peer.Conn.SetWriteDeadline(time.Now().Add(p.config.WriteTimeout))
-
-	// Marshal message
-	data, err := json.Marshal(message)
-	if err != nil {
-		return fmt.Errorf("failed to marshal message: %v", err)
-	}
-
-	// Send message
-	_, err = peer.Conn.Write(append(data, '\n'))
-	if err != nil {
-		return fmt.Errorf("failed to send message: %v", err)
-	}
-
-	return nil
-}
-
-// broadcastMessage broadcasts a message to all peers
-func (p *P2P) broadcastMessage(message *Message) error {
-	p.peerMutex.RLock()
-	defer p.peerMutex.RUnlock()
-
-	var errors []error
-
-	for _, peer := range p.peers {
-		if err := p.sendMessage(peer, message); err != nil {
-			errors = append(errors, err)
-		}
-	}
-
-	if len(errors) > 0 {
-		return fmt.Errorf("failed to send to some peers: %v", errors)
-	}
-
-	return nil
-}
-
-// addPeer adds a peer to the peer list
-func (p *P2P) addPeer(peer *Peer) {
-	p.peerMutex.Lock()
-	defer p.peerMutex.Unlock()
-
-	p.peers[peer.ID] = peer
-	log.Printf("Peer connected: %s", peer.ID)
-}
-
-// removePeer removes a peer from the peer list
-func (p *P2P) removePeer(peerID string) {
-	p.peerMutex.Lock()
-	defer p.peerMutex.Unlock()
-
-	if peer, exists := p.peers[peerID]; exists {
-		peer.Connected = false
-		delete(p.peers, peerID)
-		log.Printf("Peer disconnected: %s", peerID)
-	}
-}
-
-// discoverPeers discovers new peers from seed nodes
-func (p *P2P) discoverPeers() {
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-p.stopChan:
-			return
-		case <-ticker.C:
-			p.connectToSeedNodes()
-		}
-	}
-}
-
-// connectToSeedNodes connects to seed nodes
-func (p *P2P) connectToSeedNodes() {
-	p.peerMutex.RLock()
-	currentPeerCount := len(p.peers)
-	p.peerMutex.RUnlock()
-
-	if currentPeerCount >= p.config.MaxPeers {
-		return
-	}
-
-	for _, seedNode := range p.config.SeedNodes {
-		if currentPeerCount >= p.config.MaxPeers {
-			break
-		}
-
-		// Check if already connected
-		p.peerMutex.RLock()
-		alreadyConnected := false
-		for _, peer := range p.peers {
-			if peer.Address == seedNode {
-				alreadyConnected = true
-				break
-			}
-		}
-		p.peerMutex.RUnlock()
-
-		if alreadyConnected {
-			continue
-		}
-
-		// Connect to seed node
-		go p.connectToPeer(seedNode)
-	}
-}
-
-// connectToPeer connects to a specific peer
-func (p *P2P) connectToPeer(address string) {
-	conn, err := net.DialTimeout("tcp", address, p.config.DialTimeout)
-	if err != nil {
-		log.Printf("Failed to connect to peer %s: %v", address, err)
-		return
-	}
-
-	// Create peer
-	peer := &Peer{
-		ID:        address,
-		Address:   address,
-		Conn:      conn,
-		LastSeen:  time.Now(),
-		Connected: true,
-	}
-
-	// Add peer
-	p.addPeer(peer)
-	defer p.removePeer(peer.ID)
-
-	// Handle peer communication
-	p.handlePeerCommunication(peer)
-}
-
-// maintainPeers maintains peer connections
-func (p *P2P) maintainPeers() {
-	ticker := time.NewTicker(60 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-p.stopChan:
-			return
-		case <-ticker.C:
-			p.cleanupInactivePeers()
-		}
-	}
-}
-
-// cleanupInactivePeers removes inactive peers
-func (p *P2P) cleanupInactivePeers() {
-	p.peerMutex.Lock()
-	defer p.peerMutex.Unlock()
-
-	now := time.Now()
-	for id, peer := range p.peers {
-		if now.Sub(peer.LastSeen) > p.config.KeepAlive*2 {
-			peer.Connected = false
-			if peer.Conn != nil {
-				peer.Conn.Close()
-			}
-			delete(p.peers, id)
-			log.Printf("Removed inactive peer: %s", id)
-		}
-	}
-}
-
-// GetNetworkInfo returns network information
-func (p *P2P) GetNetworkInfo() map[string]interface{} {
-	p.peerMutex.RLock()
-	defer p.peerMutex.RUnlock()
-
-	peers := make([]map[string]interface{}, 0, len(p.peers))
-	for _, peer := range p.peers {
-		peer.mu.RLock()
-		peerInfo := map[string]interface{}{
-			"id":        peer.ID,
-			"address":   peer.Address,
-			"connected": peer.Connected,
-			"version":   peer.Version,
-			"height":    peer.Height,
-			"lastSeen":  peer.LastSeen,
-		}
-		peer.mu.RUnlock()
-		peers = append(peers, peerInfo)
-	}
-
-	return map[string]interface{}{
-		"running":    p.IsRunning(),
-		"listenAddr": p.config.ListenAddr,
-		"peerCount":  len(p.peers),
-		"maxPeers":   p.config.MaxPeers,
-		"peers":      peers,
-	}
-}
+package network
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+	"github.com/kalon-network/kalon/crypto"
+)
+
+// P2PConfig represents P2P network configuration
+type P2PConfig struct {
+	ListenAddr    string
+	SeedNodes     []string // Contacted when the address book cannot fill the outbound target
+	AddNodes      []string // Always kept connected in addition to the outbound target
+	ConnectOnly   []string // When set, the only peers dialed; the address book is not used
+	DataDir       string   // Where the address book is stored; empty keeps it in memory
+	MaxPeers      int      // Total connection limit; inbound connections are refused beyond it
+	DialTimeout   time.Duration
+	ReadTimeout   time.Duration
+	WriteTimeout  time.Duration
+	KeepAlive     time.Duration
+	DiscoveryPort int
+	MaxBlockBytes int    // Maximum serialized block size accepted from peers
+	MaxTxBytes    int    // Maximum serialized transaction size accepted from peers
+	ChainID       uint64 // Peers on a different chain are disconnected
+	Services      uint64 // Service bits advertised to peers
+	UserAgent     string // Software identifier advertised to peers
+
+	TargetOutbound        int // Outbound connections to keep open, DefaultTargetOutbound if zero
+	MaxInbound            int // Inbound connections accepted, DefaultMaxInbound if zero
+	MaxInboundPerNetgroup int // Inbound connections accepted per /16, DefaultMaxInboundPerNetgroup if zero
+
+	MessageRate  float64 // Messages per second allowed from one peer, DefaultMessageRate if zero
+	MessageBurst int     // Messages a peer may send at once above the rate, DefaultMessageBurst if zero
+
+	Encrypt      bool     // Run TLS 1.3 authenticated by node keys on every connection
+	AllowedPeers []string // Node IDs allowed to connect when encrypting; empty allows all
+
+	PingInterval time.Duration // Time between pings to a peer, DefaultPingInterval if zero; at most ReadTimeout/3
+	PingTimeout  time.Duration // Time a peer has to answer a ping, DefaultPingTimeout if zero
+}
+
+const (
+	// DefaultMessageRate is the sustained messages per second accepted from one peer
+	DefaultMessageRate = 100
+
+	// DefaultMessageBurst is the number of messages a peer may send in a burst
+	DefaultMessageBurst = 200
+)
+
+const (
+	// messageOverheadBytes is the room left for the payload struct around a full block batch
+	messageOverheadBytes = 64 * 1024
+
+	// payloadEnvelopeBytes is the room left for the payload struct around a single block or transaction
+	payloadEnvelopeBytes = 1024
+)
+
+// P2P represents the P2P network manager
+type P2P struct {
+	config    *P2PConfig
+	listener  net.Listener
+	peers     map[string]*Peer
+	peerMutex sync.RWMutex
+	running   bool
+	stopChan  chan struct{}
+	blockChan chan *ReceivedBlock
+	txChan    chan *ReceivedTransaction
+	timeSrc   TimeSampler
+	chain     Chain
+	sync      *syncManager
+	addrBook  *AddrBook
+	banMan    *BanManager
+	inv       *inventory
+	magic     [4]byte // Frame magic of our network
+	nodeKey   *crypto.Keypair
+	nodeID    string
+	tlsConfig *tls.Config     // Nil when the transport is not encrypted
+	outbound  map[string]bool // Outbound addresses connected or being dialed, guarded by peerMutex
+	anchors   []string        // Outbound peers from before the last restart, dialed first
+	nonce     uint64          // Sent in version messages to detect self-connections
+	mu        sync.RWMutex
+}
+
+// TimeSampler receives clock offsets reported by peers
+type TimeSampler interface {
+	AddTimeSample(source string, offset time.Duration)
+}
+
+// Peer represents a connected peer
+type Peer struct {
+	ID              string // Node ID on encrypted connections, otherwise the remote address
+	NodeID          string // Authenticated node ID, empty on plaintext connections
+	Address         string
+	ListenAddr      string // Address the peer accepts connections on, from its version message
+	Inbound         bool
+	Conn            net.Conn
+	LastSeen        time.Time
+	ConnectedAt     time.Time
+	Connected       bool
+	Version         string
+	ProtocolVersion uint32
+	Height          uint64
+	BestHash        core.Hash
+	Services        uint64
+	UserAgent       string
+	TimeOffset      time.Duration
+	BanScore        int
+	PingRTT         time.Duration // Round-trip time of the last answered ping
+	MinPingRTT      time.Duration
+	BytesSent       uint64
+	BytesReceived   uint64
+	versionReceived bool
+	verackReceived  bool
+	addrSent        bool
+	pingNonce       uint64 // Nonce of the outstanding ping, zero when none
+	pingSent        time.Time
+	behindSince     time.Time  // When an outbound peer fell too far behind our height
+	knownInv        *hashCache // Blocks and transactions the peer is known to have
+	msgTokens       float64    // Message rate limit bucket
+	msgRefill       time.Time
+	mu              sync.RWMutex
+	writeMu         sync.Mutex // Serializes writes from relay and handler goroutines
+}
+
+// ReceivedBlock is a block received from a peer
+type ReceivedBlock struct {
+	Block  *core.Block
+	PeerID string
+}
+
+// ReceivedTransaction is a transaction received from a peer
+type ReceivedTransaction struct {
+	Tx     *core.Transaction
+	PeerID string
+}
+
+// NewP2P creates a new P2P network manager
+func NewP2P(config *P2PConfig) *P2P {
+	p := &P2P{
+		config:    config,
+		peers:     make(map[string]*Peer),
+		stopChan:  make(chan struct{}),
+		blockChan: make(chan *ReceivedBlock, 100),
+		txChan:    make(chan *ReceivedTransaction, 1000),
+		nonce:     rand.Uint64(),
+		addrBook:  NewAddrBook(config.DataDir),
+		banMan:    NewBanManager(config.DataDir),
+		inv:       newInventory(),
+		magic:     NetworkMagic(config.ChainID),
+		outbound:  make(map[string]bool),
+	}
+	p.sync = newSyncManager(p)
+	return p
+}
+
+// Start starts the P2P network
+func (p *P2P) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.running {
+		return fmt.Errorf("P2P network is already running")
+	}
+
+	// Load the node identity before accepting anyone
+	if len(p.config.AllowedPeers) > 0 && !p.config.Encrypt {
+		return fmt.Errorf("peer allow-list requires an encrypted transport")
+	}
+	nodeKey, err := LoadOrCreateNodeKey(p.config.DataDir)
+	if err != nil {
+		return fmt.Errorf("failed to load node key: %v", err)
+	}
+	p.nodeKey = nodeKey
+	p.nodeID = NodeIDFromPubKey(nodeKey.Public)
+	if p.config.Encrypt {
+		if p.tlsConfig, err = p.newTLSConfig(); err != nil {
+			return err
+		}
+	}
+
+	// Start listening
+	listener, err := net.Listen("tcp", p.config.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to start listener: %v", err)
+	}
+
+	p.listener = listener
+	p.running = true
+
+	if err := p.addrBook.Load(); err != nil {
+		log.Printf("Failed to load address book: %v", err)
+	}
+	if err := p.banMan.Load(); err != nil {
+		log.Printf("Failed to load ban list: %v", err)
+	}
+	if p.anchors, err = p.loadAnchors(); err != nil {
+		log.Printf("Failed to load anchors: %v", err)
+	}
+
+	// Start accepting connections
+	go p.acceptConnections()
+
+	// Start outbound connection management
+	go p.maintainOutbound()
+
+	// Start peer maintenance
+	go p.maintainPeers()
+
+	// Start pinging peers
+	go p.maintainPings()
+
+	// Start block download
+	go p.sync.run()
+
+	log.Printf("P2P network started on %s (node ID %s, encrypted: %t)", p.config.ListenAddr, p.nodeID, p.tlsConfig != nil)
+
+	return nil
+}
+
+// Stop stops the P2P network
+func (p *P2P) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.running {
+		return
+	}
+
+	p.running = false
+	close(p.stopChan)
+
+	// Close listener
+	if p.listener != nil {
+		p.listener.Close()
+	}
+
+	// Remember our best outbound peers for the next start
+	if err := p.saveAnchors(p.anchorAddresses()); err != nil {
+		log.Printf("Failed to save anchors: %v", err)
+	}
+
+	// Close all peer connections
+	p.peerMutex.Lock()
+	for _, peer := range p.peers {
+		if peer.Conn != nil {
+			peer.Conn.Close()
+		}
+	}
+	p.peers = make(map[string]*Peer)
+	p.peerMutex.Unlock()
+
+	if err := p.addrBook.Save(); err != nil {
+		log.Printf("Failed to save address book: %v", err)
+	}
+
+	log.Println("P2P network stopped")
+}
+
+// IsRunning returns true if the P2P network is running
+func (p *P2P) IsRunning() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.running
+}
+
+// GetPeers returns a list of connected peers
+func (p *P2P) GetPeers() []*Peer {
+	p.peerMutex.RLock()
+	defer p.peerMutex.RUnlock()
+
+	peers := make([]*Peer, 0, len(p.peers))
+	for _, peer := range p.peers {
+		peers = append(peers, peer)
+	}
+
+	return peers
+}
+
+// activePeers returns the connected peers that have completed the handshake
+func (p *P2P) activePeers() []*Peer {
+	p.peerMutex.RLock()
+	defer p.peerMutex.RUnlock()
+
+	peers := make([]*Peer, 0, len(p.peers))
+	for _, peer := range p.peers {
+		if peer.HandshakeComplete() {
+			peers = append(peers, peer)
+		}
+	}
+
+	return peers
+}
+
+// GetPeerCount returns the number of connected peers
+func (p *P2P) GetPeerCount() int {
+	p.peerMutex.RLock()
+	defer p.peerMutex.RUnlock()
+
+	return len(p.peers)
+}
+
+// BroadcastBlock broadcasts a block to all peers
+func (p *P2P) BroadcastBlock(block *core.Block) error {
+	return p.RelayBlock(block, "")
+}
+
+// RelayBlock announces a block to all peers except the one it came from
+func (p *P2P) RelayBlock(block *core.Block, fromPeer string) error {
+	return p.relayBlock(block, fromPeer)
+}
+
+// BroadcastTransaction broadcasts a transaction to all peers
+func (p *P2P) BroadcastTransaction(tx *core.Transaction) error {
+	return p.RelayTransaction(tx, "")
+}
+
+// RelayTransaction announces a transaction to all peers except the one it came from
+func (p *P2P) RelayTransaction(tx *core.Transaction, fromPeer string) error {
+	return p.relayTransaction(tx, fromPeer)
+}
+
+// SetTimeSource sets the receiver for peer clock offsets used for network-adjusted time
+func (p *P2P) SetTimeSource(ts TimeSampler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.timeSrc = ts
+}
+
+// SetChain sets the blockchain served to peers and synced from them
+func (p *P2P) SetChain(chain Chain) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.chain = chain
+}
+
+// getChain returns the blockchain, or nil if none is set
+func (p *P2P) getChain() Chain {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.chain
+}
+
+// NodeID returns the node ID derived from this node's identity key, empty before Start
+func (p *P2P) NodeID() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.nodeID
+}
+
+// GetSyncStatus returns the initial block download progress
+func (p *P2P) GetSyncStatus() SyncStatus {
+	return p.sync.status()
+}
+
+// ConnectPeer opens an outbound connection to a peer in the background,
+// unless we are already connected to it
+func (p *P2P) ConnectPeer(address string) {
+	p.dial(address)
+}
+
+// GetBlockChannel returns the channel of blocks received from peers
+func (p *P2P) GetBlockChannel() <-chan *ReceivedBlock {
+	return p.blockChan
+}
+
+// GetTransactionChannel returns the channel of transactions received from peers
+func (p *P2P) GetTransactionChannel() <-chan *ReceivedTransaction {
+	return p.txChan
+}
+
+// Addr returns the address the P2P listener is bound to
+func (p *P2P) Addr() net.Addr {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.listener == nil {
+		return nil
+	}
+	return p.listener.Addr()
+}
+
+// acceptConnections accepts incoming connections
+func (p *P2P) acceptConnections() {
+	for {
+		select {
+		case <-p.stopChan:
+			return
+		default:
+			conn, err := p.listener.Accept()
+			if err != nil {
+				if p.IsRunning() {
+					log.Printf("Failed to accept connection: %v", err)
+				}
+				continue
+			}
+
+			// Handle connection in goroutine
+			go p.handleConnection(conn)
+		}
+	}
+}
+
+// handleConnection handles a new connection
+func (p *P2P) handleConnection(conn net.Conn) {
+	defer conn.Close()
+
+	if p.banMan.IsBanned(conn.RemoteAddr().String()) {
+		log.Printf("Rejected connection from banned address %s", conn.RemoteAddr())
+		return
+	}
+
+	// Refuse early when the slots are full; addPeer checks again before admitting the peer
+	p.peerMutex.RLock()
+	err := p.checkInbound(conn.RemoteAddr().String())
+	p.peerMutex.RUnlock()
+	if err != nil {
+		log.Printf("Rejected connection from %s: %v", conn.RemoteAddr(), err)
+		return
+	}
+
+	secured, nodeID, err := p.secureConn(conn, true)
+	if err != nil {
+		log.Printf("Rejected connection from %s: %v", conn.RemoteAddr(), err)
+		return
+	}
+
+	// Create peer
+	peer := &Peer{
+		ID:          conn.RemoteAddr().String(),
+		NodeID:      nodeID,
+		Address:     conn.RemoteAddr().String(),
+		Conn:        secured,
+		LastSeen:    time.Now(),
+		ConnectedAt: time.Now(),
+		Connected:   true,
+		knownInv:    newHashCache(knownInventorySize),
+		Inbound:     true,
+	}
+	if nodeID != "" {
+		peer.ID = nodeID
+	}
+
+	// Add peer
+	if !p.addPeer(peer) {
+		return
+	}
+	defer p.removePeer(peer.ID)
+
+	// Handle peer communication
+	p.handlePeerCommunication(peer)
+}
+
+// handlePeerCommunication handles communication with a peer
+func (p *P2P) handlePeerCommunication(peer *Peer) {
+	// Both sides announce themselves first
+	if err := p.sendVersion(peer); err != nil {
+		log.Printf("Failed to send version to peer %s: %v", peer.ID, err)
+		return
+	}
+
+	reader := bufio.NewReader(peer.Conn)
+
+	for {
+		select {
+		case <-p.stopChan:
+			return
+		default:
+			// Set read timeout, shorter until the handshake completes
+			deadline := time.Now().Add(p.config.ReadTimeout)
+			if !peer.HandshakeComplete() {
+				if handshakeDeadline := peer.ConnectedAt.Add(handshakeTimeout); handshakeDeadline.Before(deadline) {
+					deadline = handshakeDeadline
+				}
+			}
+			peer.Conn.SetReadDeadline(deadline)
+
+			// Read message; a framing error leaves the stream unusable
+			command, data, err := ReadMessage(reader, p.magic, p.maxMessageSize())
+			if err != nil {
+				if err != io.EOF {
+					log.Printf("Failed to read from peer %s: %v", peer.ID, err)
+				}
+				return
+			}
+
+			peer.mu.Lock()
+			peer.BytesReceived += uint64(MessageHeaderSize + len(data))
+			peer.mu.Unlock()
+
+			// Messages above the peer's rate are dropped and counted against it
+			if !p.allowMessage(peer) {
+				p.Misbehaving(peer.ID, 10, "message rate limit exceeded")
+				continue
+			}
+
+			if limit := p.maxPayloadSize(command); len(data) > limit {
+				p.Misbehaving(peer.ID, 20, fmt.Sprintf("oversized %s message of %d bytes", command, len(data)))
+				continue
+			}
+
+			// Parse the payload into the command's struct
+			message, err := DecodePayload(command, data)
+			if err == errUnknownCommand {
+				log.Printf("Unknown message type from peer %s: %s", peer.ID, command)
+				continue
+			}
+			if err != nil {
+				p.Misbehaving(peer.ID, 10, fmt.Sprintf("malformed message: %v", err))
+				continue
+			}
+
+			// Handle message
+			if err := p.handleMessage(peer, message); err != nil {
+				log.Printf("Disconnecting peer %s: %v", peer.ID, err)
+				return
+			}
+
+			// Update last seen
+			peer.mu.Lock()
+			peer.LastSeen = time.Now()
+			peer.mu.Unlock()
+		}
+	}
+}
+
+// allowMessage takes a token from the peer's message bucket, refilled at the configured rate
+func (p *P2P) allowMessage(peer *Peer) bool {
+	rate := p.config.MessageRate
+	if rate <= 0 {
+		rate = DefaultMessageRate
+	}
+	burst := float64(p.config.MessageBurst)
+	if burst <= 0 {
+		burst = DefaultMessageBurst
+	}
+
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	now := time.Now()
+	if peer.msgRefill.IsZero() {
+		peer.msgTokens = burst
+	} else {
+		peer.msgTokens += now.Sub(peer.msgRefill).Seconds() * rate
+		if peer.msgTokens > burst {
+			peer.msgTokens = burst
+		}
+	}
+	peer.msgRefill = now
+
+	if peer.msgTokens < 1 {
+		return false
+	}
+	peer.msgTokens--
+	return true
+}
+
+// maxMessageSize returns the largest message accepted from a peer
+func (p *P2P) maxMessageSize() int {
+	return p.config.MaxBlockBytes + messageOverheadBytes
+}
+
+// maxPayloadSize returns the largest payload accepted for a command
+func (p *P2P) maxPayloadSize(command string) int {
+	switch command {
+	case CmdBlock:
+		return p.config.MaxBlockBytes + payloadEnvelopeBytes
+	case CmdTransaction:
+		return p.config.MaxTxBytes + payloadEnvelopeBytes
+	}
+	return p.maxMessageSize()
+}
+
+// handleMessage handles a received message; an error means the peer is disconnected
+func (p *P2P) handleMessage(peer *Peer, message Payload) error {
+	switch msg := message.(type) {
+	case *VersionData:
+		return p.handleVersionMessage(peer, msg)
+	case *VerackData:
+		return p.handleVerackMessage(peer)
+	}
+
+	// Nothing but the handshake is processed until it completes
+	if !peer.HandshakeComplete() {
+		return fmt.Errorf("received %s before handshake completed", message.Command())
+	}
+
+	switch msg := message.(type) {
+	case *BlockData:
+		p.handleBlockMessage(peer, msg)
+	case *TransactionData:
+		p.handleTransactionMessage(peer, msg)
+	case *GetHeadersData:
+		p.sync.handleGetHeaders(peer, msg)
+	case *HeadersData:
+		p.sync.handleHeaders(peer, msg)
+	case *GetBlocksData:
+		p.sync.handleGetBlocks(peer, msg)
+	case *BlocksData:
+		p.sync.handleBlocks(peer, msg)
+	case *InvData:
+		p.handleInvMessage(peer, msg)
+	case *GetDataData:
+		p.handleGetDataMessage(peer, msg)
+	case *NotFoundData:
+		p.handleNotFoundMessage(peer, msg)
+	case *GetAddrData:
+		return p.handleGetAddrMessage(peer)
+	case *AddrData:
+		return p.handleAddrMessage(peer, msg)
+	case *PingData:
+		p.handlePingMessage(peer, msg)
+	case *PongData:
+		p.handlePongMessage(peer, msg)
+	}
+
+	return nil
+}
+
+// handleBlockMessage handles a block message
+func (p *P2P) handleBlockMessage(peer *Peer, data *BlockData) {
+	block := data.Block
+	if block == nil {
+		p.Misbehaving(peer.ID, 10, "block message without a block")
+		return
+	}
+	if block.CalculateHash() != block.Hash {
+		p.Misbehaving(peer.ID, BanThreshold, "block hash does not match its header")
+		return
+	}
+
+	// The sender has at least this block once it carries its work and builds on a block
+	// we know; otherwise its headers are fetched, which raises its height if they check out
+	if chain := p.getChain(); chain != nil {
+		if !chain.GetRules().CheckProofOfWork(block) {
+			p.Misbehaving(peer.ID, BanThreshold, "block has invalid proof of work")
+			return
+		}
+		switch {
+		case p.linksToKnownHeader(chain, block.Header.ParentHash):
+			peer.noteHeight(block.Header.Number, block.Hash)
+			if block.Header.Number > chain.GetHeight()+1 {
+				p.sync.wake()
+			}
+		case block.Header.Number > chain.GetHeight():
+			p.sync.syncFrom(peer)
+		}
+	}
+
+	// Drop blocks we already accepted from another peer
+	peer.knownInv.Add(block.Hash)
+	if !p.inv.delivered(block.Hash) {
+		return
+	}
+
+	// Forward to block channel
+	select {
+	case p.blockChan <- &ReceivedBlock{Block: block, PeerID: peer.ID}:
+	default:
+		log.Println("Block channel full, dropping block")
+	}
+}
+
+// handleTransactionMessage handles a transaction message
+func (p *P2P) handleTransactionMessage(peer *Peer, data *TransactionData) {
+	tx := data.Tx
+	if tx == nil {
+		p.Misbehaving(peer.ID, 10, "transaction message without a transaction")
+		return
+	}
+	if tx.TxID() != tx.Hash {
+		p.Misbehaving(peer.ID, BanThreshold, "transaction hash does not match its contents")
+		return
+	}
+
+	// Drop transactions we already accepted from another peer
+	peer.knownInv.Add(tx.Hash)
+	if !p.inv.delivered(tx.Hash) {
+		return
+	}
+
+	// Forward to transaction channel
+	select {
+	case p.txChan <- &ReceivedTransaction{Tx: tx, PeerID: peer.ID}:
+	default:
+		log.Println("Transaction channel full, dropping transaction")
+	}
+}
+
+// handlePingMessage handles a ping message
+func (p *P2P) handlePingMessage(peer *Peer, data *PingData) {
+	// Send pong response
+	p.sendMessage(peer, &PongData{Nonce: data.Nonce})
+}
+
+// handlePongMessage records the round-trip time of the ping the pong answers
+func (p *P2P) handlePongMessage(peer *Peer, data *PongData) {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	peer.LastSeen = time.Now()
+
+	// Pongs for pings we did not send, or that were already answered, are ignored
+	if data.Nonce == 0 || data.Nonce != peer.pingNonce {
+		return
+	}
+	peer.pingNonce = 0
+	peer.PingRTT = time.Since(peer.pingSent)
+	if peer.MinPingRTT == 0 || peer.PingRTT < peer.MinPingRTT {
+		peer.MinPingRTT = peer.PingRTT
+	}
+}
+
+// sendMessage frames a payload and sends it to a peer
+func (p *P2P) sendMessage(peer *Peer, payload Payload) error {
+	frame, err := EncodeMessage(p.magic, payload)
+	if err != nil {
+		return err
+	}
+
+	peer.writeMu.Lock()
+	defer peer.writeMu.Unlock()
+
+	// Set write timeout
+	peer.Conn.SetWriteDeadline(time.Now().Add(p.config.WriteTimeout))
+
+	// Send message
+	if _, err := peer.Conn.Write(frame); err != nil {
+		return fmt.Errorf("failed to send message: %v", err)
+	}
+
+	peer.mu.Lock()
+	peer.BytesSent += uint64(len(frame))
+	peer.mu.Unlock()
+
+	return nil
+}
+
+// addPeer adds a peer to the peer list
+func (p *P2P) addPeer(peer *Peer) bool {
+	p.peerMutex.Lock()
+	defer p.peerMutex.Unlock()
+
+	// A node dialing us while we dial it ends up with two connections; keep the first
+	if _, exists := p.peers[peer.ID]; exists {
+		log.Printf("Already connected to peer %s, closing duplicate connection", peer.ID)
+		return false
+	}
+	if peer.Inbound {
+		if err := p.checkInbound(peer.Address); err != nil {
+			log.Printf("Rejected connection from %s: %v", peer.Address, err)
+			return false
+		}
+	}
+
+	p.peers[peer.ID] = peer
+	log.Printf("Peer connected: %s", peer.ID)
+	return true
+}
+
+// removePeer removes a peer from the peer list
+func (p *P2P) removePeer(peerID string) {
+	p.peerMutex.Lock()
+	defer p.peerMutex.Unlock()
+
+	if peer, exists := p.peers[peerID]; exists {
+		peer.Connected = false
+		delete(p.peers, peerID)
+		log.Printf("Peer disconnected: %s", peerID)
+	}
+
+	p.sync.removePeer(peerID)
+}
+
+// connectToPeer connects to a specific peer; the address must already be marked outbound
+func (p *P2P) connectToPeer(address string) {
+	defer func() {
+		p.peerMutex.Lock()
+		delete(p.outbound, address)
+		p.peerMutex.Unlock()
+	}()
+
+	conn, err := net.DialTimeout("tcp", address, p.config.DialTimeout)
+	if err != nil {
+		log.Printf("Failed to connect to peer %s: %v", address, err)
+		p.addrBook.Failed(address)
+		return
+	}
+	defer conn.Close()
+
+	secured, nodeID, err := p.secureConn(conn, false)
+	if err != nil {
+		log.Printf("Failed to connect to peer %s: %v", address, err)
+		p.addrBook.Failed(address)
+		return
+	}
+
+	// Create peer
+	peer := &Peer{
+		ID:          address,
+		NodeID:      nodeID,
+		Address:     address,
+		Conn:        secured,
+		LastSeen:    time.Now(),
+		ConnectedAt: time.Now(),
+		Connected:   true,
+		knownInv:    newHashCache(knownInventorySize),
+	}
+	if nodeID != "" {
+		peer.ID = nodeID
+	}
+
+	// Add peer
+	if !p.addPeer(peer) {
+		return
+	}
+	defer p.removePeer(peer.ID)
+
+	// Handle peer communication
+	p.handlePeerCommunication(peer)
+
+	// A peer that never completes the handshake is no better than one that refuses connections
+	if !peer.HandshakeComplete() {
+		p.addrBook.Failed(address)
+	}
+}
+
+// maintainPeers maintains peer connections
+func (p *P2P) maintainPeers() {
+	ticker := time.NewTicker(60 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopChan:
+			return
+		case <-ticker.C:
+			p.cleanupInactivePeers()
+			p.inv.expire()
+			if err := p.addrBook.Save(); err != nil {
+				log.Printf("Failed to save address book: %v", err)
+			}
+		}
+	}
+}
+
+// cleanupInactivePeers disconnects inactive peers. Closing the connection ends its
+// handler, which removes the peer and its sync requests like any other disconnect.
+func (p *P2P) cleanupInactivePeers() {
+	p.peerMutex.RLock()
+	defer p.peerMutex.RUnlock()
+
+	now := time.Now()
+	for id, peer := range p.peers {
+		peer.mu.RLock()
+		lastSeen := peer.LastSeen
+		peer.mu.RUnlock()
+		if now.Sub(lastSeen) > p.config.KeepAlive*2 {
+			log.Printf("Disconnecting inactive peer: %s", id)
+			peer.Conn.Close()
+		}
+	}
+}
+
+// GetNetworkInfo returns network information
+func (p *P2P) GetNetworkInfo() map[string]interface{} {
+	// Start sets the node identity under p.mu, so it is read before taking peerMutex
+	p.mu.RLock()
+	nodeID, encrypted := p.nodeID, p.tlsConfig != nil
+	p.mu.RUnlock()
+
+	p.peerMutex.RLock()
+	defer p.peerMutex.RUnlock()
+
+	inbound := 0
+	peers := make([]map[string]interface{}, 0, len(p.peers))
+	for _, peer := range p.peers {
+		direction := "outbound"
+		if peer.Inbound {
+			direction = "inbound"
+			inbound++
+		}
+
+		peer.mu.RLock()
+		peerInfo := map[string]interface{}{
+			"id":         peer.ID,
+			"address":    peer.Address,
+			"nodeId":     peer.NodeID,
+			"inbound":    peer.Inbound,
+			"direction":  direction,
+			"connected":  peer.Connected,
+			"version":    peer.Version,
+			"userAgent":  peer.UserAgent,
+			"services":   peer.Services,
+			"handshake":  peer.versionReceived && peer.verackReceived,
+			"height":     peer.Height,
+			"lastSeen":   peer.LastSeen,
+			"timeOffset": peer.TimeOffset.Seconds(),
+			"banScore":   peer.BanScore,
+			"pingTime":   peer.PingRTT.Seconds(),
+			"minPing":    peer.MinPingRTT.Seconds(),
+			"bytesSent":  peer.BytesSent,
+			"bytesRecv":  peer.BytesReceived,
+		}
+		if peer.pingNonce != 0 {
+			peerInfo["pingWait"] = time.Since(peer.pingSent).Seconds()
+		}
+		peer.mu.RUnlock()
+		peers = append(peers, peerInfo)
+	}
+
+	return map[string]interface{}{
+		"running":    p.IsRunning(),
+		"listenAddr": p.config.ListenAddr,
+		"nodeId":     nodeID,
+		"encrypted":  encrypted,
+		"peerCount":  len(p.peers),
+		"maxPeers":   p.config.MaxPeers,
+		"inbound":    inbound,
+		"maxInbound": p.maxInbound(),
+		"outbound":   len(p.outbound),
+		"knownAddrs": p.addrBook.Size(),
+		"peers":      peers,
+	}
+}
@@ -0,0 +1,293 @@
+package network
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+// waitFor polls a condition until it holds or the timeout expires
+func waitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return cond()
+}
+
+// TestHandshake tests the version/verack exchange between two nodes
+func TestHandshake(t *testing.T) {
+	chainA := core.NewBlockchainV2(newTestGenesis(), nil)
+	for i := 0; i < 3; i++ {
+		if err := chainA.AddBlockV2(chainA.CreateNewBlockV2(core.Address{1}, nil)); err != nil {
+			t.Fatalf("Failed to add block: %v", err)
+		}
+	}
+	chainB := core.NewBlockchainV2(newTestGenesis(), nil)
+
+	a := newTestP2P(t, chainA)
+	b := newTestP2P(t, chainB)
+	b.ConnectPeer(a.Addr().String())
+
+	if !waitFor(5*time.Second, func() bool { return len(a.activePeers()) == 1 && len(b.activePeers()) == 1 }) {
+		t.Fatal("Expected handshake to complete on both sides")
+	}
+
+	peer := b.activePeers()[0]
+	peer.mu.RLock()
+	defer peer.mu.RUnlock()
+	if peer.ProtocolVersion != ProtocolVersion || peer.UserAgent != DefaultUserAgent {
+		t.Errorf("Unexpected peer version info: %d %q", peer.ProtocolVersion, peer.UserAgent)
+	}
+	if peer.Height < 3 || peer.BestHash == (core.Hash{}) {
+		t.Errorf("Expected peer best block from version, got height %d", peer.Height)
+	}
+}
+
+// TestHandshakeRejectsOtherNetwork tests that peers on another chain or genesis are disconnected
+func TestHandshakeRejectsOtherNetwork(t *testing.T) {
+	chain := core.NewBlockchainV2(newTestGenesis(), nil)
+	a := newTestP2P(t, chain)
+
+	// Different chain ID
+	other := newTestP2PWithChainID(t, core.NewBlockchainV2(newTestGenesis(), nil), 1)
+	other.ConnectPeer(a.Addr().String())
+
+	// Same chain ID, different genesis block
+	genesis := newTestGenesis()
+	genesis.Difficulty.InitialDifficulty = 2
+	forked := newTestP2P(t, core.NewBlockchainV2(genesis, nil))
+	forked.ConnectPeer(a.Addr().String())
+
+	if waitFor(time.Second, func() bool { return len(a.activePeers()) > 0 }) {
+		t.Error("Expected no peer to complete the handshake")
+	}
+	if !waitFor(5*time.Second, func() bool { return a.GetPeerCount() == 0 && other.GetPeerCount() == 0 && forked.GetPeerCount() == 0 }) {
+		t.Errorf("Expected mismatched peers to be disconnected, have %d", a.GetPeerCount())
+	}
+
+	// Connecting to ourselves is detected by the version nonce
+	a.ConnectPeer(a.Addr().String())
+	if waitFor(time.Second, func() bool { return len(a.activePeers()) > 0 }) {
+		t.Error("Expected self-connection not to complete the handshake")
+	}
+	if !waitFor(5*time.Second, func() bool { return a.GetPeerCount() == 0 }) {
+		t.Error("Expected self-connection to be dropped")
+	}
+}
+
+// TestAddressExchange tests that addresses learned through getaddr/addr are dialed
+func TestAddressExchange(t *testing.T) {
+	hub := newTestP2P(t, core.NewBlockchainV2(newTestGenesis(), nil))
+	listener := newTestP2P(t, core.NewBlockchainV2(newTestGenesis(), nil))
+	listener.ConnectPeer(hub.Addr().String())
+
+	// The hub learns where the inbound peer accepts connections
+	if !waitFor(5*time.Second, func() bool { return hub.addrBook.Size() == 1 }) {
+		t.Fatal("Expected hub to record the inbound peer's listen address")
+	}
+
+	newcomer := newTestP2P(t, core.NewBlockchainV2(newTestGenesis(), nil))
+	newcomer.ConnectPeer(hub.Addr().String())
+
+	listenAddr := listener.Addr().String()
+	if !waitFor(10*time.Second, func() bool { return newcomer.isConnected(listenAddr) }) {
+		t.Fatalf("Expected newcomer to dial %s learned from the hub", listenAddr)
+	}
+	if !waitFor(5*time.Second, func() bool { return len(newcomer.activePeers()) == 2 }) {
+		t.Errorf("Expected newcomer to have 2 peers, have %d", len(newcomer.activePeers()))
+	}
+}
+
+// relayCounter plays the node's part in relay: it accepts received items and relays them on,
+// counting how often each one arrived
+type relayCounter struct {
+	mu     sync.Mutex
+	counts map[core.Hash]int
+}
+
+// run consumes blocks and transactions from p until the test ends
+func (r *relayCounter) run(t *testing.T, p *P2P, chain *core.BlockchainV2) {
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case received := <-p.GetBlockChannel():
+				r.count(received.Block.Hash)
+				if err := chain.AddBlockV2(received.Block); err == nil {
+					p.RelayBlock(received.Block, received.PeerID)
+				}
+			case received := <-p.GetTransactionChannel():
+				r.count(received.Tx.Hash)
+				p.RelayTransaction(received.Tx, received.PeerID)
+			}
+		}
+	}()
+}
+
+func (r *relayCounter) count(hash core.Hash) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counts[hash]++
+}
+
+func (r *relayCounter) get(hash core.Hash) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counts[hash]
+}
+
+// TestInventoryRelay tests that blocks and transactions reach every node of a cycle exactly once
+func TestInventoryRelay(t *testing.T) {
+	chains := make([]*core.BlockchainV2, 3)
+	nodes := make([]*P2P, 3)
+	counters := make([]*relayCounter, 3)
+	for i := range nodes {
+		chains[i] = core.NewBlockchainV2(newTestGenesis(), nil)
+		nodes[i] = newTestP2P(t, chains[i])
+		counters[i] = &relayCounter{counts: make(map[core.Hash]int)}
+		counters[i].run(t, nodes[i], chains[i])
+	}
+
+	// Connect the nodes in a triangle so every item can arrive over two paths
+	nodes[1].ConnectPeer(nodes[0].Addr().String())
+	nodes[2].ConnectPeer(nodes[1].Addr().String())
+	nodes[0].ConnectPeer(nodes[2].Addr().String())
+	if !waitFor(5*time.Second, func() bool {
+		for _, node := range nodes {
+			if len(node.activePeers()) != 2 {
+				return false
+			}
+		}
+		return true
+	}) {
+		t.Fatal("Expected every node to have two peers")
+	}
+
+	for i := 1; i <= 5; i++ {
+		block := chains[0].CreateNewBlockV2(core.Address{1}, nil)
+		if err := chains[0].AddBlockV2(block); err != nil {
+			t.Fatalf("Failed to add block %d: %v", i, err)
+		}
+		nodes[0].BroadcastBlock(block)
+
+		if !waitFor(5*time.Second, func() bool {
+			return chains[1].GetHeight() == uint64(i) && chains[2].GetHeight() == uint64(i)
+		}) {
+			t.Fatalf("Expected block %d to reach every node, heights %d and %d", i, chains[1].GetHeight(), chains[2].GetHeight())
+		}
+	}
+
+	tx := &core.Transaction{Timestamp: time.Now()}
+	tx.Hash = tx.TxID()
+	nodes[0].BroadcastTransaction(tx)
+	if !waitFor(5*time.Second, func() bool { return counters[1].get(tx.Hash) > 0 && counters[2].get(tx.Hash) > 0 }) {
+		t.Fatal("Expected transaction to reach every node")
+	}
+
+	// Give any redundant deliveries time to show up
+	time.Sleep(200 * time.Millisecond)
+	for i := 1; i <= 2; i++ {
+		for number := uint64(1); number <= 5; number++ {
+			hash := chains[0].GetBlockByNumber(number).Hash
+			if n := counters[i].get(hash); n != 1 {
+				t.Errorf("Node %d received block %d %d times", i, number, n)
+			}
+		}
+		if n := counters[i].get(tx.Hash); n != 1 {
+			t.Errorf("Node %d received the transaction %d times", i, n)
+		}
+	}
+	if n := counters[0].get(tx.Hash); n != 0 {
+		t.Errorf("Expected the transaction not to echo back to its origin, got it %d times", n)
+	}
+}
+
+// TestJunkUnderRealHash tests that items sent under a hash they do not have are not
+// remembered as seen, so the real items still get through
+func TestJunkUnderRealHash(t *testing.T) {
+	chain := core.NewBlockchainV2(newTestGenesis(), nil)
+	p := newTestP2P(t, chain)
+	attacker := &Peer{ID: "attacker", knownInv: newHashCache(knownInventorySize)}
+	honest := &Peer{ID: "honest", knownInv: newHashCache(knownInventorySize)}
+
+	block := chain.CreateNewBlockV2(core.Address{1}, nil)
+	junk := *block
+	junk.Header.Nonce++
+	p.handleBlockMessage(attacker, &BlockData{Block: &junk})
+
+	tx := &core.Transaction{Timestamp: time.Now(), Outputs: []core.TxOutput{{Address: core.Address{2}, Amount: 1}}}
+	tx.Hash = tx.TxID()
+	junkTx := *tx
+	junkTx.Fee = 1
+	p.handleTransactionMessage(attacker, &TransactionData{Tx: &junkTx})
+
+	p.handleBlockMessage(honest, &BlockData{Block: block})
+	p.handleTransactionMessage(honest, &TransactionData{Tx: tx})
+	select {
+	case received := <-p.GetBlockChannel():
+		if received.Block != block {
+			t.Error("Expected only the real block to be delivered")
+		}
+	default:
+		t.Error("Expected the real block to be delivered")
+	}
+	select {
+	case received := <-p.GetTransactionChannel():
+		if received.Tx != tx {
+			t.Error("Expected only the real transaction to be delivered")
+		}
+	default:
+		t.Error("Expected the real transaction to be delivered")
+	}
+}
+
+// TestUnlinkedBlockKeepsPeerHeight tests that only a block building on a known one raises
+// the sender's height, while an unlinked one makes us ask the sender for headers
+func TestUnlinkedBlockKeepsPeerHeight(t *testing.T) {
+	chain := core.NewBlockchainV2(newTestGenesis(), nil)
+	p := newTestP2P(t, chain)
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+	peer := &Peer{ID: "peer", Conn: local, knownInv: newHashCache(knownInventorySize)}
+
+	unlinked := chain.CreateNewBlockV2(core.Address{1}, nil)
+	unlinked.Header.Number = 1000
+	unlinked.Header.ParentHash = core.Hash{9}
+	unlinked.Hash = unlinked.CalculateHash()
+
+	commands := make(chan string, 1)
+	go func() {
+		command, _, _ := ReadMessage(remote, p.magic, p.maxMessageSize())
+		commands <- command
+	}()
+	p.handleBlockMessage(peer, &BlockData{Block: unlinked})
+	if peer.Height != 0 {
+		t.Errorf("Expected unlinked block not to raise the peer height, got %d", peer.Height)
+	}
+	select {
+	case command := <-commands:
+		if command != CmdGetHeaders {
+			t.Errorf("Expected headers request, got %s", command)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected headers request to the sender of an unlinked block")
+	}
+
+	linked := chain.CreateNewBlockV2(core.Address{1}, nil)
+	p.handleBlockMessage(peer, &BlockData{Block: linked})
+	if peer.Height != 1 {
+		t.Errorf("Expected linked block to raise the peer height to 1, got %d", peer.Height)
+	}
+}
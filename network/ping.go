@@ -0,0 +1,156 @@
+package network
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+const (
+	// DefaultPingInterval is how often peers are pinged when not configured,
+	// unless a third of the read timeout is shorter
+	DefaultPingInterval = 2 * time.Minute
+
+	// DefaultPingTimeout is how long a peer may take to answer a ping when not configured
+	DefaultPingTimeout = time.Minute
+
+	// pingCheckInterval is how often outstanding pings and peer heights are checked
+	pingCheckInterval = time.Second
+
+	// maxPeerHeightLag is how many blocks an outbound peer may be behind us before it counts as stale
+	maxPeerHeightLag = 100
+
+	// staleChainTimeout is how long an outbound peer may stay behind before it is evicted
+	staleChainTimeout = 10 * time.Minute
+)
+
+// maintainPings pings peers, evicting those that stop answering or fall behind
+func (p *P2P) maintainPings() {
+	ticker := time.NewTicker(pingCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopChan:
+			return
+		case now := <-ticker.C:
+			p.checkPeers(now)
+		}
+	}
+}
+
+// checkPeers sends due pings and evicts unresponsive or stale peers as of now
+func (p *P2P) checkPeers(now time.Time) {
+	var height uint64
+	if chain := p.getChain(); chain != nil {
+		height = chain.GetHeight()
+	}
+
+	for _, peer := range p.activePeers() {
+		if reason := p.evictionReason(peer, height, now); reason != "" {
+			p.evictPeer(peer, reason)
+			continue
+		}
+
+		peer.mu.RLock()
+		due := peer.pingNonce == 0 && now.Sub(peer.pingSent) >= p.pingInterval()
+		peer.mu.RUnlock()
+		if due {
+			p.sendPing(peer)
+		}
+	}
+}
+
+// evictionReason returns why a peer should be disconnected, or an empty string to keep it
+func (p *P2P) evictionReason(peer *Peer, height uint64, now time.Time) string {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	if peer.pingNonce != 0 && now.Sub(peer.pingSent) > p.pingTimeout() {
+		return fmt.Sprintf("no pong after %s", now.Sub(peer.pingSent).Round(time.Second))
+	}
+
+	// Inbound peers may be syncing from us; only outbound peers are expected to keep up
+	if peer.Inbound || p.isManualPeer(peer.Address) || peer.Height+maxPeerHeightLag >= height {
+		peer.behindSince = time.Time{}
+		return ""
+	}
+	if peer.behindSince.IsZero() {
+		peer.behindSince = now
+		return ""
+	}
+	if behind := now.Sub(peer.behindSince); behind > staleChainTimeout {
+		return fmt.Sprintf("height %d lags ours (%d) for %s", peer.Height, height, behind.Round(time.Second))
+	}
+	return ""
+}
+
+// noteHeight records that a peer has a block, raising its known height
+func (peer *Peer) noteHeight(number uint64, hash core.Hash) {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	if number > peer.Height {
+		peer.Height = number
+		peer.BestHash = hash
+	}
+}
+
+// isManualPeer reports whether an address was configured with -addnode or -connect
+func (p *P2P) isManualPeer(address string) bool {
+	for _, list := range [][]string{p.config.AddNodes, p.config.ConnectOnly} {
+		for _, manual := range list {
+			if manual == address {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// evictPeer disconnects a peer; its connection goroutine removes it
+func (p *P2P) evictPeer(peer *Peer, reason string) {
+	log.Printf("Evicting peer %s: %s", peer.ID, reason)
+	peer.Conn.Close()
+}
+
+// sendPing sends a ping with a fresh nonce and starts timing the round trip
+func (p *P2P) sendPing(peer *Peer) {
+	nonce := rand.Uint64()
+	for nonce == 0 {
+		nonce = rand.Uint64()
+	}
+
+	peer.mu.Lock()
+	peer.pingNonce = nonce
+	peer.pingSent = time.Now()
+	peer.mu.Unlock()
+
+	if err := p.sendMessage(peer, &PingData{Nonce: nonce}); err != nil {
+		log.Printf("Failed to send ping to peer %s: %v", peer.ID, err)
+	}
+}
+
+// pingInterval returns the configured ping interval, capped to a third of the read timeout
+func (p *P2P) pingInterval() time.Duration {
+	interval := DefaultPingInterval
+	if p.config.PingInterval > 0 {
+		interval = p.config.PingInterval
+	}
+	// Quiet peers must receive a ping and answer it well before their read deadline passes
+	if limit := p.config.ReadTimeout / 3; limit > 0 && interval > limit {
+		interval = limit
+	}
+	return interval
+}
+
+// pingTimeout returns the configured ping timeout
+func (p *P2P) pingTimeout() time.Duration {
+	if p.config.PingTimeout > 0 {
+		return p.config.PingTimeout
+	}
+	return DefaultPingTimeout
+}
@@ -0,0 +1,154 @@
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+// TestPingLatency tests that peers measure round-trip times and count traffic
+func TestPingLatency(t *testing.T) {
+	a := newTestP2P(t, core.NewBlockchainV2(newTestGenesis(), nil))
+	b := newTestP2P(t, core.NewBlockchainV2(newTestGenesis(), nil))
+	b.ConnectPeer(a.Addr().String())
+
+	if !waitFor(5*time.Second, func() bool {
+		for _, node := range []*P2P{a, b} {
+			peers := node.activePeers()
+			if len(peers) != 1 {
+				return false
+			}
+			peers[0].mu.RLock()
+			rtt := peers[0].PingRTT
+			peers[0].mu.RUnlock()
+			if rtt <= 0 {
+				return false
+			}
+		}
+		return true
+	}) {
+		t.Fatal("Expected both peers to record a ping round-trip time")
+	}
+
+	info := b.GetNetworkInfo()["peers"].([]map[string]interface{})[0]
+	if info["direction"] != "outbound" {
+		t.Errorf("Expected outbound direction, got %v", info["direction"])
+	}
+	if info["pingTime"].(float64) <= 0 || info["minPing"].(float64) <= 0 {
+		t.Errorf("Expected ping times, got %v and %v", info["pingTime"], info["minPing"])
+	}
+	if info["bytesSent"].(uint64) == 0 || info["bytesRecv"].(uint64) == 0 {
+		t.Errorf("Expected traffic counters, got %v sent and %v received", info["bytesSent"], info["bytesRecv"])
+	}
+	if info := a.GetNetworkInfo()["peers"].([]map[string]interface{})[0]; info["direction"] != "inbound" {
+		t.Errorf("Expected inbound direction, got %v", info["direction"])
+	}
+}
+
+// TestUnansweredPingEvictsPeer tests that a peer is disconnected when a ping goes unanswered
+func TestUnansweredPingEvictsPeer(t *testing.T) {
+	a := newTestP2P(t, core.NewBlockchainV2(newTestGenesis(), nil))
+	b := newTestP2P(t, core.NewBlockchainV2(newTestGenesis(), nil))
+	b.ConnectPeer(a.Addr().String())
+
+	if !waitFor(5*time.Second, func() bool { return len(a.activePeers()) == 1 }) {
+		t.Fatal("Expected handshake to complete")
+	}
+
+	// Pretend a ping is outstanding and the timeout has passed
+	peer := a.activePeers()[0]
+	peer.mu.Lock()
+	peer.pingNonce = 42
+	peer.pingSent = time.Now()
+	peer.mu.Unlock()
+	a.checkPeers(time.Now().Add(DefaultPingTimeout + time.Second))
+
+	if !waitFor(5*time.Second, func() bool { return a.GetPeerCount() == 0 && b.GetPeerCount() == 0 }) {
+		t.Error("Expected peer with an unanswered ping to be evicted")
+	}
+}
+
+// TestInactivePeerCleanup tests that a silent peer is disconnected and its sync requests released
+func TestInactivePeerCleanup(t *testing.T) {
+	a := newTestP2P(t, core.NewBlockchainV2(newTestGenesis(), nil))
+	b := newTestP2P(t, core.NewBlockchainV2(newTestGenesis(), nil))
+	b.ConnectPeer(a.Addr().String())
+
+	if !waitFor(5*time.Second, func() bool { return len(a.activePeers()) == 1 }) {
+		t.Fatal("Expected handshake to complete")
+	}
+
+	peer := a.activePeers()[0]
+	a.sync.mu.Lock()
+	a.sync.headerPeer = peer.ID
+	a.sync.mu.Unlock()
+	peer.mu.Lock()
+	peer.LastSeen = time.Now().Add(-3 * a.config.KeepAlive)
+	peer.mu.Unlock()
+	a.cleanupInactivePeers()
+
+	if !waitFor(5*time.Second, func() bool {
+		if a.GetPeerCount() != 0 {
+			return false
+		}
+		a.sync.mu.Lock()
+		defer a.sync.mu.Unlock()
+		return a.sync.headerPeer == ""
+	}) {
+		t.Error("Expected inactive peer to be removed along with its sync state")
+	}
+}
+
+// TestStalePeerEviction tests that only automatic outbound peers are evicted for lagging behind
+func TestStalePeerEviction(t *testing.T) {
+	p := NewP2P(&P2PConfig{AddNodes: []string{"10.0.0.2:17335"}})
+	now := time.Now()
+	const height = 500
+
+	lagging := &Peer{Address: "10.0.0.1:17335", Height: height - maxPeerHeightLag - 1}
+	if reason := p.evictionReason(lagging, height, now); reason != "" {
+		t.Fatalf("Expected lagging peer to get a grace period, got %q", reason)
+	}
+	if reason := p.evictionReason(lagging, height, now.Add(staleChainTimeout+time.Second)); reason == "" {
+		t.Error("Expected outbound peer lagging past the timeout to be evicted")
+	}
+
+	caughtUp := &Peer{Address: "10.0.0.3:17335", Height: height - maxPeerHeightLag - 1}
+	p.evictionReason(caughtUp, height, now)
+	caughtUp.noteHeight(height-1, core.Hash{1})
+	if reason := p.evictionReason(caughtUp, height, now.Add(staleChainTimeout+time.Second)); reason != "" {
+		t.Errorf("Expected peer that caught up to be kept, got %q", reason)
+	}
+
+	for _, peer := range []*Peer{
+		{Address: "10.0.0.4:17335", Inbound: true},
+		{Address: "10.0.0.2:17335"},
+	} {
+		p.evictionReason(peer, height, now)
+		if reason := p.evictionReason(peer, height, now.Add(staleChainTimeout+time.Second)); reason != "" {
+			t.Errorf("Expected inbound or added peer %s to be kept, got %q", peer.Address, reason)
+		}
+	}
+}
+
+// TestPingsKeepQuietPeersConnected tests that pings arrive often enough to keep idle peers within their read timeout
+func TestPingsKeepQuietPeersConnected(t *testing.T) {
+	shortTimeout := func(config *P2PConfig) { config.ReadTimeout = 2 * time.Second }
+	a := newTestP2PWithConfig(t, core.NewBlockchainV2(newTestGenesis(), nil), shortTimeout)
+	b := newTestP2PWithConfig(t, core.NewBlockchainV2(newTestGenesis(), nil), shortTimeout)
+	b.ConnectPeer(a.Addr().String())
+
+	if interval := a.pingInterval(); interval > time.Second {
+		t.Errorf("Expected ping interval below the read timeout, got %s", interval)
+	}
+	if !waitFor(5*time.Second, func() bool { return len(a.activePeers()) == 1 && len(b.activePeers()) == 1 }) {
+		t.Fatal("Expected handshake to complete")
+	}
+
+	// Nothing but pings is exchanged for longer than the read timeout
+	time.Sleep(5 * time.Second)
+	if len(a.activePeers()) != 1 || len(b.activePeers()) != 1 {
+		t.Errorf("Expected idle peers to stay connected, got %d and %d", len(a.activePeers()), len(b.activePeers()))
+	}
+}
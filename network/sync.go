@@ -0,0 +1,823 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+const (
+	// maxHeadersPerMessage is the most headers returned for one get_headers request
+	maxHeadersPerMessage = 500
+
+	// maxBlocksPerRequest is the most blocks asked for in one get_blocks request
+	maxBlocksPerRequest = 16
+
+	// maxRequestsPerPeer is the number of get_blocks batches in flight to one peer
+	maxRequestsPerPeer = 2
+
+	// downloadWindow is how many headers ahead of the tip are scheduled for download
+	downloadWindow = 1024
+
+	// syncRequestTimeout is how long a peer has to answer a headers or blocks request
+	syncRequestTimeout = 30 * time.Second
+
+	// syncInterval is how often idle nodes ask a peer for new headers
+	syncInterval = 10 * time.Second
+)
+
+// Chain is the blockchain the P2P layer serves blocks from and syncs into
+type Chain interface {
+	GetHeight() uint64
+	GetBestBlock() *core.Block
+	GetBlockByHash(hash core.Hash) *core.Block
+	GetBlockByNumber(number uint64) *core.Block
+	GetRules() core.Rules
+	AddBlockV2(block *core.Block) error
+	ReorganizeV2(blocks []*core.Block) error
+}
+
+// HeaderEntry is a block header together with its block hash
+type HeaderEntry struct {
+	Header core.BlockHeader `json:"header"`
+	Hash   core.Hash        `json:"hash"`
+}
+
+// GetHeadersData is the payload of a get_headers message
+type GetHeadersData struct {
+	Locator []core.Hash `json:"locator"` // Hashes of our chain from the tip back to genesis
+	Stop    core.Hash   `json:"stop"`    // Last header wanted (zero for as many as allowed)
+}
+
+// HeadersData is the payload of a headers message
+type HeadersData struct {
+	Headers []HeaderEntry `json:"headers"`
+}
+
+// GetBlocksData is the payload of a get_blocks message
+type GetBlocksData struct {
+	Hashes []core.Hash `json:"hashes"`
+}
+
+// BlocksData is the payload of a blocks message
+type BlocksData struct {
+	Blocks []*core.Block `json:"blocks"`
+}
+
+// SyncStatus reports initial block download progress
+type SyncStatus struct {
+	Syncing        bool    `json:"syncing"`
+	CurrentHeight  uint64  `json:"currentHeight"`
+	TargetHeight   uint64  `json:"targetHeight"`
+	HeadersPending int     `json:"headersPending"`
+	BlocksInFlight int     `json:"blocksInFlight"`
+	HeaderPeer     string  `json:"headerPeer,omitempty"`
+	Progress       float64 `json:"progress"`
+}
+
+// blockBatch is one get_blocks request sent to a peer
+type blockBatch struct {
+	hashes []core.Hash
+	sent   time.Time
+}
+
+// downloadedBlock is a block waiting to be applied, with the peer that sent it
+type downloadedBlock struct {
+	block  *core.Block
+	peerID string
+}
+
+// syncManager downloads the chain headers-first: headers are fetched from one
+// peer, then block bodies are requested in batches from every peer that has
+// them and applied to the chain strictly in header order.
+type syncManager struct {
+	p          *P2P
+	mu         sync.Mutex
+	applyMu    sync.Mutex // Serializes applying downloaded blocks to the chain
+	headers    []HeaderEntry
+	branch     string // Peer whose pending headers replace our blocks above their fork point
+	headerPeer string
+	headerSent time.Time
+	lastRound  time.Time
+	inFlight   map[core.Hash]string // Key: block hash, value: peer ID
+	received   map[core.Hash]*downloadedBlock
+	batches    map[string][]*blockBatch // Key: peer ID, oldest first
+}
+
+// newSyncManager creates a sync manager for a P2P network
+func newSyncManager(p *P2P) *syncManager {
+	return &syncManager{
+		p:        p,
+		inFlight: make(map[core.Hash]string),
+		received: make(map[core.Hash]*downloadedBlock),
+		batches:  make(map[string][]*blockBatch),
+	}
+}
+
+// run drives header rounds, request timeouts and download scheduling
+func (s *syncManager) run() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.p.stopChan:
+			return
+		case <-ticker.C:
+			s.tick()
+		}
+	}
+}
+
+// tick expires stale requests, starts a header round when idle and schedules downloads
+func (s *syncManager) tick() {
+	chain := s.p.getChain()
+	if chain == nil {
+		return
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	if s.headerPeer != "" && now.Sub(s.headerSent) > syncRequestTimeout {
+		log.Printf("Headers request to peer %s timed out", s.headerPeer)
+		s.headerPeer = ""
+	}
+	for peerID, batches := range s.batches {
+		for len(batches) > 0 && now.Sub(batches[0].sent) > syncRequestTimeout {
+			log.Printf("Blocks request to peer %s timed out", peerID)
+			s.releaseBatch(batches[0])
+			batches = batches[1:]
+		}
+		s.batches[peerID] = batches
+	}
+
+	startRound := s.headerPeer == "" && len(s.headers) == 0 && now.Sub(s.lastRound) >= syncInterval
+	s.mu.Unlock()
+
+	if startRound {
+		s.requestHeaders(chain)
+	}
+	s.schedule()
+}
+
+// wake makes the next tick start a header round immediately
+func (s *syncManager) wake() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastRound = time.Time{}
+}
+
+// requestHeaders asks the best known peer for headers following our chain
+func (s *syncManager) requestHeaders(chain Chain) {
+	peer := s.pickHeaderPeer(chain.GetHeight())
+	if peer == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.startHeaderRound(chain, peer)
+}
+
+// syncFrom starts a header round with a peer that announced a block we cannot link
+// to our chain. Its height is only raised once the headers it returns check out.
+func (s *syncManager) syncFrom(peer *Peer) {
+	chain := s.p.getChain()
+	if chain == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.headerPeer != "" || len(s.headers) > 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.startHeaderRound(chain, peer)
+}
+
+// hasHeader reports whether a header is pending download
+func (s *syncManager) hasHeader(hash core.Hash) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range s.headers {
+		if entry.Hash == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// startHeaderRound sends a get_headers request to a peer (caller holds s.mu, which is released)
+func (s *syncManager) startHeaderRound(chain Chain, peer *Peer) {
+	locator := s.locator(chain)
+	s.headerPeer = peer.ID
+	s.headerSent = time.Now()
+	s.lastRound = time.Now()
+	s.mu.Unlock()
+
+	s.sendGetHeaders(peer, locator)
+}
+
+// sendGetHeaders sends a get_headers message to a peer
+func (s *syncManager) sendGetHeaders(peer *Peer, locator []core.Hash) {
+	if err := s.p.sendMessage(peer, &GetHeadersData{Locator: locator}); err != nil {
+		log.Printf("Failed to request headers from peer %s: %v", peer.ID, err)
+	}
+}
+
+// pickHeaderPeer picks a random peer among those reporting the greatest height
+func (s *syncManager) pickHeaderPeer(ourHeight uint64) *Peer {
+	var best []*Peer
+	var bestHeight uint64
+	for _, peer := range s.p.activePeers() {
+		peer.mu.RLock()
+		height := peer.Height
+		peer.mu.RUnlock()
+
+		switch {
+		case len(best) == 0 || height > bestHeight:
+			best = []*Peer{peer}
+			bestHeight = height
+		case height == bestHeight:
+			best = append(best, peer)
+		}
+	}
+
+	// Peers that told us their height and are not ahead have nothing to offer
+	if len(best) == 0 || (bestHeight > 0 && bestHeight <= ourHeight) {
+		return nil
+	}
+	return best[rand.Intn(len(best))]
+}
+
+// locator returns block hashes from the download tip back to genesis, dense
+// near the tip and exponentially sparser further back (caller holds s.mu)
+func (s *syncManager) locator(chain Chain) []core.Hash {
+	var locator []core.Hash
+	if len(s.headers) > 0 {
+		locator = append(locator, s.headers[len(s.headers)-1].Hash)
+	}
+
+	height := chain.GetHeight()
+	step := uint64(1)
+	for {
+		block := chain.GetBlockByNumber(height)
+		if block != nil {
+			locator = append(locator, block.Hash)
+		}
+		if height == 0 {
+			break
+		}
+		if len(locator) >= 10 {
+			step *= 2
+		}
+		if height < step {
+			height = 0
+		} else {
+			height -= step
+		}
+	}
+
+	return locator
+}
+
+// handleGetHeaders answers a get_headers request from the first locator hash on our chain
+func (s *syncManager) handleGetHeaders(peer *Peer, data *GetHeadersData) {
+	chain := s.p.getChain()
+	if chain == nil {
+		return
+	}
+
+	// Headers start after the most recent block we share with the peer
+	start := uint64(1)
+	for _, hash := range data.Locator {
+		if block := chain.GetBlockByHash(hash); block != nil {
+			start = block.Header.Number + 1
+			peer.noteHeight(block.Header.Number, block.Hash)
+			break
+		}
+	}
+
+	headers := make([]HeaderEntry, 0)
+	for number := start; len(headers) < maxHeadersPerMessage; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			break
+		}
+		headers = append(headers, HeaderEntry{Header: block.Header, Hash: block.Hash})
+		if block.Hash == data.Stop {
+			break
+		}
+	}
+
+	if err := s.p.sendMessage(peer, &HeadersData{Headers: headers}); err != nil {
+		log.Printf("Failed to send headers to peer %s: %v", peer.ID, err)
+	}
+}
+
+// handleHeaders appends headers that extend our download tip and requests more if the batch was full
+func (s *syncManager) handleHeaders(peer *Peer, data *HeadersData) {
+	chain := s.p.getChain()
+	if chain == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if peer.ID != s.headerPeer {
+		s.mu.Unlock()
+		// Headers we did not ask for announce new blocks
+		s.p.handleHeaderAnnouncement(peer, data)
+		return
+	}
+	s.headerPeer = ""
+
+	if len(data.Headers) == 0 {
+		s.mu.Unlock()
+		return
+	}
+
+	// Headers must link to the last pending header or our best block
+	var prevHash core.Hash
+	var prevNumber uint64
+	if len(s.headers) > 0 {
+		last := s.headers[len(s.headers)-1]
+		prevHash, prevNumber = last.Hash, last.Header.Number
+	} else {
+		best := chain.GetBestBlock()
+		prevHash, prevNumber = best.Hash, best.Header.Number
+	}
+
+	// The peer starts after the last block of our locator on its chain, so headers that do not
+	// follow our tip fork off at that block and are a branch competing with ours
+	branch := false
+	if first := data.Headers[0].Header; len(s.headers) == 0 && first.ParentHash != prevHash {
+		if fork := chain.GetBlockByHash(first.ParentHash); fork != nil {
+			prevHash, prevNumber = fork.Hash, fork.Header.Number
+			branch = true
+		}
+	}
+
+	for i, entry := range data.Headers {
+		if entry.Header.ParentHash != prevHash || entry.Header.Number != prevNumber+1 {
+			s.mu.Unlock()
+			log.Printf("Headers from peer %s do not connect at index %d (height %d)", peer.ID, i, entry.Header.Number)
+			// The first header may follow a fork point, but a broken sequence is never valid
+			if i > 0 {
+				s.p.Misbehaving(peer.ID, 20, "non-continuous headers")
+			}
+			return
+		}
+		prevHash, prevNumber = entry.Hash, entry.Header.Number
+	}
+	if err := s.checkHeaders(chain, data.Headers); err != nil {
+		s.mu.Unlock()
+		log.Printf("Invalid headers from peer %s: %v", peer.ID, err)
+		s.p.Misbehaving(peer.ID, BanThreshold, fmt.Sprintf("invalid headers: %v", err))
+		return
+	}
+	if branch {
+		// Only a branch with more work than ours replaces our blocks; others are discarded
+		if err := compareBranchWork(chain, data.Headers); err != nil {
+			s.mu.Unlock()
+			log.Printf("Ignoring branch from peer %s: %v", peer.ID, err)
+			return
+		}
+		log.Printf("Peer %s has a branch with more work forking at height %d, switching to it", peer.ID, data.Headers[0].Header.Number-1)
+		s.branch = peer.ID
+	}
+	s.headers = append(s.headers, data.Headers...)
+
+	// A full batch means the peer has more headers for us; a branch is applied first
+	more := len(data.Headers) == maxHeadersPerMessage && !branch
+	var locator []core.Hash
+	if more {
+		locator = s.locator(chain)
+		s.headerPeer = peer.ID
+		s.headerSent = time.Now()
+	}
+	s.mu.Unlock()
+
+	peer.mu.Lock()
+	if prevNumber > peer.Height {
+		peer.Height = prevNumber
+	}
+	peer.mu.Unlock()
+
+	log.Printf("Received %d headers from peer %s (up to height %d)", len(data.Headers), peer.ID, prevNumber)
+
+	if more {
+		s.sendGetHeaders(peer, locator)
+	}
+	s.schedule()
+}
+
+// checkHeaders checks that each header hashes to its claimed hash and carries enough
+// proof of work and, once strict difficulty is active, the difficulty the rules require.
+// The headers extend our recent blocks followed by the pending headers (caller holds s.mu).
+func (s *syncManager) checkHeaders(chain Chain, headers []HeaderEntry) error {
+	rules := chain.GetRules()
+	span := int(rules.Params(headers[0].Header.Number).DifficultyWindow) + 1
+
+	pending := s.headers
+	if len(pending) > span {
+		pending = pending[len(pending)-span:]
+	}
+	recent := make([]*core.Block, 0, span+len(headers))
+	if len(pending) < span {
+		// Our blocks up to the first pending header, or to the parent of a branch.
+		// Blocks already applied may still be pending, so they are not taken twice.
+		newest := headers[0].Header.Number - 1
+		if len(pending) > 0 {
+			newest = pending[0].Header.Number - 1
+		}
+		count := uint64(span - len(pending))
+		oldest := uint64(0)
+		if newest+1 > count {
+			oldest = newest + 1 - count
+		}
+		for number := oldest; number <= newest; number++ {
+			if block := chain.GetBlockByNumber(number); block != nil {
+				recent = append(recent, block)
+			}
+		}
+	}
+	for _, entry := range pending {
+		recent = append(recent, &core.Block{Header: entry.Header, Hash: entry.Hash})
+	}
+
+	for _, entry := range headers {
+		block := &core.Block{Header: entry.Header, Hash: entry.Hash}
+		number := entry.Header.Number
+		if block.CalculateHash() != entry.Hash {
+			return fmt.Errorf("header #%d does not hash to %x", number, entry.Hash)
+		}
+		if rules.IsActive(core.UpgradeStrictDifficulty, number) {
+			if expected := rules.NextDifficulty(number, recent); entry.Header.Difficulty != expected {
+				return fmt.Errorf("header #%d has difficulty %d, expected %d", number, entry.Header.Difficulty, expected)
+			}
+		}
+		if !rules.CheckProofOfWork(block) {
+			return fmt.Errorf("header #%d has invalid proof of work", number)
+		}
+		recent = append(recent, block)
+	}
+	return nil
+}
+
+// schedule requests missing blocks in the download window from peers that have them
+func (s *syncManager) schedule() {
+	type assignment struct {
+		peer   *Peer
+		hashes []core.Hash
+	}
+
+	peers := s.p.activePeers()
+	heights := make(map[string]uint64, len(peers))
+	for _, peer := range peers {
+		peer.mu.RLock()
+		heights[peer.ID] = peer.Height
+		peer.mu.RUnlock()
+	}
+
+	s.mu.Lock()
+	var assignments []*assignment
+	current := make(map[string]*assignment)
+	next := 0
+
+	window := len(s.headers)
+	if window > downloadWindow {
+		window = downloadWindow
+	}
+	for _, entry := range s.headers[:window] {
+		if _, ok := s.inFlight[entry.Hash]; ok {
+			continue
+		}
+		if _, ok := s.received[entry.Hash]; ok {
+			continue
+		}
+
+		// Rotate through peers so the download is spread across all of them
+		var chosen *assignment
+		for tries := 0; tries < len(peers) && chosen == nil; tries++ {
+			peer := peers[next%len(peers)]
+			next++
+			if heights[peer.ID] < entry.Header.Number {
+				continue
+			}
+			a := current[peer.ID]
+			if a != nil && len(a.hashes) >= maxBlocksPerRequest {
+				a = nil
+			}
+			if a == nil {
+				if len(s.batches[peer.ID]) >= maxRequestsPerPeer {
+					continue
+				}
+				a = &assignment{peer: peer}
+				current[peer.ID] = a
+				assignments = append(assignments, a)
+				s.batches[peer.ID] = append(s.batches[peer.ID], &blockBatch{sent: time.Now()})
+			}
+			chosen = a
+		}
+		if chosen == nil {
+			break
+		}
+
+		chosen.hashes = append(chosen.hashes, entry.Hash)
+		s.inFlight[entry.Hash] = chosen.peer.ID
+		batches := s.batches[chosen.peer.ID]
+		batches[len(batches)-1].hashes = chosen.hashes
+	}
+	s.mu.Unlock()
+
+	for _, a := range assignments {
+		if err := s.p.sendMessage(a.peer, &GetBlocksData{Hashes: a.hashes}); err != nil {
+			log.Printf("Failed to request blocks from peer %s: %v", a.peer.ID, err)
+		}
+	}
+}
+
+// handleGetBlocks answers a get_blocks request with the requested blocks we have
+func (s *syncManager) handleGetBlocks(peer *Peer, data *GetBlocksData) {
+	chain := s.p.getChain()
+	if chain == nil {
+		return
+	}
+
+	blocks := make([]*core.Block, 0, len(data.Hashes))
+	size := 0
+	for i, hash := range data.Hashes {
+		if i >= maxBlocksPerRequest {
+			break
+		}
+		block := chain.GetBlockByHash(hash)
+		if block == nil {
+			continue
+		}
+
+		// Keep the response within the message size limit
+		blockSize := block.Size()
+		if len(blocks) > 0 && size+blockSize > s.p.config.MaxBlockBytes {
+			break
+		}
+		size += blockSize
+		blocks = append(blocks, block)
+	}
+
+	if err := s.p.sendMessage(peer, &BlocksData{Blocks: blocks}); err != nil {
+		log.Printf("Failed to send blocks to peer %s: %v", peer.ID, err)
+	}
+}
+
+// handleBlocks stores downloaded blocks and applies those that are next in line
+func (s *syncManager) handleBlocks(peer *Peer, data *BlocksData) {
+	s.mu.Lock()
+	batches := s.batches[peer.ID]
+	if len(batches) == 0 {
+		s.mu.Unlock()
+		log.Printf("Ignoring unsolicited blocks from peer %s", peer.ID)
+		return
+	}
+
+	// Peers answer requests in order, so this is the oldest outstanding batch
+	batch := batches[0]
+	s.batches[peer.ID] = batches[1:]
+
+	delivered := make(map[core.Hash]*core.Block, len(data.Blocks))
+	for _, block := range data.Blocks {
+		if block != nil {
+			delivered[block.Hash] = block
+		}
+	}
+	for _, hash := range batch.hashes {
+		if s.inFlight[hash] != peer.ID {
+			continue
+		}
+		delete(s.inFlight, hash)
+		if block, ok := delivered[hash]; ok {
+			s.received[hash] = &downloadedBlock{block: block, peerID: peer.ID}
+		}
+	}
+	s.mu.Unlock()
+
+	s.apply()
+	s.schedule()
+}
+
+// releaseBatch makes the blocks of an abandoned request schedulable again (caller holds s.mu)
+func (s *syncManager) releaseBatch(batch *blockBatch) {
+	for _, hash := range batch.hashes {
+		delete(s.inFlight, hash)
+	}
+}
+
+// apply adds downloaded blocks to the chain in header order
+func (s *syncManager) apply() {
+	chain := s.p.getChain()
+	if chain == nil {
+		return
+	}
+
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+
+	s.mu.Lock()
+	branch := s.branch
+	s.mu.Unlock()
+	if branch != "" {
+		s.applyBranch(chain, branch)
+		return
+	}
+
+	for {
+		s.mu.Lock()
+		if len(s.headers) == 0 {
+			s.mu.Unlock()
+			break
+		}
+		entry := s.headers[0]
+		downloaded, ok := s.received[entry.Hash]
+		if !ok {
+			s.mu.Unlock()
+			break
+		}
+		delete(s.received, entry.Hash)
+		s.mu.Unlock()
+		block := downloaded.block
+
+		// Relay may already have delivered this block
+		if chain.GetBlockByHash(entry.Hash) == nil {
+			// The block must be the one the header describes, not merely fill its slot.
+			// It stays pending, so it is requested again from another peer.
+			if !matchesHeader(chain.GetRules(), block, entry) {
+				log.Printf("Downloaded block %x does not match its header %x", block.Hash, entry.Hash)
+				s.p.Misbehaving(downloaded.peerID, BanThreshold, "block does not match its header")
+				return
+			}
+			if err := chain.AddBlockV2(block); err != nil {
+				log.Printf("Downloaded block #%d failed validation, restarting sync: %v", block.Header.Number, err)
+				// A block ahead of our clock is fetched again on a later round
+				if !errors.Is(err, core.ErrFutureBlock) {
+					s.p.Misbehaving(downloaded.peerID, BanThreshold, fmt.Sprintf("invalid block #%d: %v", block.Header.Number, err))
+				}
+				s.reset()
+				return
+			}
+		}
+
+		s.mu.Lock()
+		if len(s.headers) > 0 && s.headers[0].Hash == entry.Hash {
+			s.headers = s.headers[1:]
+		}
+		done := len(s.headers) == 0
+		s.mu.Unlock()
+
+		if done {
+			log.Printf("Block download complete at height %d", chain.GetHeight())
+			s.wake()
+		}
+	}
+}
+
+// applyBranch switches the chain to the pending branch once all its blocks are downloaded
+func (s *syncManager) applyBranch(chain Chain, peerID string) {
+	s.mu.Lock()
+	blocks := make([]*core.Block, 0, len(s.headers))
+	for _, entry := range s.headers {
+		downloaded, ok := s.received[entry.Hash]
+		if !ok {
+			s.mu.Unlock()
+			return
+		}
+		if !matchesHeader(chain.GetRules(), downloaded.block, entry) {
+			delete(s.received, entry.Hash)
+			s.mu.Unlock()
+			log.Printf("Downloaded block %x does not match its header %x", downloaded.block.Hash, entry.Hash)
+			s.p.Misbehaving(downloaded.peerID, BanThreshold, "block does not match its header")
+			return
+		}
+		blocks = append(blocks, downloaded.block)
+	}
+	s.mu.Unlock()
+
+	if err := chain.ReorganizeV2(blocks); err != nil {
+		log.Printf("Failed to switch to the branch from peer %s: %v", peerID, err)
+		if !errors.Is(err, core.ErrFutureBlock) {
+			s.p.Misbehaving(peerID, BanThreshold, fmt.Sprintf("invalid branch: %v", err))
+		}
+	} else {
+		log.Printf("Switched to the branch from peer %s at height %d", peerID, chain.GetHeight())
+	}
+	s.reset()
+	s.wake()
+}
+
+// matchesHeader reports whether a downloaded block is the one its header describes.
+// Once headers commit to transactions, the merkle root must match them as well.
+func matchesHeader(rules core.Rules, block *core.Block, entry HeaderEntry) bool {
+	if block.Hash != entry.Hash || block.CalculateHash() != entry.Hash {
+		return false
+	}
+	if rules.IsActive(core.UpgradeTxCommitments, entry.Header.Number) {
+		return core.CalculateMerkleRoot(block.Txs) == block.Header.MerkleRoot
+	}
+	return true
+}
+
+// compareBranchWork checks that a branch forking off our chain carries more work
+// than our blocks above the fork point, and that it does not fork too deep
+func compareBranchWork(chain Chain, headers []HeaderEntry) error {
+	forkNumber := headers[0].Header.Number - 1
+	height := chain.GetHeight()
+	if height-forkNumber > core.MaxReorgDepth {
+		return fmt.Errorf("fork at height %d is more than %d blocks deep", forkNumber, core.MaxReorgDepth)
+	}
+
+	ours := new(big.Int)
+	for number := forkNumber + 1; number <= height; number++ {
+		if block := chain.GetBlockByNumber(number); block != nil {
+			ours.Add(ours, core.BlockWork(block.Header.Difficulty))
+		}
+	}
+	theirs := new(big.Int)
+	for _, entry := range headers {
+		theirs.Add(theirs, core.BlockWork(entry.Header.Difficulty))
+	}
+	if theirs.Cmp(ours) <= 0 {
+		return fmt.Errorf("branch work %s does not exceed ours %s above height %d", theirs, ours, forkNumber)
+	}
+	return nil
+}
+
+// reset discards all pending headers and downloads
+func (s *syncManager) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.headers = nil
+	s.branch = ""
+	s.headerPeer = ""
+	s.inFlight = make(map[core.Hash]string)
+	s.received = make(map[core.Hash]*downloadedBlock)
+	s.batches = make(map[string][]*blockBatch)
+}
+
+// removePeer drops the requests outstanding to a disconnected peer
+func (s *syncManager) removePeer(peerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, batch := range s.batches[peerID] {
+		s.releaseBatch(batch)
+	}
+	delete(s.batches, peerID)
+	if s.headerPeer == peerID {
+		s.headerPeer = ""
+	}
+}
+
+// status returns the current sync progress
+func (s *syncManager) status() SyncStatus {
+	var height uint64
+	if chain := s.p.getChain(); chain != nil {
+		height = chain.GetHeight()
+	}
+
+	// Peer heights are read before taking s.mu to keep the lock order of addPeer/removePeer
+	target := height
+	for _, peer := range s.p.activePeers() {
+		peer.mu.RLock()
+		if peer.Height > target {
+			target = peer.Height
+		}
+		peer.mu.RUnlock()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.headers) > 0 && s.headers[len(s.headers)-1].Header.Number > target {
+		target = s.headers[len(s.headers)-1].Header.Number
+	}
+
+	progress := 1.0
+	if target > 0 {
+		progress = float64(height) / float64(target)
+	}
+
+	return SyncStatus{
+		Syncing:        height < target,
+		CurrentHeight:  height,
+		TargetHeight:   target,
+		HeadersPending: len(s.headers),
+		BlocksInFlight: len(s.inFlight),
+		HeaderPeer:     s.headerPeer,
+		Progress:       progress,
+	}
+}
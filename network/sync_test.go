@@ -0,0 +1,171 @@
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+// newTestGenesis returns a genesis with proof of work disabled for fast block creation
+func newTestGenesis() *core.GenesisConfig {
+	return &core.GenesisConfig{
+		ChainID:            7718,
+		BlockTimeTarget:    15,
+		InitialBlockReward: 5.0,
+		Difficulty: core.DifficultyConfig{
+			Window:            120,
+			InitialDifficulty: 1,
+		},
+	}
+}
+
+// newTestP2P starts a P2P network on a loopback port serving the given chain
+func newTestP2P(t *testing.T, chain Chain) *P2P {
+	t.Helper()
+	return newTestP2PWithChainID(t, chain, 7718)
+}
+
+// newTestP2PWithChainID starts a P2P network on a loopback port for a specific chain ID
+func newTestP2PWithChainID(t *testing.T, chain Chain, chainID uint64) *P2P {
+	t.Helper()
+	return newTestP2PWithConfig(t, chain, func(config *P2PConfig) { config.ChainID = chainID })
+}
+
+// newTestP2PWithConfig starts a P2P network on a loopback port after applying configure to the test config
+func newTestP2PWithConfig(t *testing.T, chain Chain, configure func(*P2PConfig)) *P2P {
+	t.Helper()
+
+	config := &P2PConfig{
+		ListenAddr:    "127.0.0.1:0",
+		MaxPeers:      8,
+		DialTimeout:   5 * time.Second,
+		ReadTimeout:   time.Minute,
+		WriteTimeout:  10 * time.Second,
+		KeepAlive:     time.Minute,
+		MaxBlockBytes: int(core.DefaultMaxBlockBytes),
+		MaxTxBytes:    int(core.DefaultMaxTxBytes),
+		ChainID:       7718,
+	}
+	configure(config)
+
+	p := NewP2P(config)
+	p.SetChain(chain)
+	if err := p.Start(); err != nil {
+		t.Fatalf("Failed to start P2P: %v", err)
+	}
+	t.Cleanup(p.Stop)
+	return p
+}
+
+// TestInitialBlockDownload syncs a fresh node from a peer over loopback
+func TestInitialBlockDownload(t *testing.T) {
+	const blocks = 120
+
+	source := core.NewBlockchainV2(newTestGenesis(), nil)
+	for i := 0; i < blocks; i++ {
+		block := source.CreateNewBlockV2(core.Address{1}, nil)
+		if err := source.AddBlockV2(block); err != nil {
+			t.Fatalf("Failed to add block %d: %v", i+1, err)
+		}
+	}
+
+	fresh := core.NewBlockchainV2(newTestGenesis(), nil)
+
+	sourceP2P := newTestP2P(t, source)
+	freshP2P := newTestP2P(t, fresh)
+	freshP2P.ConnectPeer(sourceP2P.Addr().String())
+
+	deadline := time.Now().Add(20 * time.Second)
+	for fresh.GetHeight() < blocks && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if fresh.GetHeight() != blocks {
+		t.Fatalf("Expected synced height %d, got %d (status %+v)", blocks, fresh.GetHeight(), freshP2P.GetSyncStatus())
+	}
+	if fresh.GetBestBlock().Hash != source.GetBestBlock().Hash {
+		t.Error("Expected synced best block to match the source")
+	}
+
+	status := freshP2P.GetSyncStatus()
+	if status.Syncing || status.CurrentHeight != blocks || status.HeadersPending != 0 || status.BlocksInFlight != 0 {
+		t.Errorf("Unexpected sync status after download: %+v", status)
+	}
+
+	// The source has nothing to download from the fresh node
+	if status := sourceP2P.GetSyncStatus(); status.Syncing {
+		t.Errorf("Expected source to report not syncing: %+v", status)
+	}
+}
+
+// TestSyncSwitchesToHeavierBranch tests that a node on a branch with less work
+// switches to the branch of a peer that forked off its chain
+func TestSyncSwitchesToHeavierBranch(t *testing.T) {
+	local := core.NewBlockchainV2(newTestGenesis(), nil)
+	remote := core.NewBlockchainV2(newTestGenesis(), nil)
+
+	fork := local.CreateNewBlockV2(core.Address{1}, nil)
+	for _, chain := range []*core.BlockchainV2{local, remote} {
+		if err := chain.AddBlockV2(fork); err != nil {
+			t.Fatalf("Failed to add fork block: %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := local.AddBlockV2(local.CreateNewBlockV2(core.Address{2}, nil)); err != nil {
+			t.Fatalf("Failed to add local block: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if err := remote.AddBlockV2(remote.CreateNewBlockV2(core.Address{3}, nil)); err != nil {
+			t.Fatalf("Failed to add remote block: %v", err)
+		}
+	}
+
+	localP2P := newTestP2P(t, local)
+	remoteP2P := newTestP2P(t, remote)
+	localP2P.ConnectPeer(remoteP2P.Addr().String())
+
+	if !waitFor(10*time.Second, func() bool { return local.GetBestBlock().Hash == remote.GetBestBlock().Hash }) {
+		t.Fatalf("Expected local node to switch to the remote branch, at height %d (status %+v)", local.GetHeight(), localP2P.GetSyncStatus())
+	}
+	if local.GetBalance(core.Address{2}) != 0 {
+		t.Error("Expected rewards of the replaced blocks to be undone")
+	}
+}
+
+// TestCheckHeaders tests that headers must hash to their claimed hash and carry the required difficulty
+func TestCheckHeaders(t *testing.T) {
+	genesis := newTestGenesis()
+	genesis.Upgrades = []core.NetworkUpgrade{{Name: core.UpgradeStrictDifficulty, Height: 0}}
+
+	source := core.NewBlockchainV2(genesis, nil)
+	var headers []HeaderEntry
+	for i := 0; i < 3; i++ {
+		block := source.CreateNewBlockV2(core.Address{1}, nil)
+		if err := source.AddBlockV2(block); err != nil {
+			t.Fatalf("Failed to add block %d: %v", i+1, err)
+		}
+		headers = append(headers, HeaderEntry{Header: block.Header, Hash: block.Hash})
+	}
+
+	fresh := core.NewBlockchainV2(genesis, nil)
+	s := &syncManager{}
+	if err := s.checkHeaders(fresh, headers); err != nil {
+		t.Fatalf("Expected valid headers to be accepted: %v", err)
+	}
+
+	// A claimed hash with leading zeros does not stand in for proof of work
+	forged := append([]HeaderEntry(nil), headers...)
+	forged[1].Hash = core.Hash{31: 1}
+	if err := s.checkHeaders(fresh, forged); err == nil {
+		t.Error("Expected a header with a forged hash to be rejected")
+	}
+
+	easy := append([]HeaderEntry(nil), headers...)
+	easy[2].Header.Difficulty = 2
+	easy[2].Hash = (&core.Block{Header: easy[2].Header}).CalculateHash()
+	if err := s.checkHeaders(fresh, easy); err == nil {
+		t.Error("Expected a header with the wrong difficulty to be rejected")
+	}
+}
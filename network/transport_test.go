@@ -0,0 +1,132 @@
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+// newEncryptedTestP2P starts a P2P network using the encrypted transport
+func newEncryptedTestP2P(t *testing.T, allowed ...string) *P2P {
+	t.Helper()
+	return newTestP2PWithConfig(t, core.NewBlockchainV2(newTestGenesis(), nil), func(config *P2PConfig) {
+		config.Encrypt = true
+		config.AllowedPeers = allowed
+	})
+}
+
+// TestEncryptedHandshake tests that encrypted peers identify each other by node ID
+func TestEncryptedHandshake(t *testing.T) {
+	a := newEncryptedTestP2P(t)
+	b := newEncryptedTestP2P(t)
+	b.ConnectPeer(a.Addr().String())
+
+	if !waitFor(5*time.Second, func() bool { return len(a.activePeers()) == 1 && len(b.activePeers()) == 1 }) {
+		t.Fatal("Expected encrypted handshake to complete on both sides")
+	}
+
+	if peer := a.activePeers()[0]; peer.ID != b.NodeID() || peer.NodeID != b.NodeID() {
+		t.Errorf("Expected inbound peer ID %s, got %s", b.NodeID(), peer.ID)
+	}
+	if peer := b.activePeers()[0]; peer.ID != a.NodeID() || peer.NodeID != a.NodeID() {
+		t.Errorf("Expected outbound peer ID %s, got %s", a.NodeID(), peer.ID)
+	}
+}
+
+// TestEncryptedTransportRejectsPlaintext tests that plaintext and encrypted nodes do not connect
+func TestEncryptedTransportRejectsPlaintext(t *testing.T) {
+	encrypted := newEncryptedTestP2P(t)
+	plain := newTestP2P(t, core.NewBlockchainV2(newTestGenesis(), nil))
+
+	plain.ConnectPeer(encrypted.Addr().String())
+	encrypted.ConnectPeer(plain.Addr().String())
+
+	if waitFor(time.Second, func() bool { return len(encrypted.activePeers()) > 0 || len(plain.activePeers()) > 0 }) {
+		t.Error("Expected no handshake between plaintext and encrypted nodes")
+	}
+	if !waitFor(10*time.Second, func() bool { return encrypted.GetPeerCount() == 0 && plain.GetPeerCount() == 0 }) {
+		t.Error("Expected mismatched connections to be dropped")
+	}
+}
+
+// TestAllowedPeers tests that only node IDs in the allow-list can connect
+func TestAllowedPeers(t *testing.T) {
+	trusted := newEncryptedTestP2P(t)
+	stranger := newEncryptedTestP2P(t)
+	guarded := newEncryptedTestP2P(t, trusted.NodeID())
+
+	stranger.ConnectPeer(guarded.Addr().String())
+	if waitFor(time.Second, func() bool { return len(guarded.activePeers()) > 0 }) {
+		t.Fatal("Expected node outside the allow-list to be rejected")
+	}
+
+	trusted.ConnectPeer(guarded.Addr().String())
+	if !waitFor(5*time.Second, func() bool { return len(guarded.activePeers()) == 1 }) {
+		t.Fatal("Expected allowed node to connect")
+	}
+	if peer := guarded.activePeers()[0]; peer.NodeID != trusted.NodeID() {
+		t.Errorf("Expected peer %s, got %s", trusted.NodeID(), peer.NodeID)
+	}
+
+	// The allow-list applies to outbound connections too
+	guarded.ConnectPeer(stranger.Addr().String())
+	if waitFor(time.Second, func() bool { return len(guarded.activePeers()) > 1 }) {
+		t.Error("Expected outbound connection to a node outside the allow-list to fail")
+	}
+}
+
+// TestNodeKeyPersistence tests that the node ID survives a restart with the same data directory
+func TestNodeKeyPersistence(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := LoadOrCreateNodeKey(dir)
+	if err != nil {
+		t.Fatalf("Failed to create node key: %v", err)
+	}
+	second, err := LoadOrCreateNodeKey(dir)
+	if err != nil {
+		t.Fatalf("Failed to load node key: %v", err)
+	}
+	if NodeIDFromPubKey(first.Public) != NodeIDFromPubKey(second.Public) {
+		t.Error("Expected the same node ID after reloading the key")
+	}
+
+	other, err := LoadOrCreateNodeKey(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create node key: %v", err)
+	}
+	if NodeIDFromPubKey(first.Public) == NodeIDFromPubKey(other.Public) {
+		t.Error("Expected different data directories to get different node IDs")
+	}
+}
+
+// TestNetworkInfoDuringStart tests that network info can be read while Start sets the node identity
+func TestNetworkInfoDuringStart(t *testing.T) {
+	p := NewP2P(&P2PConfig{
+		ListenAddr:  "127.0.0.1:0",
+		MaxPeers:    8,
+		DialTimeout: 5 * time.Second,
+		KeepAlive:   time.Minute,
+		ChainID:     7718,
+		Encrypt:     true,
+	})
+	p.SetChain(core.NewBlockchainV2(newTestGenesis(), nil))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			p.GetNetworkInfo()
+		}
+	}()
+	if err := p.Start(); err != nil {
+		t.Fatalf("Failed to start P2P: %v", err)
+	}
+	defer p.Stop()
+	<-done
+
+	if info := p.GetNetworkInfo(); info["nodeId"] != p.NodeID() || info["encrypted"] != true {
+		t.Errorf("Expected the node identity once started, got %v and %v", info["nodeId"], info["encrypted"])
+	}
+}
@@ -0,0 +1,259 @@
+package network
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+// Every message is framed as
+//
+//	magic    [4]byte   network magic derived from the chain ID
+//	command  [12]byte  ASCII command name, NUL padded
+//	length   uint32    payload length, little endian
+//	checksum [4]byte   first four bytes of sha256(sha256(payload))
+//	payload  []byte    JSON encoding of the command's payload struct
+const (
+	// CommandSize is the fixed width of the command field
+	CommandSize = 12
+
+	// MessageHeaderSize is the size of the frame header preceding every payload
+	MessageHeaderSize = 4 + CommandSize + 4 + 4
+)
+
+// Message commands
+const (
+	CmdVersion     = "version"
+	CmdVerack      = "verack"
+	CmdPing        = "ping"
+	CmdPong        = "pong"
+	CmdBlock       = "block"
+	CmdTransaction = "transaction"
+	CmdGetHeaders  = "get_headers"
+	CmdHeaders     = "headers"
+	CmdGetBlocks   = "get_blocks"
+	CmdBlocks      = "blocks"
+	CmdInv         = "inv"
+	CmdGetData     = "getdata"
+	CmdNotFound    = "notfound"
+	CmdGetAddr     = "getaddr"
+	CmdAddr        = "addr"
+)
+
+var (
+	// errMessageTooLarge is returned when a peer sends a message above the size limit
+	errMessageTooLarge = errors.New("message exceeds maximum size")
+
+	// errBadMagic is returned for frames from another network or a desynchronized stream
+	errBadMagic = errors.New("invalid network magic")
+
+	// errBadChecksum is returned when a payload does not match its checksum
+	errBadChecksum = errors.New("payload checksum mismatch")
+
+	// errUnknownCommand is returned when decoding a payload for a command we do not know
+	errUnknownCommand = errors.New("unknown command")
+)
+
+// Payload is the typed body of a P2P message
+type Payload interface {
+	Command() string
+}
+
+// VerackData is the payload of a verack message
+type VerackData struct{}
+
+// PingData is the payload of a ping message
+type PingData struct {
+	Nonce uint64 `json:"nonce"`
+}
+
+// PongData is the payload of a pong message, echoing the ping nonce
+type PongData struct {
+	Nonce uint64 `json:"nonce"`
+}
+
+// BlockData is the payload of a block message
+type BlockData struct {
+	Block *core.Block `json:"block"`
+}
+
+// TransactionData is the payload of a transaction message
+type TransactionData struct {
+	Tx *core.Transaction `json:"tx"`
+}
+
+// GetAddrData is the payload of a getaddr message
+type GetAddrData struct{}
+
+func (*VersionData) Command() string     { return CmdVersion }
+func (*VerackData) Command() string      { return CmdVerack }
+func (*PingData) Command() string        { return CmdPing }
+func (*PongData) Command() string        { return CmdPong }
+func (*BlockData) Command() string       { return CmdBlock }
+func (*TransactionData) Command() string { return CmdTransaction }
+func (*GetHeadersData) Command() string  { return CmdGetHeaders }
+func (*HeadersData) Command() string     { return CmdHeaders }
+func (*GetBlocksData) Command() string   { return CmdGetBlocks }
+func (*BlocksData) Command() string      { return CmdBlocks }
+func (*GetAddrData) Command() string     { return CmdGetAddr }
+func (*AddrData) Command() string        { return CmdAddr }
+func (*InvData) Command() string         { return CmdInv }
+func (*GetDataData) Command() string     { return CmdGetData }
+func (*NotFoundData) Command() string    { return CmdNotFound }
+
+// newPayload returns an empty payload struct for a command
+func newPayload(command string) (Payload, error) {
+	switch command {
+	case CmdVersion:
+		return &VersionData{}, nil
+	case CmdVerack:
+		return &VerackData{}, nil
+	case CmdPing:
+		return &PingData{}, nil
+	case CmdPong:
+		return &PongData{}, nil
+	case CmdBlock:
+		return &BlockData{}, nil
+	case CmdTransaction:
+		return &TransactionData{}, nil
+	case CmdGetHeaders:
+		return &GetHeadersData{}, nil
+	case CmdHeaders:
+		return &HeadersData{}, nil
+	case CmdGetBlocks:
+		return &GetBlocksData{}, nil
+	case CmdBlocks:
+		return &BlocksData{}, nil
+	case CmdInv:
+		return &InvData{}, nil
+	case CmdGetData:
+		return &GetDataData{}, nil
+	case CmdNotFound:
+		return &NotFoundData{}, nil
+	case CmdGetAddr:
+		return &GetAddrData{}, nil
+	case CmdAddr:
+		return &AddrData{}, nil
+	}
+	return nil, errUnknownCommand
+}
+
+// NetworkMagic returns the frame magic for a chain, so nodes of different
+// networks reject each other's traffic before parsing it
+func NetworkMagic(chainID uint64) [4]byte {
+	var buf [13]byte
+	copy(buf[:], "kalon")
+	binary.BigEndian.PutUint64(buf[5:], chainID)
+	sum := sha256.Sum256(buf[:])
+
+	var magic [4]byte
+	copy(magic[:], sum[:4])
+	return magic
+}
+
+// payloadChecksum returns the first four bytes of the double SHA-256 of a payload
+func payloadChecksum(payload []byte) [4]byte {
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+
+	var checksum [4]byte
+	copy(checksum[:], second[:4])
+	return checksum
+}
+
+// EncodeMessage frames a payload for the network with the given magic
+func EncodeMessage(magic [4]byte, payload Payload) ([]byte, error) {
+	command := payload.Command()
+	if len(command) == 0 || len(command) > CommandSize {
+		return nil, fmt.Errorf("invalid command %q", command)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s payload: %v", command, err)
+	}
+
+	frame := make([]byte, MessageHeaderSize, MessageHeaderSize+len(data))
+	copy(frame[0:4], magic[:])
+	copy(frame[4:4+CommandSize], command)
+	binary.LittleEndian.PutUint32(frame[16:20], uint32(len(data)))
+	checksum := payloadChecksum(data)
+	copy(frame[20:24], checksum[:])
+	return append(frame, data...), nil
+}
+
+// ReadMessage reads one frame and returns its command and verified payload bytes.
+// Errors mean the stream can no longer be trusted to be aligned on frames.
+func ReadMessage(r io.Reader, magic [4]byte, maxSize int) (string, []byte, error) {
+	var header [MessageHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return "", nil, err
+	}
+
+	if !bytes.Equal(header[0:4], magic[:]) {
+		return "", nil, errBadMagic
+	}
+
+	command, err := parseCommand(header[4 : 4+CommandSize])
+	if err != nil {
+		return "", nil, err
+	}
+
+	length := binary.LittleEndian.Uint32(header[16:20])
+	if maxSize < 0 || uint64(length) > uint64(maxSize) {
+		return "", nil, errMessageTooLarge
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return "", nil, err
+	}
+
+	checksum := payloadChecksum(payload)
+	if !bytes.Equal(header[20:24], checksum[:]) {
+		return "", nil, errBadChecksum
+	}
+
+	return command, payload, nil
+}
+
+// parseCommand extracts a NUL-padded printable ASCII command name
+func parseCommand(field []byte) (string, error) {
+	end := bytes.IndexByte(field, 0)
+	if end < 0 {
+		end = len(field)
+	}
+	if end == 0 {
+		return "", fmt.Errorf("empty command")
+	}
+	for _, c := range field[end:] {
+		if c != 0 {
+			return "", fmt.Errorf("command is not NUL padded")
+		}
+	}
+	for _, c := range field[:end] {
+		if c < 0x21 || c > 0x7e {
+			return "", fmt.Errorf("command contains invalid byte 0x%02x", c)
+		}
+	}
+	return string(field[:end]), nil
+}
+
+// DecodePayload parses the payload of a command into its typed struct
+func DecodePayload(command string, data []byte) (Payload, error) {
+	payload, err := newPayload(command)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %v", command, err)
+	}
+	return payload, nil
+}
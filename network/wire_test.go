@@ -0,0 +1,122 @@
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+// rawFrame builds a frame around arbitrary payload bytes
+func rawFrame(magic [4]byte, command string, payload []byte) []byte {
+	frame := make([]byte, MessageHeaderSize, MessageHeaderSize+len(payload))
+	copy(frame[0:4], magic[:])
+	copy(frame[4:4+CommandSize], command)
+	binary.LittleEndian.PutUint32(frame[16:20], uint32(len(payload)))
+	checksum := payloadChecksum(payload)
+	copy(frame[20:24], checksum[:])
+	return append(frame, payload...)
+}
+
+// TestMessageFraming tests encoding and reading frames, including the ways a frame is rejected
+func TestMessageFraming(t *testing.T) {
+	magic := NetworkMagic(7718)
+	if magic == NetworkMagic(7719) {
+		t.Fatal("Expected different chains to use different magic")
+	}
+
+	sent := &HeadersData{Headers: []HeaderEntry{{Header: core.BlockHeader{Number: 42}, Hash: core.Hash{1, 2, 3}}}}
+	frame, err := EncodeMessage(magic, sent)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+
+	command, data, err := ReadMessage(bytes.NewReader(frame), magic, 1<<20)
+	if err != nil || command != CmdHeaders {
+		t.Fatalf("Failed to read frame: %q %v", command, err)
+	}
+	payload, err := DecodePayload(command, data)
+	if err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	received, ok := payload.(*HeadersData)
+	if !ok || len(received.Headers) != 1 || received.Headers[0].Header.Number != 42 || received.Headers[0].Hash != sent.Headers[0].Hash {
+		t.Fatalf("Unexpected payload after round trip: %+v", payload)
+	}
+
+	// Large integers survive without float rounding
+	version, _ := EncodeMessage(magic, &VersionData{Nonce: 1<<63 + 1})
+	_, data, _ = ReadMessage(bytes.NewReader(version), magic, 1<<20)
+	if payload, err := DecodePayload(CmdVersion, data); err != nil || payload.(*VersionData).Nonce != 1<<63+1 {
+		t.Errorf("Expected exact nonce, got %+v %v", payload, err)
+	}
+
+	if _, _, err := ReadMessage(bytes.NewReader(frame), NetworkMagic(1), 1<<20); err != errBadMagic {
+		t.Errorf("Expected bad magic error, got %v", err)
+	}
+	if _, _, err := ReadMessage(bytes.NewReader(frame), magic, len(frame)-MessageHeaderSize-1); err != errMessageTooLarge {
+		t.Errorf("Expected size error, got %v", err)
+	}
+
+	corrupted := append([]byte(nil), frame...)
+	corrupted[len(corrupted)-2] ^= 0xff
+	if _, _, err := ReadMessage(bytes.NewReader(corrupted), magic, 1<<20); err != errBadChecksum {
+		t.Errorf("Expected checksum error, got %v", err)
+	}
+
+	if _, _, err := ReadMessage(bytes.NewReader(rawFrame(magic, "bad\x00cmd", nil)), magic, 1<<20); err == nil {
+		t.Error("Expected command with bytes after the padding to be rejected")
+	}
+	if _, err := DecodePayload("nosuchcmd", []byte("{}")); err != errUnknownCommand {
+		t.Errorf("Expected unknown command error, got %v", err)
+	}
+}
+
+// FuzzReadMessage checks that arbitrary input never crashes the frame reader or payload decoder
+func FuzzReadMessage(f *testing.F) {
+	magic := NetworkMagic(7718)
+	for _, payload := range []Payload{
+		&VersionData{ProtocolVersion: ProtocolVersion, ChainID: 7718, UserAgent: DefaultUserAgent},
+		&VerackData{},
+		&PingData{Nonce: 7},
+		&InvData{Items: []InvVector{{Type: InvTypeTx, Hash: core.Hash{1}}}},
+		&AddrData{Addresses: []string{"127.0.0.1:17335"}},
+		&BlocksData{Blocks: []*core.Block{{Hash: core.Hash{2}}}},
+	} {
+		frame, err := EncodeMessage(magic, payload)
+		if err != nil {
+			f.Fatalf("Failed to encode seed: %v", err)
+		}
+		f.Add(frame)
+	}
+	f.Add(rawFrame(magic, CmdBlock, []byte(`{"block":{"header":`)))
+	f.Add(rawFrame(magic, CmdTransaction, []byte(`null`)))
+	f.Add([]byte("not a frame\n"))
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		reader := bytes.NewReader(input)
+		for {
+			command, data, err := ReadMessage(reader, magic, 1<<16)
+			if err != nil {
+				return
+			}
+			if len(command) == 0 || len(command) > CommandSize {
+				t.Fatalf("Read invalid command %q", command)
+			}
+
+			payload, err := DecodePayload(command, data)
+			if err != nil {
+				continue
+			}
+			if payload.Command() != command {
+				t.Fatalf("Decoded %s payload for command %s", payload.Command(), command)
+			}
+
+			// Anything decoded must encode again
+			if _, err := EncodeMessage(magic, payload); err != nil {
+				t.Fatalf("Failed to re-encode %s: %v", command, err)
+			}
+		}
+	})
+}
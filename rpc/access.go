@@ -0,0 +1,349 @@
+package rpc
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Defaults of AccessConfig
+const (
+	DefaultRateLimit     = 600 // Cost units a client may spend per minute
+	DefaultMaxConcurrent = 50  // Requests served at the same time
+)
+
+// AccessConfig configures which clients may use the RPC server and how much they may ask of it
+type AccessConfig struct {
+	AllowedNets    []string // IPs or CIDR ranges allowed to connect; empty allows every client
+	TrustedProxies []string // IPs or CIDR ranges whose X-Forwarded-For and X-Real-Ip headers are believed
+	AllowedOrigins []string // Web origins, such as https://explorer.example.com, whose pages may open WebSockets; "*" allows any
+
+	RateLimit     int            // Cost units a client may spend per minute, DefaultRateLimit if zero; negative disables rate limits
+	LimitLoopback bool           // Rate limit loopback and Unix socket clients too, which are exempt otherwise
+	MethodCosts   map[string]int // Cost of a call by method; methods not listed cost 1
+
+	MaxConcurrent int // Requests served at the same time, DefaultMaxConcurrent if zero
+}
+
+// accessControl is a checked AccessConfig
+type accessControl struct {
+	allowed       []*net.IPNet
+	trusted       []*net.IPNet
+	origins       map[string]bool // "*" allows any origin
+	rateLimit     int             // Zero when rate limits are disabled
+	limitLoopback bool
+	methodCosts   map[string]int
+	maxConcurrent int
+}
+
+// caller is who sent a call: the client charged for it and the role it authenticated as
+type caller struct {
+	ip      string
+	role    *role
+	batched bool // The call is part of a batch
+}
+
+// newAccessControl checks an AccessConfig and applies its defaults
+func newAccessControl(config AccessConfig) (*accessControl, error) {
+	allowed, err := parseNets(config.AllowedNets)
+	if err != nil {
+		return nil, fmt.Errorf("invalid allowed network: %v", err)
+	}
+	trusted, err := parseNets(config.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxy: %v", err)
+	}
+	origins, err := parseOrigins(config.AllowedOrigins)
+	if err != nil {
+		return nil, fmt.Errorf("invalid allowed origin: %v", err)
+	}
+
+	a := &accessControl{
+		allowed:       allowed,
+		trusted:       trusted,
+		origins:       origins,
+		rateLimit:     config.RateLimit,
+		limitLoopback: config.LimitLoopback,
+		methodCosts:   make(map[string]int),
+		maxConcurrent: config.MaxConcurrent,
+	}
+	switch {
+	case a.rateLimit == 0:
+		a.rateLimit = DefaultRateLimit
+	case a.rateLimit < 0:
+		a.rateLimit = 0
+	}
+	switch {
+	case a.maxConcurrent == 0:
+		a.maxConcurrent = DefaultMaxConcurrent
+	case a.maxConcurrent < 0:
+		return nil, fmt.Errorf("invalid maximum of concurrent requests %d", a.maxConcurrent)
+	}
+
+	for method, cost := range config.MethodCosts {
+		if _, known := methodParams[method]; !known {
+			return nil, fmt.Errorf("cost of unknown method %q", method)
+		}
+		if cost < 0 {
+			return nil, fmt.Errorf("invalid cost %d of %s", cost, method)
+		}
+		a.methodCosts[method] = cost
+	}
+	return a, nil
+}
+
+// parseNets parses IPs and CIDR ranges; a single IP matches only itself
+func parseNets(list []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(list))
+	for _, entry := range list {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("%q is not an IP or CIDR range", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an IP or CIDR range", entry)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// parseOrigins parses web origins into the scheme://host form browsers send
+func parseOrigins(list []string) (map[string]bool, error) {
+	origins := make(map[string]bool, len(list))
+	for _, entry := range list {
+		entry = strings.TrimSpace(entry)
+		if entry == "*" {
+			origins[entry] = true
+			continue
+		}
+		u, err := url.Parse(entry)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+			return nil, fmt.Errorf("%q is not an origin like https://example.com", entry)
+		}
+		origins[strings.ToLower(u.Scheme+"://"+u.Host)] = true
+	}
+	return origins, nil
+}
+
+// containsIP reports whether an address is in any of the networks
+func containsIP(nets []*net.IPNet, address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatNets lists networks in CIDR notation
+func formatNets(nets []*net.IPNet) []string {
+	list := make([]string, len(nets))
+	for i, ipNet := range nets {
+		list[i] = ipNet.String()
+	}
+	return list
+}
+
+// clientAllowed reports whether a client address may use the server
+func (s *ServerV2) clientAllowed(ip string) bool {
+	return len(s.access.allowed) == 0 || ip == unixSocketClient || containsIP(s.access.allowed, ip)
+}
+
+// originAllowed reports whether a WebSocket upgrade may proceed. Browsers send the
+// origin of the page opening the connection; other clients send none. Pages from
+// other sites are refused unless allowed, so they cannot use a browser's access to
+// the node, such as a loopback address or a whitelisted network.
+func (s *ServerV2) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || s.access.origins["*"] {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host) || s.access.origins[strings.ToLower(u.Scheme+"://"+u.Host)]
+}
+
+// extractIP returns the client address of a request. Forwarding headers are only
+// believed when the connection comes from a trusted proxy; the forwarded chain is
+// then followed back to the first address that is not a trusted proxy itself.
+func (s *ServerV2) extractIP(r *http.Request) string {
+	if local, _ := r.Context().Value(unixSocketKey{}).(bool); local {
+		return unixSocketClient
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !containsIP(s.access.trusted, ip) {
+		return ip
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				// Nothing before a malformed entry can be relied on
+				break
+			}
+			ip = hop
+			if !containsIP(s.access.trusted, hop) {
+				break
+			}
+		}
+		return ip
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-Ip")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return ip
+}
+
+// methodCost returns what a call of a method is charged against the rate limit
+func (s *ServerV2) methodCost(method string) int {
+	if cost, ok := s.access.methodCosts[method]; ok {
+		return cost
+	}
+	return 1
+}
+
+// rateLimitExempt reports whether a client is not rate limited
+func (s *ServerV2) rateLimitExempt(ip string) bool {
+	if s.access.rateLimit == 0 {
+		return true
+	}
+	if ip == unixSocketClient {
+		return !s.access.limitLoopback
+	}
+	parsed := net.ParseIP(ip)
+	return !s.access.limitLoopback && parsed != nil && parsed.IsLoopback()
+}
+
+// checkRateLimit charges a cost to a client and reports whether its budget for the
+// current minute was not yet used up. A cost of zero only checks the budget.
+func (s *ServerV2) checkRateLimit(ip string, cost int) bool {
+	if s.rateLimitExempt(ip) {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Get or create rate limit for this IP
+	limit, exists := s.rateLimits[ip]
+	if !exists {
+		limit = &RateLimit{
+			LastReset:      time.Now(),
+			RequestsPerMin: s.access.rateLimit,
+		}
+		s.rateLimits[ip] = limit
+	}
+
+	// Reset counter if more than a minute has passed
+	if time.Since(limit.LastReset) > time.Minute {
+		limit.Count = 0
+		limit.LastReset = time.Now()
+	}
+
+	// The call that reaches the limit is still served, so an expensive method is
+	// never out of reach
+	if limit.Count >= limit.RequestsPerMin {
+		return false
+	}
+	limit.Count += cost
+	return true
+}
+
+// chargeCall charges a call to its caller, returning an error if the caller's
+// budget is used up
+func (s *ServerV2) chargeCall(req *RPCRequest, c *caller) *RPCResponse {
+	if s.checkRateLimit(c.ip, s.methodCost(req.Method)) {
+		return nil
+	}
+	return errorResponse(req.ID, CodeLimitExceeded, "Limit exceeded",
+		fmt.Sprintf("rate limit of %d per minute exceeded", s.access.rateLimit))
+}
+
+// handleGetRPCInfo reports the access configuration and the current rate limit state
+// of every client
+func (s *ServerV2) handleGetRPCInfo(req *RPCRequest) *RPCResponse {
+	s.mu.RLock()
+	clients := make([]map[string]interface{}, 0, len(s.rateLimits))
+	for ip, limit := range s.rateLimits {
+		resetIn := time.Minute - time.Since(limit.LastReset)
+		if resetIn < 0 {
+			continue
+		}
+		client := map[string]interface{}{
+			"ip":      ip,
+			"used":    limit.Count,
+			"limit":   limit.RequestsPerMin,
+			"resetIn": int64(resetIn.Seconds()),
+		}
+		if conn, ok := s.connections[ip]; ok {
+			client["requests"] = conn.Requests
+			client["lastSeen"] = conn.LastSeen.Unix()
+		}
+		clients = append(clients, client)
+	}
+	s.mu.RUnlock()
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i]["used"].(int) > clients[j]["used"].(int)
+	})
+
+	s.wsMu.Lock()
+	wsClients := len(s.wsClients)
+	s.wsMu.Unlock()
+
+	origins := make([]string, 0, len(s.access.origins))
+	for origin := range s.access.origins {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+
+	costs := make(map[string]int, len(s.access.methodCosts))
+	for method, cost := range s.access.methodCosts {
+		costs[method] = cost
+	}
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"allowedNets":      formatNets(s.access.allowed),
+			"trustedProxies":   formatNets(s.access.trusted),
+			"allowedOrigins":   origins,
+			"maxConcurrent":    s.access.maxConcurrent,
+			"inFlight":         s.inFlight.Load(),
+			"webSocketClients": wsClients,
+			"rateLimit": map[string]interface{}{
+				"perMinute":     s.access.rateLimit,
+				"limitLoopback": s.access.limitLoopback,
+				"methodCosts":   costs,
+			},
+			"clients": clients,
+		},
+		ID: req.ID,
+	}
+}
@@ -0,0 +1,162 @@
+package rpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestExtractIP tests that forwarding headers are only believed from trusted proxies
+func TestExtractIP(t *testing.T) {
+	s := newConfiguredTestServer(t, ServerConfig{Access: AccessConfig{TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1"}}})
+
+	for _, c := range []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{"direct", "198.51.100.7:4000", nil, "", "198.51.100.7"},
+		{"untrusted forwarder", "198.51.100.7:4000", []string{"203.0.113.9"}, "", "198.51.100.7"},
+		{"trusted proxy", "192.0.2.1:4000", []string{"203.0.113.9"}, "", "203.0.113.9"},
+		{"proxy chain", "10.1.1.1:4000", []string{"6.6.6.6, 203.0.113.9, 10.2.2.2"}, "", "203.0.113.9"},
+		{"repeated headers", "10.1.1.1:4000", []string{"6.6.6.6", "203.0.113.9"}, "", "203.0.113.9"},
+		{"malformed hop", "10.1.1.1:4000", []string{"203.0.113.9, junk, 10.2.2.2"}, "", "10.2.2.2"},
+		{"real ip", "10.1.1.1:4000", nil, "203.0.113.9", "203.0.113.9"},
+		{"ipv6", "[2001:db8::1]:4000", []string{"203.0.113.9"}, "", "2001:db8::1"},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.RemoteAddr = c.remoteAddr
+		if c.forwarded != nil {
+			r.Header["X-Forwarded-For"] = c.forwarded
+		}
+		if c.realIP != "" {
+			r.Header.Set("X-Real-Ip", c.realIP)
+		}
+		if got := s.extractIP(r); got != c.want {
+			t.Errorf("%s: expected %s, got %s", c.name, c.want, got)
+		}
+	}
+}
+
+// TestAllowedNets tests rejecting clients outside the allowed networks
+func TestAllowedNets(t *testing.T) {
+	s := newConfiguredTestServer(t, ServerConfig{Access: AccessConfig{AllowedNets: []string{"192.0.2.0/24", "2001:db8::1"}}})
+	body := `{"jsonrpc":"2.0","id":1,"method":"getHeight"}`
+
+	for remoteAddr, status := range map[string]int{
+		"192.0.2.200:4000":   http.StatusOK,
+		"[2001:db8::1]:4000": http.StatusOK,
+		"198.51.100.7:4000":  http.StatusForbidden,
+		"[2001:db8::2]:4000": http.StatusForbidden,
+	} {
+		if w := post(s, remoteAddr, nil, body); w.Code != status {
+			t.Errorf("%s: expected %d, got %d", remoteAddr, status, w.Code)
+		}
+	}
+
+	for _, config := range []AccessConfig{
+		{AllowedNets: []string{"192.0.2.0/33"}},
+		{TrustedProxies: []string{"proxy.example"}},
+		{MethodCosts: map[string]int{"noSuchMethod": 2}},
+		{MethodCosts: map[string]int{"getHeight": -1}},
+		{MaxConcurrent: -1},
+	} {
+		if _, err := newAccessControl(config); err == nil {
+			t.Errorf("Expected %+v to be rejected", config)
+		}
+	}
+}
+
+// TestRateLimitCosts tests charging calls by method cost
+func TestRateLimitCosts(t *testing.T) {
+	s := newConfiguredTestServer(t, ServerConfig{Access: AccessConfig{
+		RateLimit:   10,
+		MethodCosts: map[string]int{"getRecentBlocks": 4, "getMiningInfo": 0},
+	}})
+	client := "198.51.100.7:4000"
+	height := `{"jsonrpc":"2.0","id":1,"method":"getHeight"}`
+
+	// 4 + 4 + 1 leaves one unit, which the next call may still spend
+	batch := `[{"jsonrpc":"2.0","id":1,"method":"getRecentBlocks"},{"jsonrpc":"2.0","id":2,"method":"getRecentBlocks"},` +
+		`{"jsonrpc":"2.0","id":3,"method":"getMiningInfo"},{"jsonrpc":"2.0","id":4,"method":"getHeight"}]`
+	if w := post(s, client, nil, batch); strings.Contains(w.Body.String(), `"code":-32005`) {
+		t.Fatalf("Expected the batch to fit the limit, got %s", w.Body.String())
+	}
+	if w := post(s, client, nil, `[`+height+`,`+height+`]`); !strings.Contains(w.Body.String(), `"code":-32005`) || !strings.Contains(w.Body.String(), `"result":1`) {
+		t.Errorf("Expected the call over the limit to fail, got %s", w.Body.String())
+	}
+	if w := post(s, client, nil, height); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected a client over its limit to be refused, got %d", w.Code)
+	}
+
+	// Other clients and loopback are not affected
+	if w := post(s, "198.51.100.8:4000", nil, height); !strings.Contains(w.Body.String(), `"result":1`) {
+		t.Errorf("Expected another client to be served, got %s", w.Body.String())
+	}
+	for i := 0; i < 20; i++ {
+		if w := post(s, "127.0.0.1:4000", nil, height); w.Code != http.StatusOK {
+			t.Fatalf("Expected loopback to be exempt, got %d", w.Code)
+		}
+	}
+
+	limited := newConfiguredTestServer(t, ServerConfig{Access: AccessConfig{RateLimit: 1, LimitLoopback: true}})
+	post(limited, "127.0.0.1:4000", nil, height)
+	if w := post(limited, "127.0.0.1:4000", nil, height); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected loopback to be limited, got %d", w.Code)
+	}
+
+	unlimited := newConfiguredTestServer(t, ServerConfig{Access: AccessConfig{RateLimit: -1}})
+	for i := 0; i < DefaultRateLimit+1; i++ {
+		if w := post(unlimited, client, nil, height); w.Code != http.StatusOK {
+			t.Fatalf("Expected no rate limit, got %d after %d calls", w.Code, i)
+		}
+	}
+}
+
+// TestGetRPCInfo tests reporting the access configuration and rate limit state
+func TestGetRPCInfo(t *testing.T) {
+	s := newConfiguredTestServer(t, ServerConfig{Access: AccessConfig{
+		TrustedProxies: []string{"192.0.2.1"},
+		RateLimit:      100,
+		MethodCosts:    map[string]int{"getRecentBlocks": 5},
+		MaxConcurrent:  8,
+	}})
+	post(s, "192.0.2.1:4000", http.Header{"X-Forwarded-For": {"203.0.113.9"}}, `{"jsonrpc":"2.0","id":1,"method":"getRecentBlocks"}`)
+
+	// Only admins may read it
+	if resp := s.handleBody([]byte(`{"jsonrpc":"2.0","id":1,"method":"getRPCInfo"}`), &caller{ip: "192.0.2.9", role: s.auth.anonymous}); !strings.Contains(string(resp), `"code":-32003`) {
+		t.Errorf("Expected getRPCInfo to be admin only, got %s", resp)
+	}
+
+	var resp struct {
+		Result struct {
+			TrustedProxies []string `json:"trustedProxies"`
+			MaxConcurrent  int      `json:"maxConcurrent"`
+			RateLimit      struct {
+				PerMinute   int            `json:"perMinute"`
+				MethodCosts map[string]int `json:"methodCosts"`
+			} `json:"rateLimit"`
+			Clients []struct {
+				IP    string `json:"ip"`
+				Used  int    `json:"used"`
+				Limit int    `json:"limit"`
+			} `json:"clients"`
+		} `json:"result"`
+	}
+	reply := s.handleBody([]byte(`{"jsonrpc":"2.0","id":1,"method":"getRPCInfo"}`), &caller{ip: "127.0.0.1", role: s.auth.roles[RoleAdmin]})
+	if err := json.Unmarshal(reply, &resp); err != nil {
+		t.Fatalf("Invalid reply %s: %v", reply, err)
+	}
+	info := resp.Result
+	if len(info.TrustedProxies) != 1 || info.TrustedProxies[0] != "192.0.2.1/32" || info.MaxConcurrent != 8 ||
+		info.RateLimit.PerMinute != 100 || info.RateLimit.MethodCosts["getRecentBlocks"] != 5 {
+		t.Errorf("Unexpected configuration: %s", reply)
+	}
+	if len(info.Clients) != 1 || info.Clients[0].IP != "203.0.113.9" || info.Clients[0].Used != 5 || info.Clients[0].Limit != 100 {
+		t.Errorf("Expected the forwarded client charged 5, got %s", reply)
+	}
+}
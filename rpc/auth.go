@@ -0,0 +1,295 @@
+package rpc
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Built-in RPC roles
+const (
+	RolePublic = "public" // Read-only methods and relaying signed transactions
+	RoleMiner  = "miner"  // Public methods plus block templates and block submission
+	RoleAdmin  = "admin"  // Every method
+	RoleNone   = "none"   // Anonymous role that rejects requests without credentials
+)
+
+const (
+	// CookieFile is the file in the data directory holding the credential written on start
+	CookieFile = ".cookie"
+
+	// cookieUser is the basic auth user name of the cookie credential
+	cookieUser = "__cookie__"
+)
+
+// publicMethods can be called by every role. Relaying a signed transaction is allowed
+// because any peer can do the same over the network.
+var publicMethods = []string{
+	"getHeight", "getBestBlock", "getRecentBlocks",
+	"getBlockByHash", "getBlockByNumber", "getBlockHeader",
+	"getTransaction", "getRawMempool", "getMempoolEntry",
+	"getMiningInfo", "getUpgrades", "getSyncStatus", "getBalance",
+	"createRawTransaction", "fundRawTransaction", "sendRawTransaction",
+	"subscribe", "unsubscribe",
+}
+
+// minerMethods can be called by miners in addition to the public methods
+var minerMethods = []string{"createBlockTemplate", "getBlockTemplate", "submitBlock"}
+
+// AuthConfig configures who may call which RPC methods
+type AuthConfig struct {
+	// Anonymous is the role of requests without credentials, RolePublic if empty;
+	// RoleNone requires credentials on every request
+	Anonymous string `json:"anonymous"`
+
+	// Roles defines additional roles, or overrides built-in ones, as the methods
+	// they may call; "*" allows every method
+	Roles map[string][]string `json:"roles"`
+
+	Tokens []TokenCredential `json:"tokens"` // Sent as "Authorization: Bearer <token>"
+	Users  []UserCredential  `json:"users"`  // Sent with HTTP basic auth
+}
+
+// TokenCredential grants a role to a bearer token
+type TokenCredential struct {
+	Token string `json:"token"`
+	Role  string `json:"role"`
+}
+
+// UserCredential grants a role to a basic auth user
+type UserCredential struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// LoadAuthConfig reads an AuthConfig from a JSON file
+func LoadAuthConfig(path string) (AuthConfig, error) {
+	var config AuthConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("failed to read auth config: %v", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("invalid auth config %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// role is a named set of methods
+type role struct {
+	name    string
+	all     bool
+	methods map[string]bool
+}
+
+// allows reports whether the role may call a method
+func (r *role) allows(method string) bool {
+	return r.all || r.methods[method]
+}
+
+var (
+	errMissingCredentials = errors.New("credentials required")
+	errInvalidCredentials = errors.New("invalid credentials")
+)
+
+// authenticator maps request credentials to roles
+type authenticator struct {
+	roles     map[string]*role
+	anonymous *role // nil when credentials are required
+	tokens    []credential
+	users     map[string]credential
+	cookie    []byte // Cookie secret, set while the cookie file exists
+}
+
+// credential is a secret and the role it grants
+type credential struct {
+	secret []byte
+	role   *role
+}
+
+// newAuthenticator checks an AuthConfig and builds its roles and credentials
+func newAuthenticator(config AuthConfig) (*authenticator, error) {
+	a := &authenticator{
+		roles: make(map[string]*role),
+		users: make(map[string]credential),
+	}
+
+	definitions := map[string][]string{
+		RolePublic: publicMethods,
+		RoleMiner:  append(append([]string(nil), publicMethods...), minerMethods...),
+		RoleAdmin:  {"*"},
+	}
+	for name, methods := range config.Roles {
+		if name == RoleNone || name == "" {
+			return nil, fmt.Errorf("invalid role name %q", name)
+		}
+		if name == RoleAdmin {
+			return nil, fmt.Errorf("the %s role cannot be redefined", RoleAdmin)
+		}
+		definitions[name] = methods
+	}
+	for name, methods := range definitions {
+		r := &role{name: name, methods: make(map[string]bool)}
+		for _, method := range methods {
+			if method == "*" {
+				r.all = true
+				continue
+			}
+			if _, known := methodParams[method]; !known {
+				return nil, fmt.Errorf("role %s: unknown method %q", name, method)
+			}
+			r.methods[method] = true
+		}
+		a.roles[name] = r
+	}
+
+	switch config.Anonymous {
+	case "":
+		a.anonymous = a.roles[RolePublic]
+	case RoleNone:
+	default:
+		if a.anonymous = a.roles[config.Anonymous]; a.anonymous == nil {
+			return nil, fmt.Errorf("unknown anonymous role %q", config.Anonymous)
+		}
+	}
+
+	for i, token := range config.Tokens {
+		r := a.roles[token.Role]
+		if r == nil {
+			return nil, fmt.Errorf("token %d: unknown role %q", i+1, token.Role)
+		}
+		if len(token.Token) < 16 {
+			return nil, fmt.Errorf("token %d: tokens must be at least 16 characters", i+1)
+		}
+		a.tokens = append(a.tokens, credential{secret: []byte(token.Token), role: r})
+	}
+	for _, user := range config.Users {
+		r := a.roles[user.Role]
+		if r == nil {
+			return nil, fmt.Errorf("user %s: unknown role %q", user.User, user.Role)
+		}
+		if user.User == "" || user.User == cookieUser || strings.Contains(user.User, ":") {
+			return nil, fmt.Errorf("invalid user name %q", user.User)
+		}
+		if user.Password == "" {
+			return nil, fmt.Errorf("user %s: password required", user.User)
+		}
+		if _, exists := a.users[user.User]; exists {
+			return nil, fmt.Errorf("user %s defined twice", user.User)
+		}
+		a.users[user.User] = credential{secret: []byte(user.Password), role: r}
+	}
+
+	return a, nil
+}
+
+// authenticate returns the role of a request's credentials
+func (a *authenticator) authenticate(r *http.Request) (*role, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		if a.anonymous == nil {
+			return nil, errMissingCredentials
+		}
+		return a.anonymous, nil
+	}
+
+	if scheme, token, ok := strings.Cut(header, " "); ok && strings.EqualFold(scheme, "Bearer") {
+		// Every token is compared so the time taken does not reveal which one matched
+		var match *role
+		for _, credential := range a.tokens {
+			if subtle.ConstantTimeCompare(credential.secret, []byte(token)) == 1 {
+				match = credential.role
+			}
+		}
+		if match == nil {
+			return nil, errInvalidCredentials
+		}
+		return match, nil
+	}
+
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, errInvalidCredentials
+	}
+	if user == cookieUser {
+		if a.cookie != nil && subtle.ConstantTimeCompare(a.cookie, []byte(password)) == 1 {
+			return a.roles[RoleAdmin], nil
+		}
+		return nil, errInvalidCredentials
+	}
+	if credential, exists := a.users[user]; exists && subtle.ConstantTimeCompare(credential.secret, []byte(password)) == 1 {
+		return credential.role, nil
+	}
+	return nil, errInvalidCredentials
+}
+
+// writeCookie creates a new random admin credential in the data directory. Only
+// the node's user can read it, which is what authorizes local tools.
+func (a *authenticator) writeCookie(dataDir string) error {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("failed to generate RPC cookie: %v", err)
+	}
+	cookie := []byte(hex.EncodeToString(secret))
+
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %v", err)
+	}
+	path := filepath.Join(dataDir, CookieFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(cookieUser+":"+string(cookie)), 0600); err != nil {
+		return fmt.Errorf("failed to write RPC cookie: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write RPC cookie: %v", err)
+	}
+
+	a.cookie = cookie
+	return nil
+}
+
+// removeCookie deletes the cookie file so stale credentials do not outlive the node
+func (a *authenticator) removeCookie(dataDir string) {
+	if a.cookie == nil {
+		return
+	}
+	os.Remove(filepath.Join(dataDir, CookieFile))
+}
+
+// ReadCookie reads the RPC cookie a node wrote to its data directory, as "user:password"
+func ReadCookie(dataDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, CookieFile))
+	if err != nil {
+		return "", err
+	}
+	cookie := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(cookie, cookieUser+":") {
+		return "", fmt.Errorf("invalid RPC cookie in %s", dataDir)
+	}
+	return cookie, nil
+}
+
+// ClientAuthorization returns the Authorization header a client should send: the
+// bearer token if one is given, otherwise the node's cookie if it can be read from
+// dataDir, otherwise nothing
+func ClientAuthorization(dataDir, token string) string {
+	if token != "" {
+		return "Bearer " + token
+	}
+	if dataDir != "" {
+		if cookie, err := ReadCookie(dataDir); err == nil {
+			return "Basic " + base64.StdEncoding.EncodeToString([]byte(cookie))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+package rpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testToken is a bearer token long enough to be accepted
+const testToken = "0123456789abcdef0123"
+
+// TestAuthRoles tests which methods anonymous, miner and admin callers may call
+func TestAuthRoles(t *testing.T) {
+	s := newConfiguredTestServer(t, ServerConfig{Auth: AuthConfig{
+		Tokens: []TokenCredential{{Token: testToken, Role: RoleMiner}},
+		Users:  []UserCredential{{User: "ops", Password: "secret", Role: RoleAdmin}},
+	}})
+	basic := func(user, password string) string {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.SetBasicAuth(user, password)
+		return r.Header.Get("Authorization")
+	}
+
+	for _, c := range []struct {
+		name          string
+		authorization string
+		method        string
+		status        int
+		want          string
+	}{
+		{"anonymous read", "", "getHeight", http.StatusOK, `"result":1`},
+		{"anonymous template", "", "getBlockTemplate", http.StatusOK, `"code":-32003`},
+		{"anonymous admin", "", "listBanned", http.StatusOK, `"code":-32003`},
+		{"anonymous unknown", "", "noSuchMethod", http.StatusOK, `"code":-32601`},
+		{"miner template", "Bearer " + testToken, "getBlockTemplate", http.StatusOK, `"code":-32602`},
+		{"miner admin", "Bearer " + testToken, "listBanned", http.StatusOK, `"code":-32003`},
+		{"wrong token", "Bearer " + testToken + "x", "getHeight", http.StatusUnauthorized, `"code":-32001`},
+		// The test server has no peer network, so an allowed listBanned fails inside the method
+		{"admin user", basic("ops", "secret"), "listBanned", http.StatusOK, `"code":-32603`},
+		{"wrong password", basic("ops", "guess"), "getHeight", http.StatusUnauthorized, `"code":-32001`},
+		{"cookie before start", basic(cookieUser, ""), "getHeight", http.StatusUnauthorized, `"code":-32001`},
+	} {
+		w := post(s, "", http.Header{"Authorization": {c.authorization}}, `{"jsonrpc":"2.0","id":1,"method":"`+c.method+`"}`)
+		if w.Code != c.status || !strings.Contains(w.Body.String(), c.want) {
+			t.Errorf("%s: expected %d with %s, got %d %s", c.name, c.status, c.want, w.Code, w.Body.String())
+		}
+		if c.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: expected a WWW-Authenticate challenge", c.name)
+		}
+	}
+
+	// Each call in a batch is checked separately
+	w := post(s, "", nil, `[{"jsonrpc":"2.0","id":1,"method":"getHeight"},{"jsonrpc":"2.0","id":2,"method":"submitBlock"}]`)
+	if body := w.Body.String(); !strings.Contains(body, `"result":1`) || !strings.Contains(body, `"code":-32003`) {
+		t.Errorf("Expected batch to mix results and forbidden errors, got %s", body)
+	}
+}
+
+// TestAuthConfig tests custom roles, anonymous access and invalid configurations
+func TestAuthConfig(t *testing.T) {
+	s := newConfiguredTestServer(t, ServerConfig{Auth: AuthConfig{
+		Anonymous: RoleNone,
+		Roles:     map[string][]string{"monitor": {"getHeight"}},
+		Tokens:    []TokenCredential{{Token: testToken, Role: "monitor"}},
+	}})
+	if w := post(s, "", nil, `{"jsonrpc":"2.0","id":1,"method":"getHeight"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected credentials to be required, got %d %s", w.Code, w.Body.String())
+	}
+	if w := post(s, "", http.Header{"Authorization": {"Bearer " + testToken}}, `{"jsonrpc":"2.0","id":1,"method":"getHeight"}`); !strings.Contains(w.Body.String(), `"result":1`) {
+		t.Errorf("Expected monitor to read the height, got %s", w.Body.String())
+	}
+	if w := post(s, "", http.Header{"Authorization": {"Bearer " + testToken}}, `{"jsonrpc":"2.0","id":1,"method":"getMiningInfo"}`); !strings.Contains(w.Body.String(), `"code":-32003`) {
+		t.Errorf("Expected monitor to be limited to its methods, got %s", w.Body.String())
+	}
+
+	for _, config := range []AuthConfig{
+		{Anonymous: "nobody"},
+		{Roles: map[string][]string{RoleAdmin: {"getHeight"}}},
+		{Roles: map[string][]string{"monitor": {"noSuchMethod"}}},
+		{Tokens: []TokenCredential{{Token: "short", Role: RoleAdmin}}},
+		{Tokens: []TokenCredential{{Token: testToken, Role: "nobody"}}},
+		{Users: []UserCredential{{User: cookieUser, Password: "secret", Role: RoleAdmin}}},
+		{Users: []UserCredential{{User: "ops", Role: RoleAdmin}}},
+	} {
+		if _, err := newAuthenticator(config); err == nil {
+			t.Errorf("Expected %+v to be rejected", config)
+		}
+	}
+}
+
+// TestAuthCookie tests the cookie written for local tools
+func TestAuthCookie(t *testing.T) {
+	dataDir := t.TempDir()
+	s := newConfiguredTestServer(t, ServerConfig{Auth: AuthConfig{}})
+	if err := s.auth.writeCookie(dataDir); err != nil {
+		t.Fatalf("Failed to write cookie: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dataDir, CookieFile))
+	if err != nil {
+		t.Fatalf("Expected cookie file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected cookie to be readable by its owner only, got %v", info.Mode().Perm())
+	}
+
+	authorization := ClientAuthorization(dataDir, "")
+	if !strings.HasPrefix(authorization, "Basic ") {
+		t.Fatalf("Expected basic auth from the cookie, got %q", authorization)
+	}
+	if w := post(s, "", http.Header{"Authorization": {authorization}}, `{"jsonrpc":"2.0","id":1,"method":"listBanned"}`); !strings.Contains(w.Body.String(), `"code":-32603`) {
+		t.Errorf("Expected the cookie to grant admin access, got %s", w.Body.String())
+	}
+	if got := ClientAuthorization(dataDir, testToken); got != "Bearer "+testToken {
+		t.Errorf("Expected a token to take precedence over the cookie, got %q", got)
+	}
+
+	s.auth.removeCookie(dataDir)
+	if _, err := os.Stat(filepath.Join(dataDir, CookieFile)); !os.IsNotExist(err) {
+		t.Errorf("Expected cookie file to be removed, got %v", err)
+	}
+	if got := ClientAuthorization(dataDir, ""); got != "" {
+		t.Errorf("Expected no authorization without a cookie, got %q", got)
+	}
+}
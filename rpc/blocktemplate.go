@@ -0,0 +1,208 @@
+package rpc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+const (
+	// maxBlockTemplates is how many issued templates are kept for submission by id
+	maxBlockTemplates = 64
+
+	// blockTemplateExpiry is how long an issued template can be submitted
+	blockTemplateExpiry = 10 * time.Minute
+
+	// longPollTimeout bounds how long getBlockTemplate waits for new work; it stays
+	// below the server write timeout so the reply is always delivered
+	longPollTimeout = 25 * time.Second
+)
+
+// BlockTemplateParams are the parameters of getBlockTemplate
+type BlockTemplateParams struct {
+	Miner      string `json:"miner"`
+	LongPollID string `json:"longpollid"` // Wait until the work identified by a previous template changes
+}
+
+// SubmitWorkParams are the parameters of submitBlock when submitting work for an issued template
+type SubmitWorkParams struct {
+	TemplateID string  `json:"templateId"`
+	Nonce      *uint64 `json:"nonce"`
+	Timestamp  *int64  `json:"timestamp"` // Header time in Unix seconds, if the miner changed it
+}
+
+// blockTemplate is a template issued to a miner
+type blockTemplate struct {
+	block  *core.Block
+	issued time.Time
+}
+
+// longPollID identifies the work a template is built from: the chain tip and the mempool contents
+func (s *ServerV2) longPollID() string {
+	var tip core.Hash
+	if best := s.blockchain.GetBestBlock(); best != nil {
+		tip = best.Hash
+	}
+	return fmt.Sprintf("%x-%d", tip, s.blockchain.GetMempool().Sequence())
+}
+
+// waitForNewWork blocks until the work identified by longPollID changes, the long
+// poll times out or the server stops
+func (s *ServerV2) waitForNewWork(longPollID string) {
+	// Subscribe before checking, so a change in between still wakes the wait
+	blocks := s.eventBus.Subscribe(core.EventBlockAdded)
+	txs := s.eventBus.Subscribe(core.EventTransactionAdded)
+	defer s.eventBus.Unsubscribe(core.EventBlockAdded, blocks)
+	defer s.eventBus.Unsubscribe(core.EventTransactionAdded, txs)
+
+	if s.longPollID() != longPollID {
+		return
+	}
+
+	timer := time.NewTimer(longPollTimeout)
+	defer timer.Stop()
+	select {
+	case <-blocks:
+	case <-txs:
+	case <-timer.C:
+	case <-s.ctx.Done():
+	}
+}
+
+// handleGetBlockTemplate returns a block template with an id for submitBlock. Given
+// the longpollid of an earlier template it first waits until there is new work.
+func (s *ServerV2) handleGetBlockTemplate(req *RPCRequest) *RPCResponse {
+	var params BlockTemplateParams
+	if resp := decodeParams(req, &params); resp != nil {
+		return resp
+	}
+	miner, err := parseAddressParam(params.Miner)
+	if err != nil {
+		return invalidParams(req, err.Error())
+	}
+
+	if params.LongPollID != "" {
+		s.waitForNewWork(params.LongPollID)
+	}
+
+	// The id is taken before the template is built, so a change in between makes the
+	// next long poll return at once rather than being missed
+	longPollID := s.longPollID()
+	block := s.blockchain.CreateNewBlockV2(miner, nil)
+	if block == nil {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInternalError,
+				Message: "Internal error",
+				Data:    "Failed to create block template",
+			},
+			ID: req.ID,
+		}
+	}
+	templateID := s.storeBlockTemplate(block)
+
+	var fees uint64
+	txHashes := make([]string, len(block.Txs))
+	for i, tx := range block.Txs {
+		txHashes[i] = hex.EncodeToString(tx.Hash[:])
+		fees += tx.Fee
+	}
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"templateId":    templateID,
+			"longpollid":    longPollID,
+			"number":        block.Header.Number,
+			"parentHash":    hex.EncodeToString(block.Header.ParentHash[:]),
+			"timestamp":     block.Header.Timestamp.Unix(),
+			"difficulty":    block.Header.Difficulty,
+			"miner":         hex.EncodeToString(block.Header.Miner[:]),
+			"merkleRoot":    hex.EncodeToString(block.Header.MerkleRoot[:]),
+			"txCount":       block.Header.TxCount,
+			"networkFee":    block.Header.NetworkFee,
+			"treasuryFee":   block.Header.TreasuryFee,
+			"coinbaseValue": block.Txs[0].Amount,
+			"fees":          fees,
+			"transactions":  txHashes,
+			"expires":       time.Now().Add(blockTemplateExpiry).Unix(),
+		},
+		ID: req.ID,
+	}
+}
+
+// storeBlockTemplate keeps a template for submission and returns its id. Expired
+// templates and the oldest ones beyond the limit are dropped.
+func (s *ServerV2) storeBlockTemplate(block *core.Block) string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	templateID := hex.EncodeToString(id)
+
+	s.templatesMu.Lock()
+	defer s.templatesMu.Unlock()
+
+	now := time.Now()
+	for len(s.templateOrder) > 0 {
+		oldest := s.templateOrder[0]
+		if len(s.templateOrder) < maxBlockTemplates && now.Sub(s.templates[oldest].issued) < blockTemplateExpiry {
+			break
+		}
+		delete(s.templates, oldest)
+		s.templateOrder = s.templateOrder[1:]
+	}
+	s.templates[templateID] = &blockTemplate{block: block, issued: now}
+	s.templateOrder = append(s.templateOrder, templateID)
+
+	return templateID
+}
+
+// getBlockTemplate returns an issued template that has not expired
+func (s *ServerV2) getBlockTemplate(templateID string) *core.Block {
+	s.templatesMu.Lock()
+	defer s.templatesMu.Unlock()
+
+	template, ok := s.templates[templateID]
+	if !ok || time.Since(template.issued) >= blockTemplateExpiry {
+		return nil
+	}
+	return template.block
+}
+
+// blockFromWork completes an issued template with the miner's nonce and timestamp.
+// The hash is always computed here, never taken from the miner.
+func (s *ServerV2) blockFromWork(req *RPCRequest) (*core.Block, *RPCResponse) {
+	var params SubmitWorkParams
+	if resp := decodeParams(req, &params); resp != nil {
+		return nil, resp
+	}
+	if params.TemplateID == "" {
+		return nil, invalidParams(req, "missing 'templateId'")
+	}
+	if params.Nonce == nil {
+		return nil, invalidParams(req, "missing 'nonce'")
+	}
+
+	template := s.getBlockTemplate(params.TemplateID)
+	if template == nil {
+		return nil, notFound(req, fmt.Sprintf("template %q not found or expired", params.TemplateID))
+	}
+
+	// Copy the header so concurrent submissions of one template do not interfere
+	block := &core.Block{
+		Header: template.Header,
+		Txs:    template.Txs,
+	}
+	block.Header.Nonce = *params.Nonce
+	if params.Timestamp != nil {
+		block.Header.Timestamp = time.Unix(*params.Timestamp, 0)
+	}
+	block.Hash = block.CalculateHash()
+
+	log.Printf("🔧 Work submitted for template %s: block #%d, nonce %d", params.TemplateID, block.Header.Number, block.Header.Nonce)
+	return block, nil
+}
@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+// TestSubmitWork tests submitting a nonce for an issued template
+func TestSubmitWork(t *testing.T) {
+	s, _ := newTestServer(t)
+	miner := "0200000000000000000000000000000000000000"
+
+	resp := call(s, "getBlockTemplate", `{"miner":"`+miner+`"}`)
+	if resp.Error != nil {
+		t.Fatalf("getBlockTemplate failed: %+v", resp.Error)
+	}
+	template := resp.Result.(map[string]interface{})
+	templateID := template["templateId"].(string)
+	if template["number"] != uint64(2) || template["longpollid"] != s.longPollID() {
+		t.Errorf("Unexpected template: %v", template)
+	}
+
+	for _, c := range []struct {
+		params string
+		code   int
+	}{
+		{`{"templateId":"` + templateID + `"}`, CodeInvalidParams},
+		{`{"templateId":"unknown","nonce":1}`, CodeNotFound},
+		{`{"nonce":1}`, CodeInvalidParams},
+		{`{"block":{"number":2,"nonce":1}}`, CodeInvalidParams},
+		{`{"templateId":"` + templateID + `","nonce":1,"hash":"00"}`, CodeInvalidParams},
+	} {
+		if resp := call(s, "submitBlock", c.params); resp.Error == nil || resp.Error.Code != c.code {
+			t.Errorf("%s: expected error %d, got %+v", c.params, c.code, resp)
+		}
+	}
+
+	resp = call(s, "submitBlock", `{"templateId":"`+templateID+`","nonce":42}`)
+	if resp.Error != nil {
+		t.Fatalf("submitBlock failed: %+v", resp.Error)
+	}
+	best := s.blockchain.GetBestBlock()
+	if best.Header.Number != 2 || best.Header.Nonce != 42 || best.Hash != best.CalculateHash() {
+		t.Errorf("Expected the template completed with the nonce, got %+v", best.Header)
+	}
+	if resp.Result.(map[string]interface{})["hash"] != hex.EncodeToString(best.Hash[:]) {
+		t.Errorf("Expected the computed hash to be returned, got %v", resp.Result)
+	}
+
+	if resp := call(s, "submitBlock", `{"templateId":"`+templateID+`","nonce":43}`); resp.Error == nil {
+		t.Error("Expected a stale template to be rejected")
+	}
+}
+
+// TestGetBlockTemplateLongPoll tests waiting for new work with a longpollid
+func TestGetBlockTemplateLongPoll(t *testing.T) {
+	s, _ := newTestServer(t)
+	miner := "0200000000000000000000000000000000000000"
+
+	stale := "00-0"
+	start := time.Now()
+	if resp := call(s, "getBlockTemplate", `{"miner":"`+miner+`","longpollid":"`+stale+`"}`); resp.Error != nil {
+		t.Fatalf("getBlockTemplate failed: %+v", resp.Error)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Expected an outdated longpollid to return at once")
+	}
+
+	current := s.longPollID()
+	done := make(chan *RPCResponse, 1)
+	go func() {
+		done <- call(s, "getBlockTemplate", `{"miner":"`+miner+`","longpollid":"`+current+`"}`)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Expected long poll to wait while the work is unchanged")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	block := s.blockchain.CreateNewBlockV2(core.Address{1}, nil)
+	if err := s.blockchain.AddBlockV2(block); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+	select {
+	case resp := <-done:
+		template := resp.Result.(map[string]interface{})
+		if template["number"] != uint64(3) || template["longpollid"] == current {
+			t.Errorf("Expected a template on the new tip, got %v", template)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected long poll to return when the tip changed")
+	}
+}
@@ -0,0 +1,58 @@
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// unixURLPrefix starts RPC URLs that name a node's Unix socket, as unix:///path/to/socket
+const unixURLPrefix = "unix://"
+
+// NewHTTPClient returns an HTTP client for a node RPC URL and the URL to post
+// requests to. A unix:// URL connects to the node's Unix socket. For https, the
+// certificate in certFile is trusted, or otherwise the self-signed certificate the
+// node generated in dataDir, in addition to the system roots.
+func NewHTTPClient(rpcURL, dataDir, certFile string, timeout time.Duration) (*http.Client, string, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if socket, ok := strings.CutPrefix(rpcURL, unixURLPrefix); ok {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		// The host is not used to connect, only sent in the request
+		return &http.Client{Transport: transport, Timeout: timeout}, "http://localhost/", nil
+	}
+
+	if strings.HasPrefix(rpcURL, "https://") {
+		if certFile == "" && dataDir != "" {
+			if _, err := os.Stat(filepath.Join(dataDir, TLSCertFile)); err == nil {
+				certFile = filepath.Join(dataDir, TLSCertFile)
+			}
+		}
+		if certFile != "" {
+			pem, err := os.ReadFile(certFile)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to read RPC certificate: %v", err)
+			}
+			roots, err := x509.SystemCertPool()
+			if err != nil {
+				roots = x509.NewCertPool()
+			}
+			if !roots.AppendCertsFromPEM(pem) {
+				return nil, "", fmt.Errorf("no certificate found in %s", certFile)
+			}
+			transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+		}
+	}
+
+	return &http.Client{Transport: transport, Timeout: timeout}, rpcURL, nil
+}
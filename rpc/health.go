@@ -0,0 +1,82 @@
+package rpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// readySyncLag is how many blocks a node may be behind its peers and still be ready
+const readySyncLag = 2
+
+// handleLivez reports that the server is up. It does not look at the node, so a
+// node that is syncing or has lost its peers is still live.
+func (s *ServerV2) handleLivez(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "ok",
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+// handleReadyz reports whether the node serves current data: it is synced with its
+// peers, has enough of them and its storage works. A node that is not ready, or is
+// shutting down, answers 503 so load balancers route around it.
+func (s *ServerV2) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ready := s.ctx.Err() == nil
+
+	height := s.blockchain.GetHeight()
+	syncCheck := map[string]interface{}{
+		"currentHeight": height,
+		"targetHeight":  height,
+		"syncing":       false,
+		"ok":            true,
+	}
+	peers := 0
+	if p2p := s.getP2P(); p2p != nil {
+		status := p2p.GetSyncStatus()
+		synced := !status.Syncing && status.TargetHeight <= status.CurrentHeight+readySyncLag
+		syncCheck["currentHeight"] = status.CurrentHeight
+		syncCheck["targetHeight"] = status.TargetHeight
+		syncCheck["syncing"] = status.Syncing
+		syncCheck["ok"] = synced
+		ready = ready && synced
+		peers = p2p.GetPeerCount()
+	}
+
+	peersOK := peers >= s.minPeers
+	ready = ready && peersOK
+
+	persistent, err := s.blockchain.StorageHealth()
+	storageCheck := map[string]interface{}{
+		"persistent": persistent,
+		"ok":         err == nil,
+	}
+	if err != nil {
+		storageCheck["error"] = err.Error()
+		ready = false
+	}
+
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not ready", http.StatusServiceUnavailable
+	}
+	if s.ctx.Err() != nil {
+		status = "shutting down"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": status,
+		"checks": map[string]interface{}{
+			"sync": syncCheck,
+			"peers": map[string]interface{}{
+				"count":    peers,
+				"required": s.minPeers,
+				"ok":       peersOK,
+			},
+			"storage": storageCheck,
+		},
+	})
+}
@@ -0,0 +1,220 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+// failingStorage is a persister whose block writes fail
+type failingStorage struct{}
+
+func (failingStorage) StoreBlock(*core.Block) error { return errors.New("disk full") }
+func (failingStorage) GetBlockByNumber(uint64) (*core.Block, error) {
+	return nil, errors.New("not found")
+}
+func (failingStorage) GetBlockByHash([]byte) (*core.Block, error) {
+	return nil, errors.New("not found")
+}
+func (failingStorage) GetBestBlock() (*core.Block, error) { return nil, errors.New("not found") }
+func (failingStorage) GetBlockCount() (uint64, error)     { return 0, nil }
+func (failingStorage) Close() error                       { return nil }
+
+// getHealth fetches a health endpoint and decodes its JSON body
+func getHealth(t *testing.T, s *ServerV2, path string) (int, map[string]interface{}) {
+	t.Helper()
+	resp, err := http.Get("http://" + s.Addr().String() + path)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", path, err)
+	}
+	defer resp.Body.Close()
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Invalid %s response: %v", path, err)
+	}
+	return resp.StatusCode, body
+}
+
+// TestStartBindError tests that Start reports an address that cannot be bound
+func TestStartBindError(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer taken.Close()
+
+	dataDir := t.TempDir()
+	s := newConfiguredTestServer(t, ServerConfig{
+		Addr:    taken.Addr().String(),
+		DataDir: dataDir,
+		Cookie:  true,
+	})
+	if err := s.Start(); err == nil {
+		s.Stop()
+		t.Fatal("Expected binding a port in use to fail")
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, CookieFile)); !os.IsNotExist(err) {
+		t.Error("Expected no cookie to be written when the server did not start")
+	}
+}
+
+// TestHealthEndpoints tests /livez and the checks of /readyz
+func TestHealthEndpoints(t *testing.T) {
+	s := newConfiguredTestServer(t, ServerConfig{Addr: "127.0.0.1:0", ReadyMinPeers: 1})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer s.Stop()
+
+	if code, body := getHealth(t, s, "/livez"); code != http.StatusOK || body["status"] != "ok" {
+		t.Errorf("Expected /livez to be ok, got %d %v", code, body)
+	}
+
+	// Without peers the node is live but not ready
+	code, body := getHealth(t, s, "/readyz")
+	if code != http.StatusServiceUnavailable || body["status"] != "not ready" {
+		t.Errorf("Expected /readyz to be not ready without peers, got %d %v", code, body)
+	}
+	checks := body["checks"].(map[string]interface{})
+	if peers := checks["peers"].(map[string]interface{}); peers["ok"] != false || peers["required"] != float64(1) {
+		t.Errorf("Expected the peer check to fail, got %v", peers)
+	}
+	if storage := checks["storage"].(map[string]interface{}); storage["ok"] != true || storage["persistent"] != false {
+		t.Errorf("Expected in-memory storage to be ok, got %v", storage)
+	}
+
+	s.minPeers = 0
+	if code, body := getHealth(t, s, "/readyz"); code != http.StatusOK || body["status"] != "ready" {
+		t.Errorf("Expected /readyz to be ready, got %d %v", code, body)
+	}
+}
+
+// TestReadyzStorageFailure tests that a failed block write makes the node not ready
+func TestReadyzStorageFailure(t *testing.T) {
+	bc := core.NewBlockchainV2(&core.GenesisConfig{
+		BlockTimeTarget:    15,
+		InitialBlockReward: 5.0,
+		Difficulty:         core.DifficultyConfig{Window: 120, InitialDifficulty: 1},
+	}, failingStorage{})
+	if err := bc.AddBlockV2(bc.CreateNewBlockV2(core.Address{1}, nil)); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+	s := NewServerV2("127.0.0.1:0", bc)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer s.Stop()
+
+	code, body := getHealth(t, s, "/readyz")
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Expected /readyz to fail, got %d %v", code, body)
+	}
+	storage := body["checks"].(map[string]interface{})["storage"].(map[string]interface{})
+	if storage["ok"] != false || !strings.Contains(storage["error"].(string), "disk full") {
+		t.Errorf("Expected the write error to be reported, got %v", storage)
+	}
+}
+
+// TestStopDrainsRequests tests that Stop ends long polls and waits for their responses
+func TestStopDrainsRequests(t *testing.T) {
+	s := newConfiguredTestServer(t, ServerConfig{
+		Addr: "127.0.0.1:0",
+		Auth: AuthConfig{Anonymous: RoleMiner},
+	})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	url := "http://" + s.Addr().String()
+
+	type result struct {
+		body string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		body := `{"jsonrpc":"2.0","id":1,"method":"getBlockTemplate","params":{"miner":"0200000000000000000000000000000000000000","longpollid":"` + s.longPollID() + `"}}`
+		resp, err := http.Post(url, "application/json", strings.NewReader(body))
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		done <- result{string(data), err}
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s.inFlight.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the long poll to be in flight")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.Stop()
+	// The response was written before Stop returned; the client only has to read it
+	select {
+	case res := <-done:
+		if res.err != nil || !strings.Contains(res.body, `"longpollid"`) {
+			t.Errorf("Expected the long poll to be answered before stopping, got %q %v", res.body, res.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Stop to wait for the long poll")
+	}
+
+	if _, err := http.Get(url + "/livez"); err == nil {
+		t.Error("Expected the server to refuse connections after stopping")
+	}
+}
+
+// TestHealthOutsideConcurrencyLimit tests that health checks answer while long polls hold every slot
+func TestHealthOutsideConcurrencyLimit(t *testing.T) {
+	s := newConfiguredTestServer(t, ServerConfig{
+		Addr:   "127.0.0.1:0",
+		Auth:   AuthConfig{Anonymous: RoleMiner},
+		Access: AccessConfig{MaxConcurrent: 1},
+	})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer s.Stop()
+	url := "http://" + s.Addr().String()
+
+	go func() {
+		body := `{"jsonrpc":"2.0","id":1,"method":"getBlockTemplate","params":{"miner":"0200000000000000000000000000000000000000","longpollid":"` + s.longPollID() + `"}}`
+		if resp, err := http.Post(url, "application/json", strings.NewReader(body)); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for s.inFlight.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the long poll to be in flight")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	resp, err := http.Post(url, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"getHeight"}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected calls beyond the limit to be refused, got %d", resp.StatusCode)
+	}
+	if code, body := getHealth(t, s, "/livez"); code != http.StatusOK {
+		t.Errorf("Expected /livez to answer while the limit is reached, got %d %v", code, body)
+	}
+	if code, body := getHealth(t, s, "/readyz"); code != http.StatusOK {
+		t.Errorf("Expected /readyz to answer while the limit is reached, got %d %v", code, body)
+	}
+}
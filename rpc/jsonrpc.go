@@ -0,0 +1,297 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	CodeParseError     = -32700 // The body is not valid JSON
+	CodeInvalidRequest = -32600 // The JSON is not a valid request object
+	CodeMethodNotFound = -32601 // The method does not exist or is not available
+	CodeInvalidParams  = -32602 // The method parameters are invalid
+	CodeInternalError  = -32603 // The call failed inside the node
+	CodeUnauthorized   = -32001 // Credentials are missing or invalid
+	CodeForbidden      = -32003 // The caller's role may not call the method
+	CodeNotFound       = -32004 // The requested block or transaction is unknown
+	CodeLimitExceeded  = -32005 // The caller used up its rate limit
+)
+
+const (
+	// maxBatchSize is the most calls accepted in one batch request
+	maxBatchSize = 100
+
+	// maxBatchConcurrency is how many calls of a batch run at the same time
+	maxBatchConcurrency = 8
+)
+
+// methodParams lists the parameters of every method in positional order, so that
+// params may be given as an array as well as an object
+var methodParams = map[string][]string{
+	"getHeight":            nil,
+	"getBestBlock":         nil,
+	"getRecentBlocks":      {"limit"},
+	"getBlockByHash":       {"hash", "verbose", "raw"},
+	"getBlockByNumber":     {"number", "verbose", "raw"},
+	"getBlockHeader":       {"hash", "number"},
+	"getTransaction":       {"hash", "raw"},
+	"getRawMempool":        {"verbose"},
+	"getMempoolEntry":      {"hash"},
+	"createBlockTemplate":  {"miner"},
+	"getBlockTemplate":     {"miner", "longpollid"},
+	"submitBlock":          {"block"}, // Work for an issued template is submitted with named params
+	"getMiningInfo":        nil,
+	"getUpgrades":          nil,
+	"getSyncStatus":        nil,
+	"listBanned":           nil,
+	"setBan":               {"address", "command", "banTime", "reason"},
+	"clearBanned":          nil,
+	"getBalance":           {"address", "verbose"},
+	"sendTransaction":      {"from", "to", "amount", "fee"},
+	"sendRawTransaction":   {"hex"},
+	"createRawTransaction": {"inputs", "outputs", "data"},
+	"fundRawTransaction":   {"from", "to", "amount", "fee", "feeRate", "changeAddress", "data"},
+	"getRPCInfo":           nil,
+	"subscribe":            {"topic", "address"}, // WebSocket only
+	"unsubscribe":          {"subscription"},     // WebSocket only
+}
+
+// MarshalJSON encodes a response with exactly one of result and error, as JSON-RPC 2.0
+// requires; a nil, zero or false result is still sent
+func (r RPCResponse) MarshalJSON() ([]byte, error) {
+	if r.Error != nil {
+		return json.Marshal(struct {
+			JSONRPC string      `json:"jsonrpc"`
+			Error   *RPCError   `json:"error"`
+			ID      interface{} `json:"id"`
+		}{"2.0", r.Error, r.ID})
+	}
+	return json.Marshal(struct {
+		JSONRPC string      `json:"jsonrpc"`
+		Result  interface{} `json:"result"`
+		ID      interface{} `json:"id"`
+	}{"2.0", r.Result, r.ID})
+}
+
+// errorResponse creates an error response
+func errorResponse(id interface{}, code int, message, data string) *RPCResponse {
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Error: &RPCError{
+			Code:    code,
+			Message: message,
+			Data:    data,
+		},
+		ID: id,
+	}
+}
+
+// handleBody processes a single or batch request body for a caller and returns the
+// encoded reply, or nil when every call was a notification
+func (s *ServerV2) handleBody(body []byte, c *caller) []byte {
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		if !json.Valid(body) {
+			return encodeResponse(errorResponse(nil, CodeParseError, "Parse error", "body is not valid JSON"))
+		}
+		if resp := s.handleCall(body, c); resp != nil {
+			return encodeResponse(resp)
+		}
+		return nil
+	}
+
+	var calls []json.RawMessage
+	if err := json.Unmarshal(body, &calls); err != nil {
+		return encodeResponse(errorResponse(nil, CodeParseError, "Parse error", err.Error()))
+	}
+	if len(calls) == 0 {
+		return encodeResponse(errorResponse(nil, CodeInvalidRequest, "Invalid Request", "empty batch"))
+	}
+	if len(calls) > maxBatchSize {
+		return encodeResponse(errorResponse(nil, CodeInvalidRequest, "Invalid Request",
+			fmt.Sprintf("batch of %d calls exceeds the limit of %d", len(calls), maxBatchSize)))
+	}
+
+	// Calls run concurrently; each writes only its own slot
+	batch := &caller{ip: c.ip, role: c.role, batched: true}
+	replies := make([]json.RawMessage, len(calls))
+	slots := make(chan struct{}, maxBatchConcurrency)
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, call json.RawMessage) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if resp := s.handleCall(call, batch); resp != nil {
+				replies[i] = encodeResponse(resp)
+			}
+		}(i, call)
+	}
+	wg.Wait()
+
+	// Notifications get no reply; a batch of only notifications gets nothing at all
+	responses := make([]json.RawMessage, 0, len(replies))
+	for _, reply := range replies {
+		if reply != nil {
+			responses = append(responses, reply)
+		}
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(responses)
+	return data
+}
+
+// handleCall validates one request object and dispatches it if the caller's role
+// allows the method and its rate limit is not used up. It returns nil for a valid
+// notification, which is processed but not answered.
+func (s *ServerV2) handleCall(raw json.RawMessage, c *caller) *RPCResponse {
+	req, answer, resp := parseCall(raw)
+	if resp == nil {
+		resp = forbidden(req, c.role)
+	}
+	if resp == nil && c.batched {
+		resp = longPollInBatch(req)
+	}
+	if resp == nil {
+		resp = s.chargeCall(req, c)
+	}
+	if resp == nil {
+		resp = s.dispatch(req)
+	}
+	return reply(resp, answer)
+}
+
+// forbidden returns an error if a role may not call a known method. Unknown methods
+// are left to dispatch, which reports them as not found.
+func forbidden(req *RPCRequest, role *role) *RPCResponse {
+	if _, known := methodParams[req.Method]; !known || role.allows(req.Method) {
+		return nil
+	}
+	return errorResponse(req.ID, CodeForbidden, "Forbidden",
+		fmt.Sprintf("role %s may not call %s", role.name, req.Method))
+}
+
+// longPollInBatch returns an error for a long poll in a batch. The batch is answered
+// only when its last call is done, so long polls could hold it past the write timeout.
+func longPollInBatch(req *RPCRequest) *RPCResponse {
+	if params, ok := req.Params.(map[string]interface{}); ok && req.Method == "getBlockTemplate" {
+		if id, _ := params["longpollid"].(string); id != "" {
+			return invalidParams(req, "longpollid is not allowed in a batch")
+		}
+	}
+	return nil
+}
+
+// parseCall validates one request object. It returns the request, or an error response
+// if it is invalid, and whether a response is expected; notifications are not answered
+// unless they are malformed.
+func parseCall(raw json.RawMessage) (*RPCRequest, bool, *RPCResponse) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return nil, true, errorResponse(nil, CodeInvalidRequest, "Invalid Request", "request must be an object")
+	}
+
+	// A request without an id is a notification; ids keep their exact JSON value
+	var id interface{}
+	idRaw, hasID := fields["id"]
+	if hasID {
+		decoder := json.NewDecoder(bytes.NewReader(idRaw))
+		decoder.UseNumber()
+		decoder.Decode(&id)
+		switch id.(type) {
+		case nil, string, json.Number:
+		default:
+			return nil, true, errorResponse(nil, CodeInvalidRequest, "Invalid Request", "id must be a string, number or null")
+		}
+	}
+
+	var version, method string
+	if json.Unmarshal(fields["jsonrpc"], &version) != nil || version != "2.0" {
+		return nil, true, errorResponse(id, CodeInvalidRequest, "Invalid Request", `jsonrpc must be "2.0"`)
+	}
+	if json.Unmarshal(fields["method"], &method) != nil || method == "" {
+		return nil, true, errorResponse(id, CodeInvalidRequest, "Invalid Request", "method must be a non-empty string")
+	}
+
+	req := &RPCRequest{JSONRPC: version, Method: method, ID: id}
+	if paramsRaw, ok := fields["params"]; ok {
+		json.Unmarshal(paramsRaw, &req.Params)
+		switch params := req.Params.(type) {
+		case nil, map[string]interface{}:
+		case []interface{}:
+			if resp := namePositionalParams(req, params); resp != nil {
+				return req, hasID, resp
+			}
+		default:
+			return req, true, errorResponse(id, CodeInvalidRequest, "Invalid Request", "params must be an object or an array")
+		}
+	}
+
+	return req, hasID, nil
+}
+
+// reply drops the response to a notification
+func reply(resp *RPCResponse, hasID bool) *RPCResponse {
+	if !hasID {
+		return nil
+	}
+	return resp
+}
+
+// namePositionalParams replaces array params with an object keyed by the method's
+// parameter names. Methods the node does not know keep their params unchanged.
+func namePositionalParams(req *RPCRequest, params []interface{}) *RPCResponse {
+	names, known := methodParams[req.Method]
+	if !known {
+		return nil
+	}
+	if len(params) > len(names) {
+		return errorResponse(req.ID, CodeInvalidParams, "Invalid params",
+			fmt.Sprintf("%s takes at most %d positional parameters", req.Method, len(names)))
+	}
+	if len(params) == 0 {
+		req.Params = nil
+		return nil
+	}
+
+	named := make(map[string]interface{}, len(params))
+	for i, value := range params {
+		// null leaves an optional parameter at its default
+		if value != nil {
+			named[names[i]] = value
+		}
+	}
+	req.Params = named
+	return nil
+}
+
+// dispatch runs a method, turning a panic into an internal error for this call only
+func (s *ServerV2) dispatch(req *RPCRequest) (resp *RPCResponse) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("❌ PANIC in %s: %v\nStack trace: %s", req.Method, rec, debug.Stack())
+			resp = errorResponse(req.ID, CodeInternalError, "Internal error", fmt.Sprintf("Panic: %v", rec))
+		}
+	}()
+	return s.handleRPCMethod(req)
+}
+
+// encodeResponse encodes a response, replacing it with an internal error if the
+// result cannot be encoded
+func encodeResponse(resp *RPCResponse) []byte {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("❌ Failed to marshal RPC response: %v", err)
+		data, _ = json.Marshal(errorResponse(resp.ID, CodeInternalError, "Internal error",
+			fmt.Sprintf("JSON marshal error: %v", err)))
+	}
+	return data
+}
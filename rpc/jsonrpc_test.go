@@ -0,0 +1,155 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// normalizeReply decodes a reply and drops error data, which is free text
+func normalizeReply(t *testing.T, reply []byte) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal(reply, &value); err != nil {
+		t.Fatalf("Reply is not valid JSON: %s", reply)
+	}
+	responses, isBatch := value.([]interface{})
+	if !isBatch {
+		responses = []interface{}{value}
+	}
+	for _, response := range responses {
+		if errObj, ok := response.(map[string]interface{})["error"].(map[string]interface{}); ok {
+			delete(errObj, "data")
+		}
+	}
+	return value
+}
+
+// TestJSONRPCConformance tests request handling against the JSON-RPC 2.0 specification
+func TestJSONRPCConformance(t *testing.T) {
+	s, _ := newTestServer(t)
+	owner := "0100000000000000000000000000000000000000"
+
+	cases := []struct {
+		name string
+		body string
+		want string // Empty when no reply is expected
+	}{
+		{"named params", `{"jsonrpc":"2.0","method":"getBalance","params":{"address":"` + owner + `"},"id":1}`,
+			`{"jsonrpc":"2.0","result":5000000,"id":1}`},
+		{"positional params", `{"jsonrpc":"2.0","method":"getBalance","params":["` + owner + `"],"id":2}`,
+			`{"jsonrpc":"2.0","result":5000000,"id":2}`},
+		{"positional null keeps default", `{"jsonrpc":"2.0","method":"getBalance","params":["` + owner + `",null],"id":3}`,
+			`{"jsonrpc":"2.0","result":5000000,"id":3}`},
+		{"no params", `{"jsonrpc":"2.0","method":"getHeight","id":"a"}`,
+			`{"jsonrpc":"2.0","result":1,"id":"a"}`},
+		{"empty result is sent", `{"jsonrpc":"2.0","method":"getRawMempool","id":4}`,
+			`{"jsonrpc":"2.0","result":[],"id":4}`},
+		{"null id", `{"jsonrpc":"2.0","method":"getHeight","id":null}`,
+			`{"jsonrpc":"2.0","result":1,"id":null}`},
+		{"notification", `{"jsonrpc":"2.0","method":"getHeight"}`, ``},
+		{"notification of unknown method", `{"jsonrpc":"2.0","method":"foobar"}`, ``},
+		{"unknown method", `{"jsonrpc":"2.0","method":"foobar","id":"1"}`,
+			`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":"1"}`},
+		{"too many positional params", `{"jsonrpc":"2.0","method":"getBalance","params":["a",true,3],"id":5}`,
+			`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params"},"id":5}`},
+		{"invalid JSON", `{"jsonrpc":"2.0","method":"foobar,"params":"bar","baz]`,
+			`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`},
+		{"invalid request object", `{"jsonrpc":"2.0","method":1,"params":"bar"}`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`},
+		{"missing version", `{"method":"getHeight","id":6}`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":6}`},
+		{"scalar params", `{"jsonrpc":"2.0","method":"getHeight","params":"bar","id":7}`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":7}`},
+		{"object id", `{"jsonrpc":"2.0","method":"getHeight","id":{}}`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`},
+		{"batch invalid JSON", `[{"jsonrpc":"2.0","method":"getHeight","id":"1"},{"jsonrpc":"2.0","method"]`,
+			`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`},
+		{"empty batch", `[]`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`},
+		{"invalid batch", `[1]`,
+			`[{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}]`},
+		{"invalid batch entries", `[1,2,3]`,
+			`[{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null},
+			  {"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null},
+			  {"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}]`},
+		{"mixed batch", `[
+			{"jsonrpc":"2.0","method":"getHeight","id":"1"},
+			{"jsonrpc":"2.0","method":"getBalance","params":["` + owner + `"]},
+			{"jsonrpc":"2.0","method":"getBalance","params":{"address":"` + owner + `"},"id":"2"},
+			{"foo":"boo"},
+			{"jsonrpc":"2.0","method":"foo.get","params":{"name":"myself"},"id":"5"},
+			{"jsonrpc":"2.0","method":"getRawMempool","id":"9"}
+		]`,
+			`[{"jsonrpc":"2.0","result":1,"id":"1"},
+			  {"jsonrpc":"2.0","result":5000000,"id":"2"},
+			  {"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null},
+			  {"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":"5"},
+			  {"jsonrpc":"2.0","result":[],"id":"9"}]`},
+		{"long poll in batch", `[
+			{"jsonrpc":"2.0","method":"getBlockTemplate","params":["` + owner + `","tip-0"],"id":1},
+			{"jsonrpc":"2.0","method":"getHeight","id":2}
+		]`,
+			`[{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params"},"id":1},
+			  {"jsonrpc":"2.0","result":1,"id":2}]`},
+		{"batch of notifications", `[{"jsonrpc":"2.0","method":"getHeight"},{"jsonrpc":"2.0","method":"getBestBlock"}]`, ``},
+	}
+
+	for _, c := range cases {
+		reply := s.handleBody([]byte(c.body), &caller{role: s.auth.roles[RoleAdmin]})
+		if c.want == "" {
+			if reply != nil {
+				t.Errorf("%s: expected no reply, got %s", c.name, reply)
+			}
+			continue
+		}
+		if reply == nil {
+			t.Errorf("%s: expected a reply", c.name)
+			continue
+		}
+		if got, want := normalizeReply(t, reply), normalizeReply(t, []byte(c.want)); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s:\n got  %s\n want %s", c.name, reply, c.want)
+		}
+	}
+
+	// Numbers decode as floats above, so check the id digits separately
+	if reply := s.handleBody([]byte(`{"jsonrpc":"2.0","method":"getHeight","id":12345678901234567890}`), &caller{role: s.auth.roles[RoleAdmin]}); !strings.Contains(string(reply), `"id":12345678901234567890`) {
+		t.Errorf("Expected numeric id to be echoed exactly, got %s", reply)
+	}
+}
+
+// TestBatchOverHTTP tests batch ordering, the batch limit and replies to notifications
+func TestBatchOverHTTP(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	calls := make([]string, maxBatchSize)
+	for i := range calls {
+		calls[i] = fmt.Sprintf(`{"jsonrpc":"2.0","method":"getHeight","id":%d}`, i)
+	}
+	recorder := post(s, "", nil, "["+strings.Join(calls, ",")+"]")
+	var responses []struct {
+		Result uint64 `json:"result"`
+		ID     int    `json:"id"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &responses); err != nil || len(responses) != maxBatchSize {
+		t.Fatalf("Expected %d responses, got %s", maxBatchSize, recorder.Body)
+	}
+	for i, response := range responses {
+		if response.ID != i || response.Result != 1 {
+			t.Fatalf("Expected responses in request order, got %+v at %d", response, i)
+		}
+	}
+
+	recorder = post(s, "", nil, "["+strings.Join(append(calls, calls[0]), ",")+"]")
+	if !strings.Contains(recorder.Body.String(), `"code":-32600`) {
+		t.Errorf("Expected batch beyond the limit to be rejected, got %s", recorder.Body)
+	}
+
+	recorder = post(s, "", nil, `{"jsonrpc":"2.0","method":"getHeight"}`)
+	if recorder.Code != http.StatusNoContent || recorder.Body.Len() != 0 {
+		t.Errorf("Expected empty reply to a notification, got %d %q", recorder.Code, recorder.Body)
+	}
+}
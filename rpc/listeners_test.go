@@ -0,0 +1,150 @@
+package rpc
+
+import (
+	"bytes"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestUnixSocket tests serving RPC on a Unix socket with restricted permissions
+func TestUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "rpc.sock")
+	s := newConfiguredTestServer(t, ServerConfig{
+		Addr:       "127.0.0.1:0",
+		UnixSocket: socket,
+		// Socket clients are not subject to the allow-list
+		Access: AccessConfig{AllowedNets: []string{"192.0.2.0/24"}},
+	})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatalf("Expected socket file: %v", err)
+	}
+	if info.Mode().Perm() != DefaultSocketMode {
+		t.Errorf("Expected socket mode %v, got %v", DefaultSocketMode, info.Mode().Perm())
+	}
+	if _, err := listenUnix(socket, DefaultSocketMode); err == nil {
+		t.Error("Expected a socket in use not to be replaced")
+	}
+
+	client, url, err := NewHTTPClient("unix://"+socket, "", "", 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	resp, err := client.Post(url, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"getHeight"}`))
+	if err != nil {
+		t.Fatalf("Request over the socket failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), `"result":1`) {
+		t.Errorf("Expected the height, got %s", body)
+	}
+
+	s.Stop()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(socket); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the socket file to be removed on stop")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestListenUnixStaleSocket tests replacing a socket left behind by a crashed node
+func TestListenUnixStaleSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "rpc.sock")
+	stale, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := listenUnix(socket, 0660)
+	if err != nil {
+		t.Fatalf("Expected the stale socket to be replaced: %v", err)
+	}
+	defer listener.Close()
+	if info, _ := os.Stat(socket); info.Mode().Perm() != 0660 {
+		t.Errorf("Expected socket mode 0660, got %v", info.Mode().Perm())
+	}
+
+	file := filepath.Join(t.TempDir(), "not-a-socket")
+	os.WriteFile(file, nil, 0600)
+	if _, err := listenUnix(file, DefaultSocketMode); err == nil {
+		t.Error("Expected a regular file not to be replaced")
+	}
+}
+
+// TestSelfSignedCertificate tests generating, reusing and trusting the RPC certificate
+func TestSelfSignedCertificate(t *testing.T) {
+	dataDir := t.TempDir()
+	config, err := newServerTLSConfig("", "", dataDir, "203.0.113.5:16316")
+	if err != nil {
+		t.Fatalf("Failed to create TLS config: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(config.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatalf("Invalid certificate: %v", err)
+	}
+	if err := leaf.VerifyHostname("203.0.113.5"); err != nil {
+		t.Errorf("Expected the listen address in the certificate: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(dataDir, TLSKeyFile)); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a private key file, got %v %v", info, err)
+	}
+
+	again, err := newServerTLSConfig("", "", dataDir, "203.0.113.5:16316")
+	if err != nil {
+		t.Fatalf("Failed to load TLS config: %v", err)
+	}
+	if !bytes.Equal(again.Certificates[0].Certificate[0], config.Certificates[0].Certificate[0]) {
+		t.Error("Expected the certificate to be reused across restarts")
+	}
+
+	// A client trusts the node's certificate from its data directory
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	server.TLS = config
+	server.StartTLS()
+	defer server.Close()
+	url := server.URL
+
+	untrusted, _, _ := NewHTTPClient(url, "", "", 5*time.Second)
+	if _, err := untrusted.Get(url); err == nil {
+		t.Error("Expected the self-signed certificate to be untrusted by default")
+	}
+	client, _, err := NewHTTPClient(url, dataDir, "", 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("Expected the node certificate to be trusted: %v", err)
+	}
+	resp.Body.Close()
+
+	if _, _, err := NewHTTPClient(url, "", filepath.Join(dataDir, "missing.cert"), time.Second); err == nil {
+		t.Error("Expected a missing certificate file to be reported")
+	}
+	base, _ := newTestServer(t)
+	if _, err := NewServerV2WithConfig(ServerConfig{TLS: true}, base.blockchain); err == nil {
+		t.Error("Expected a self-signed certificate without a data directory to be rejected")
+	}
+}
@@ -0,0 +1,373 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+// BlockByHashParams are the parameters of getBlockByHash
+type BlockByHashParams struct {
+	Hash    string `json:"hash"`
+	Verbose bool   `json:"verbose"` // Include full transactions instead of their hashes
+	Raw     bool   `json:"raw"`     // Return the hex-encoded block as relayed between peers
+}
+
+// BlockByNumberParams are the parameters of getBlockByNumber
+type BlockByNumberParams struct {
+	Number  *uint64 `json:"number"`
+	Verbose bool    `json:"verbose"` // Include full transactions instead of their hashes
+	Raw     bool    `json:"raw"`     // Return the hex-encoded block as relayed between peers
+}
+
+// BlockHeaderParams are the parameters of getBlockHeader; the block is chosen by hash or number
+type BlockHeaderParams struct {
+	Hash   string  `json:"hash"`
+	Number *uint64 `json:"number"`
+}
+
+// TransactionParams are the parameters of getTransaction
+type TransactionParams struct {
+	Hash string `json:"hash"`
+	Raw  bool   `json:"raw"` // Return the hex-encoded binary transaction only
+}
+
+// MempoolEntryParams are the parameters of getMempoolEntry
+type MempoolEntryParams struct {
+	Hash string `json:"hash"`
+}
+
+// RawMempoolParams are the parameters of getRawMempool
+type RawMempoolParams struct {
+	Verbose bool `json:"verbose"` // Describe every entry instead of listing hashes
+}
+
+// decodeParams decodes the request parameters into a parameter struct. Missing
+// parameters leave the struct unchanged; unknown fields are rejected.
+func decodeParams(req *RPCRequest, params interface{}) *RPCResponse {
+	if req.Params == nil {
+		return nil
+	}
+	data, err := json.Marshal(req.Params)
+	if err != nil {
+		return invalidParams(req, err.Error())
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(params); err != nil {
+		return invalidParams(req, err.Error())
+	}
+	return nil
+}
+
+// notFound returns an error for a block or transaction the node does not know
+func notFound(req *RPCRequest, data string) *RPCResponse {
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Error: &RPCError{
+			Code:    CodeNotFound,
+			Message: "Not found",
+			Data:    data,
+		},
+		ID: req.ID,
+	}
+}
+
+// handleGetBlockByHash returns a main chain block by hash
+func (s *ServerV2) handleGetBlockByHash(req *RPCRequest) *RPCResponse {
+	var params BlockByHashParams
+	if resp := decodeParams(req, &params); resp != nil {
+		return resp
+	}
+	hash, err := parseHashParam(params.Hash)
+	if err != nil {
+		return invalidParams(req, "missing or invalid 'hash'")
+	}
+
+	block := s.blockchain.GetBlockByHash(hash)
+	if block == nil {
+		return notFound(req, fmt.Sprintf("block %x not found", hash))
+	}
+	return s.blockResponse(req, block, params.Verbose, params.Raw)
+}
+
+// handleGetBlockByNumber returns the main chain block at a height
+func (s *ServerV2) handleGetBlockByNumber(req *RPCRequest) *RPCResponse {
+	var params BlockByNumberParams
+	if resp := decodeParams(req, &params); resp != nil {
+		return resp
+	}
+	if params.Number == nil {
+		return invalidParams(req, "missing 'number'")
+	}
+
+	block := s.blockchain.GetBlockByNumber(*params.Number)
+	if block == nil {
+		return notFound(req, fmt.Sprintf("no block at height %d", *params.Number))
+	}
+	return s.blockResponse(req, block, params.Verbose, params.Raw)
+}
+
+// blockResponse describes a block, or returns its serialization in raw mode
+func (s *ServerV2) blockResponse(req *RPCRequest, block *core.Block, verbose, raw bool) *RPCResponse {
+	if raw {
+		data, err := json.Marshal(block)
+		if err != nil {
+			return &RPCResponse{
+				JSONRPC: "2.0",
+				Error: &RPCError{
+					Code:    CodeInternalError,
+					Message: "Internal error",
+					Data:    err.Error(),
+				},
+				ID: req.ID,
+			}
+		}
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Result:  hex.EncodeToString(data),
+			ID:      req.ID,
+		}
+	}
+
+	result := s.blockHeaderResult(block)
+	result["size"] = block.Size()
+	if verbose {
+		txs := make([]map[string]interface{}, len(block.Txs))
+		for i := range block.Txs {
+			txs[i] = transactionResult(&block.Txs[i])
+		}
+		result["transactions"] = txs
+	} else {
+		hashes := make([]string, len(block.Txs))
+		for i, tx := range block.Txs {
+			hashes[i] = hex.EncodeToString(tx.Hash[:])
+		}
+		result["transactions"] = hashes
+	}
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result:  result,
+		ID:      req.ID,
+	}
+}
+
+// handleGetBlockHeader returns the header of a main chain block by hash or number
+func (s *ServerV2) handleGetBlockHeader(req *RPCRequest) *RPCResponse {
+	var params BlockHeaderParams
+	if resp := decodeParams(req, &params); resp != nil {
+		return resp
+	}
+
+	var block *core.Block
+	switch {
+	case params.Hash != "":
+		hash, err := parseHashParam(params.Hash)
+		if err != nil {
+			return invalidParams(req, "invalid 'hash'")
+		}
+		if block = s.blockchain.GetBlockByHash(hash); block == nil {
+			return notFound(req, fmt.Sprintf("block %x not found", hash))
+		}
+	case params.Number != nil:
+		if block = s.blockchain.GetBlockByNumber(*params.Number); block == nil {
+			return notFound(req, fmt.Sprintf("no block at height %d", *params.Number))
+		}
+	default:
+		return invalidParams(req, "either 'hash' or 'number' is required")
+	}
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result:  s.blockHeaderResult(block),
+		ID:      req.ID,
+	}
+}
+
+// blockHeaderResult describes a block header in the format of getRecentBlocks,
+// with the block's confirmations
+func (s *ServerV2) blockHeaderResult(block *core.Block) map[string]interface{} {
+	result := map[string]interface{}{
+		"hash":          hex.EncodeToString(block.Hash[:]),
+		"number":        block.Header.Number,
+		"parentHash":    hex.EncodeToString(block.Header.ParentHash[:]),
+		"timestamp":     float64(block.Header.Timestamp.Unix()),
+		"difficulty":    block.Header.Difficulty,
+		"nonce":         block.Header.Nonce,
+		"merkleRoot":    hex.EncodeToString(block.Header.MerkleRoot[:]),
+		"txCount":       uint32(len(block.Txs)),
+		"networkFee":    block.Header.NetworkFee,
+		"treasuryFee":   block.Header.TreasuryFee,
+		"confirmations": s.confirmations(block),
+	}
+	if block.Header.Miner != (core.Address{}) {
+		result["miner"] = block.Header.Miner.String()
+	}
+	return result
+}
+
+// confirmations returns how many main chain blocks, including itself, a block has
+func (s *ServerV2) confirmations(block *core.Block) uint64 {
+	height := s.blockchain.GetHeight()
+	if block.Header.Number > height {
+		return 0
+	}
+	return height - block.Header.Number + 1
+}
+
+// handleGetTransaction returns a transaction from the mempool or the main chain
+func (s *ServerV2) handleGetTransaction(req *RPCRequest) *RPCResponse {
+	var params TransactionParams
+	if resp := decodeParams(req, &params); resp != nil {
+		return resp
+	}
+	hash, err := parseHashParam(params.Hash)
+	if err != nil {
+		return invalidParams(req, "missing or invalid 'hash'")
+	}
+
+	var result map[string]interface{}
+	tx, block := s.blockchain.GetTransaction(hash)
+	switch {
+	case tx != nil:
+		result = transactionResult(tx)
+		result["status"] = "confirmed"
+		result["blockHash"] = hex.EncodeToString(block.Hash[:])
+		result["blockNumber"] = block.Header.Number
+		result["confirmations"] = s.confirmations(block)
+	default:
+		entry := s.blockchain.GetMempool().GetEntry(hash)
+		if entry == nil {
+			return notFound(req, fmt.Sprintf("transaction %x not found", hash))
+		}
+		tx = entry.Tx
+		result = transactionResult(tx)
+		result["status"] = "pending"
+		result["confirmations"] = 0
+	}
+
+	if params.Raw {
+		raw, _ := tx.MarshalBinary()
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Result:  hex.EncodeToString(raw),
+			ID:      req.ID,
+		}
+	}
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result:  result,
+		ID:      req.ID,
+	}
+}
+
+// handleGetRawMempool lists the pending transactions, oldest first
+func (s *ServerV2) handleGetRawMempool(req *RPCRequest) *RPCResponse {
+	var params RawMempoolParams
+	if resp := decodeParams(req, &params); resp != nil {
+		return resp
+	}
+
+	entries := s.blockchain.GetMempool().GetEntries()
+	if params.Verbose {
+		result := make(map[string]interface{}, len(entries))
+		for _, entry := range entries {
+			result[hex.EncodeToString(entry.Tx.Hash[:])] = mempoolEntryResult(entry)
+		}
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Result:  result,
+			ID:      req.ID,
+		}
+	}
+
+	hashes := make([]string, len(entries))
+	for i, entry := range entries {
+		hashes[i] = hex.EncodeToString(entry.Tx.Hash[:])
+	}
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result:  hashes,
+		ID:      req.ID,
+	}
+}
+
+// handleGetMempoolEntry describes a pending transaction
+func (s *ServerV2) handleGetMempoolEntry(req *RPCRequest) *RPCResponse {
+	var params MempoolEntryParams
+	if resp := decodeParams(req, &params); resp != nil {
+		return resp
+	}
+	hash, err := parseHashParam(params.Hash)
+	if err != nil {
+		return invalidParams(req, "missing or invalid 'hash'")
+	}
+
+	entry := s.blockchain.GetMempool().GetEntry(hash)
+	if entry == nil {
+		return notFound(req, fmt.Sprintf("transaction %x not in mempool", hash))
+	}
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result:  mempoolEntryResult(entry),
+		ID:      req.ID,
+	}
+}
+
+// mempoolEntryResult describes a mempool entry
+func mempoolEntryResult(entry *core.MempoolEntry) map[string]interface{} {
+	raw, _ := entry.Tx.MarshalBinary()
+	return map[string]interface{}{
+		"size":   len(raw),
+		"fee":    entry.Tx.Fee,
+		"time":   entry.Added.Unix(),
+		"age":    time.Since(entry.Added).Round(time.Second).Seconds(),
+		"from":   hex.EncodeToString(entry.Tx.From[:]),
+		"to":     hex.EncodeToString(entry.Tx.To[:]),
+		"amount": entry.Tx.Amount,
+	}
+}
+
+// transactionResult describes a transaction
+func transactionResult(tx *core.Transaction) map[string]interface{} {
+	raw, _ := tx.MarshalBinary()
+
+	inputs := make([]map[string]interface{}, len(tx.Inputs))
+	for i, input := range tx.Inputs {
+		inputs[i] = map[string]interface{}{
+			"txHash": hex.EncodeToString(input.PreviousTxHash[:]),
+			"index":  input.Index,
+		}
+		if len(input.PublicKey) > 0 {
+			inputs[i]["publicKey"] = hex.EncodeToString(input.PublicKey)
+		}
+	}
+
+	outputs := make([]map[string]interface{}, len(tx.Outputs))
+	for i, output := range tx.Outputs {
+		outputs[i] = map[string]interface{}{
+			"address": hex.EncodeToString(output.Address[:]),
+			"amount":  output.Amount,
+		}
+	}
+
+	return map[string]interface{}{
+		"hash":      hex.EncodeToString(tx.Hash[:]),
+		"from":      hex.EncodeToString(tx.From[:]),
+		"to":        hex.EncodeToString(tx.To[:]),
+		"amount":    tx.Amount,
+		"fee":       tx.Fee,
+		"nonce":     tx.Nonce,
+		"data":      hex.EncodeToString(tx.Data),
+		"timestamp": tx.Timestamp.Unix(),
+		"coinbase":  tx.IsCoinbase(),
+		"size":      len(raw),
+		"inputs":    inputs,
+		"outputs":   outputs,
+	}
+}
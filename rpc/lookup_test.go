@@ -0,0 +1,180 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+// newTestServer creates a server on a fresh chain with one mined block
+func newTestServer(t *testing.T) (*ServerV2, *core.Block) {
+	t.Helper()
+	bc := core.NewBlockchainV2(&core.GenesisConfig{
+		BlockTimeTarget:    15,
+		InitialBlockReward: 5.0,
+		Difficulty:         core.DifficultyConfig{Window: 120, InitialDifficulty: 1},
+	}, nil)
+	block := bc.CreateNewBlockV2(core.Address{1}, nil)
+	if err := bc.AddBlockV2(block); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+	return NewServerV2("127.0.0.1:0", bc), block
+}
+
+// newConfiguredTestServer creates a server with a configuration on the chain of newTestServer
+func newConfiguredTestServer(t *testing.T, config ServerConfig) *ServerV2 {
+	t.Helper()
+	base, _ := newTestServer(t)
+	s, err := NewServerV2WithConfig(config, base.blockchain)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	return s
+}
+
+// post sends a request body to the server over HTTP from a remote address with extra
+// headers; an empty address keeps the default of httptest
+func post(s *ServerV2, remoteAddr string, header http.Header, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if remoteAddr != "" {
+		r.RemoteAddr = remoteAddr
+	}
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	s.handleRequest(w, r)
+	return w
+}
+
+// call runs a method with parameters given as JSON, as they arrive over HTTP
+func call(s *ServerV2, method, params string) *RPCResponse {
+	req := &RPCRequest{JSONRPC: "2.0", Method: method, ID: 1}
+	if params != "" {
+		json.Unmarshal([]byte(params), &req.Params)
+	}
+	return s.handleRPCMethod(req)
+}
+
+// TestBlockLookups tests getBlockByHash, getBlockByNumber and getBlockHeader
+func TestBlockLookups(t *testing.T) {
+	s, block := newTestServer(t)
+	hash := hex.EncodeToString(block.Hash[:])
+
+	resp := call(s, "getBlockByNumber", `{"number":1}`)
+	if resp.Error != nil {
+		t.Fatalf("getBlockByNumber failed: %+v", resp.Error)
+	}
+	result := resp.Result.(map[string]interface{})
+	if result["hash"] != hash || result["confirmations"] != uint64(1) {
+		t.Errorf("Unexpected block: %v", result)
+	}
+	if txs := result["transactions"].([]string); len(txs) != 1 || txs[0] != hex.EncodeToString(block.Txs[0].Hash[:]) {
+		t.Errorf("Expected transaction hashes, got %v", result["transactions"])
+	}
+
+	resp = call(s, "getBlockByHash", `{"hash":"`+hash+`","verbose":true}`)
+	if resp.Error != nil {
+		t.Fatalf("getBlockByHash failed: %+v", resp.Error)
+	}
+	if txs := resp.Result.(map[string]interface{})["transactions"].([]map[string]interface{}); txs[0]["coinbase"] != true {
+		t.Errorf("Expected full transactions in verbose mode, got %v", txs)
+	}
+
+	resp = call(s, "getBlockByHash", `{"hash":"`+hash+`","raw":true}`)
+	raw, _ := hex.DecodeString(resp.Result.(string))
+	var decoded core.Block
+	if err := json.Unmarshal(raw, &decoded); err != nil || decoded.Hash != block.Hash {
+		t.Errorf("Expected raw mode to return the encoded block: %v", err)
+	}
+
+	if resp := call(s, "getBlockHeader", `{"number":0}`); resp.Error != nil || resp.Result.(map[string]interface{})["confirmations"] != uint64(2) {
+		t.Errorf("Expected genesis header with two confirmations, got %+v", resp)
+	}
+	// The height can also be given positionally, after an empty hash
+	reply := s.handleBody([]byte(`{"jsonrpc":"2.0","method":"getBlockHeader","params":[null,0],"id":1}`), &caller{role: s.auth.roles[RoleAdmin]})
+	if !strings.Contains(string(reply), `"confirmations":2`) {
+		t.Errorf("Expected genesis header by positional number, got %s", reply)
+	}
+
+	for _, c := range []struct{ method, params string }{
+		{"getBlockByNumber", `{"number":5}`},
+		{"getBlockByHash", `{"hash":"` + hex.EncodeToString(make([]byte, 32)) + `"}`},
+	} {
+		if resp := call(s, c.method, c.params); resp.Error == nil || resp.Error.Code != CodeNotFound {
+			t.Errorf("%s %s: expected not found, got %+v", c.method, c.params, resp)
+		}
+	}
+	for _, c := range []struct{ method, params string }{
+		{"getBlockByNumber", `{}`},
+		{"getBlockByNumber", `{"number":"one"}`},
+		{"getBlockByHash", `{"hash":"xyz"}`},
+		{"getBlockHeader", `{"height":1}`},
+	} {
+		if resp := call(s, c.method, c.params); resp.Error == nil || resp.Error.Code != CodeInvalidParams {
+			t.Errorf("%s %s: expected invalid params, got %+v", c.method, c.params, resp)
+		}
+	}
+}
+
+// TestTransactionLookups tests getTransaction, getRawMempool and getMempoolEntry
+func TestTransactionLookups(t *testing.T) {
+	s, block := newTestServer(t)
+	coinbase := hex.EncodeToString(block.Txs[0].Hash[:])
+
+	resp := call(s, "getTransaction", `{"hash":"`+coinbase+`"}`)
+	if resp.Error != nil {
+		t.Fatalf("getTransaction failed: %+v", resp.Error)
+	}
+	result := resp.Result.(map[string]interface{})
+	if result["status"] != "confirmed" || result["blockNumber"] != uint64(1) || result["confirmations"] != uint64(1) {
+		t.Errorf("Unexpected confirmed transaction: %v", result)
+	}
+
+	pending := &core.Transaction{
+		From:      core.Address{1},
+		To:        core.Address{2},
+		Amount:    100,
+		Timestamp: time.Now(),
+		Inputs:    []core.TxInput{{PreviousTxHash: block.Txs[0].Hash}},
+		Outputs:   []core.TxOutput{{Address: core.Address{2}, Amount: 100}},
+	}
+	pending.Hash = pending.TxID()
+	s.blockchain.GetMempool().AddTransaction(pending)
+	pendingHash := hex.EncodeToString(pending.Hash[:])
+
+	resp = call(s, "getTransaction", `{"hash":"`+pendingHash+`"}`)
+	if resp.Error != nil || resp.Result.(map[string]interface{})["status"] != "pending" {
+		t.Errorf("Expected pending transaction, got %+v", resp)
+	}
+	resp = call(s, "getTransaction", `{"hash":"`+pendingHash+`","raw":true}`)
+	raw, _ := hex.DecodeString(resp.Result.(string))
+	var decoded core.Transaction
+	if err := decoded.UnmarshalBinary(raw); err != nil || decoded.Hash != pending.Hash {
+		t.Errorf("Expected raw mode to return the binary transaction: %v", err)
+	}
+
+	if resp := call(s, "getRawMempool", ""); len(resp.Result.([]string)) != 1 || resp.Result.([]string)[0] != pendingHash {
+		t.Errorf("Expected mempool to list the pending transaction, got %+v", resp.Result)
+	}
+	resp = call(s, "getRawMempool", `{"verbose":true}`)
+	if entry, ok := resp.Result.(map[string]interface{})[pendingHash].(map[string]interface{}); !ok || entry["amount"] != uint64(100) {
+		t.Errorf("Expected verbose mempool entry, got %+v", resp.Result)
+	}
+
+	if resp := call(s, "getMempoolEntry", `{"hash":"`+pendingHash+`"}`); resp.Error != nil {
+		t.Errorf("getMempoolEntry failed: %+v", resp.Error)
+	}
+	if resp := call(s, "getMempoolEntry", `{"hash":"`+coinbase+`"}`); resp.Error == nil || resp.Error.Code != CodeNotFound {
+		t.Errorf("Expected confirmed transaction to be absent from the mempool, got %+v", resp)
+	}
+	if resp := call(s, "getMempoolEntry", `{"hash":"`+pendingHash+`","raw":true}`); resp.Error == nil || resp.Error.Code != CodeInvalidParams {
+		t.Errorf("Expected getMempoolEntry to reject raw, got %+v", resp)
+	}
+}
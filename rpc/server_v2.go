@@ -1,1121 +1,1633 @@
-package rpc
-
-import (
-	"context"
-	"encoding/hex"
-	"encoding/json"
-	"fmt"
-	"log"
-	"net/http"
-	"runtime/debug"
-	"strings"
-	"sync"
-	"time"
-
-	"github.com/kalon-network/kalon/core"
-)
-
-// RPCRequest represents a JSON-RPC request
-type RPCRequest struct {
-	JSONRPC string      `json:"jsonrpc"`
-	Method  string      `json:"method"`
-	Params  interface{} `json:"params"`
-	ID      interface{} `json:"id"`
-}
-
-// RPCResponse represents a JSON-RPC response
-type RPCResponse struct {
-	JSONRPC string      `json:"jsonrpc"`
-	Result  interface{} `json:"result,omitempty"`
-	Error   *RPCError   `json:"error,omitempty"`
-	ID      interface{} `json:"id"`
-}
-
-// RPCError represents an RPC error
-type RPCError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Data    string `json:"data,omitempty"`
-}
-
-// ServerV2 represents a professional RPC server
-type ServerV2 struct {
-	addr        string
-	blockchain  *core.BlockchainV2
-	mu          sync.RWMutex
-	connections map[string]*Connection
-	eventBus    *core.EventBus
-	ctx         context.Context
-	cancel      context.CancelFunc
-	allowedIPs  map[string]bool       // Whitelist of allowed IPs
-	rateLimits  map[string]*RateLimit // Rate limiting per IP
-	requireAuth bool                  // Whether auth is required
-	authTokens  map[string]bool       // Valid auth tokens
-	server      *http.Server          // HTTP server instance for shutdown
-}
-
-// Connection represents a client connection
-type Connection struct {
-	ID        string
-	CreatedAt time.Time
-	LastSeen  time.Time
-	Requests  int64
-}
-
-// RateLimit tracks rate limiting for an IP
-type RateLimit struct {
-	mu             sync.RWMutex
-	Count          int
-	LastReset      time.Time
-	RequestsPerMin int
-}
-
-// NewServerV2 creates a new professional RPC server
-func NewServerV2(addr string, blockchain *core.BlockchainV2) *ServerV2 {
-	ctx, cancel := context.WithCancel(context.Background())
-
-	server := &ServerV2{
-		addr:        addr,
-		blockchain:  blockchain,
-		connections: make(map[string]*Connection),
-		eventBus:    blockchain.GetEventBus(),
-		ctx:         ctx,
-		cancel:      cancel,
-		allowedIPs:  make(map[string]bool),
-		rateLimits:  make(map[string]*RateLimit),
-		requireAuth: false, // For testnet: auth disabled by default
-		authTokens:  make(map[string]bool),
-	}
-
-	// Start connection cleanup routine
-	go server.cleanupConnections()
-
-	return server
-}
-
-// Start starts the RPC server professionally
-func (s *ServerV2) Start() error {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", s.handleRequest)
-	mux.HandleFunc("/health", s.handleHealth)
-	mux.HandleFunc("/rpc", s.handleRequest)
-
-	// Create server with professional settings
-	server := &http.Server{
-		Addr:           s.addr,
-		Handler:        s.limitConnections(mux),
-		ReadTimeout:    30 * time.Second,
-		WriteTimeout:   30 * time.Second,
-		IdleTimeout:    60 * time.Second,
-		MaxHeaderBytes: 1 << 20, // 1MB
-	}
-
-	log.Printf("🚀 Professional RPC Server starting on %s", s.addr)
-
-	// Store server reference for shutdown BEFORE starting
-	s.server = server
-
-	// Start server in goroutine
-	started := make(chan bool, 1)
-	go func() {
-		log.Printf("🔧 Attempting to bind to %s", s.addr)
-		started <- true
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("❌ RPC Server error: %v", err)
-		}
-	}()
-
-	// Wait for goroutine to start
-	<-started
-
-	// Give server time to bind to port (increased timeout)
-	time.Sleep(1 * time.Second)
-	
-	// Log success
-	log.Printf("✅ RPC Server should be listening on %s", s.addr)
-
-	// Handle shutdown in background (non-blocking)
-	go func() {
-		<-s.ctx.Done()
-		// Graceful shutdown
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if s.server != nil {
-			if err := s.server.Shutdown(ctx); err != nil {
-				log.Printf("⚠️ Error shutting down RPC server: %v", err)
-			}
-		}
-	}()
-
-	// Return immediately (non-blocking)
-	return nil
-}
-
-// Stop stops the RPC server
-func (s *ServerV2) Stop() {
-	s.cancel()
-}
-
-// handleRequest handles RPC requests professionally
-func (s *ServerV2) handleRequest(w http.ResponseWriter, r *http.Request) {
-	// CRITICAL: Panic recovery to prevent server crashes
-	defer func() {
-		if rec := recover(); rec != nil {
-			log.Printf("❌ PANIC in handleRequest: %v\nStack trace: %s", rec, debug.Stack())
-
-			// Try to write error response
-			w.Header().Set("Content-Type", "application/json")
-			errorResponse := map[string]interface{}{
-				"jsonrpc": "2.0",
-				"error": map[string]interface{}{
-					"code":    -32603,
-					"message": "Internal error",
-					"data":    fmt.Sprintf("Panic: %v", rec),
-				},
-				"id": nil,
-			}
-			if jsonBytes, err := json.Marshal(errorResponse); err == nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write(jsonBytes)
-			} else {
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
-			}
-		}
-	}()
-
-	// Extract IP
-	ip := s.extractIP(r)
-
-	// Check IP whitelist if enabled
-	if len(s.allowedIPs) > 0 && !s.allowedIPs[ip] {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-		return
-	}
-
-	// Check rate limit
-	if !s.checkRateLimit(ip) {
-		http.Error(w, "Too many requests", http.StatusTooManyRequests)
-		return
-	}
-
-	// Track connection
-	s.trackConnection(ip)
-
-	// Parse request
-	var req RPCRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.writeError(w, nil, -32700, "Parse error", err.Error())
-		return
-	}
-
-	// Handle request
-	response := s.handleRPCMethod(&req)
-
-	// Write response
-	w.Header().Set("Content-Type", "application/json")
-
-	// CRITICAL: Manual JSON encoding to prevent circular references
-	// Wrap in additional safety check
-	jsonBytes, err := json.Marshal(response)
-	if err != nil {
-		log.Printf("❌ Failed to marshal RPC response: %v", err)
-		log.Printf("❌ Response type: %T, Response: %+v", response, response)
-
-		// Write safe error response instead of crashing
-		errorResponse := map[string]interface{}{
-			"jsonrpc": "2.0",
-			"error": map[string]interface{}{
-				"code":    -32603,
-				"message": "Internal error",
-				"data":    fmt.Sprintf("JSON marshal error: %v", err),
-			},
-			"id": req.ID,
-		}
-		if safeBytes, safeErr := json.Marshal(errorResponse); safeErr == nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write(safeBytes)
-		} else {
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	if _, err := w.Write(jsonBytes); err != nil {
-		log.Printf("❌ Failed to write RPC response: %v", err)
-	}
-}
-
-// handleRPCMethod handles RPC methods professionally
-func (s *ServerV2) handleRPCMethod(req *RPCRequest) *RPCResponse {
-	switch req.Method {
-	case "getHeight":
-		return s.handleGetHeight(req)
-	case "getBestBlock":
-		return s.handleGetBestBlock(req)
-	case "getRecentBlocks":
-		return s.handleGetRecentBlocks(req)
-	case "createBlockTemplate":
-		return s.handleCreateBlockTemplateV2(req)
-	case "submitBlock":
-		return s.handleSubmitBlockV2(req)
-	case "getMiningInfo":
-		return s.handleGetMiningInfo(req)
-	case "getBalance":
-		return s.handleGetBalance(req)
-	case "sendTransaction":
-		return s.handleSendTransaction(req)
-	default:
-		return &RPCResponse{
-			JSONRPC: "2.0",
-			Error: &RPCError{
-				Code:    -32601,
-				Message: "Method not found",
-			},
-			ID: req.ID,
-		}
-	}
-}
-
-// handleGetHeight handles getHeight requests
-func (s *ServerV2) handleGetHeight(req *RPCRequest) *RPCResponse {
-	height := s.blockchain.GetHeight()
-	return &RPCResponse{
-		JSONRPC: "2.0",
-		Result:  height,
-		ID:      req.ID,
-	}
-}
-
-// handleGetBestBlock handles getBestBlock requests
-func (s *ServerV2) handleGetBestBlock(req *RPCRequest) *RPCResponse {
-	block := s.blockchain.GetBestBlock()
-	if block == nil {
-		return &RPCResponse{
-			JSONRPC: "2.0",
-			Error: &RPCError{
-				Code:    -32603,
-				Message: "Internal error",
-				Data:    "No blocks found",
-			},
-			ID: req.ID,
-		}
-	}
-
-	return &RPCResponse{
-		JSONRPC: "2.0",
-		Result: map[string]interface{}{
-			"hash":   hex.EncodeToString(block.Hash[:]),
-			"number": block.Header.Number,
-		},
-		ID: req.ID,
-	}
-}
-
-// handleGetRecentBlocks handles getRecentBlocks requests
-func (s *ServerV2) handleGetRecentBlocks(req *RPCRequest) *RPCResponse {
-	// Parse parameters
-	params, ok := req.Params.(map[string]interface{})
-	limit := 20 // Default limit
-
-	if ok {
-		if limitVal, ok := params["limit"].(float64); ok {
-			limit = int(limitVal)
-			if limit > 100 {
-				limit = 100
-			}
-			if limit < 1 {
-				limit = 1
-			}
-		}
-	}
-
-	// Get recent blocks
-	blocks := s.blockchain.GetRecentBlocks(limit)
-	if blocks == nil || len(blocks) == 0 {
-		return &RPCResponse{
-			JSONRPC: "2.0",
-			Result:  []interface{}{},
-			ID:      req.ID,
-		}
-	}
-
-	// Convert blocks to JSON-compatible format
-	result := make([]map[string]interface{}, 0, len(blocks))
-	for _, block := range blocks {
-		txCount := uint32(len(block.Txs))
-		blockMap := map[string]interface{}{
-			"hash":        hex.EncodeToString(block.Hash[:]),
-			"number":      block.Header.Number,
-			"parentHash":  hex.EncodeToString(block.Header.ParentHash[:]),
-			"timestamp":   float64(block.Header.Timestamp.Unix()),
-			"difficulty":  block.Header.Difficulty,
-			"nonce":       block.Header.Nonce,
-			"merkleRoot":  hex.EncodeToString(block.Header.MerkleRoot[:]),
-			"txCount":     txCount,
-			"networkFee":  block.Header.NetworkFee,
-			"treasuryFee": block.Header.TreasuryFee,
-			"gasUsed":     uint64(0), // Not used in Kalon
-			"gasLimit":    uint64(0), // Not used in Kalon
-		}
-
-		// Add miner if available
-		if block.Header.Miner != (core.Address{}) {
-			blockMap["miner"] = block.Header.Miner.String()
-		}
-
-		result = append(result, blockMap)
-	}
-
-	return &RPCResponse{
-		JSONRPC: "2.0",
-		Result:  result,
-		ID:      req.ID,
-	}
-}
-
-// handleCreateBlockTemplateV2 handles createBlockTemplate requests professionally
-func (s *ServerV2) handleCreateBlockTemplateV2(req *RPCRequest) *RPCResponse {
-	params, ok := req.Params.(map[string]interface{})
-	if !ok {
-		return &RPCResponse{
-			JSONRPC: "2.0",
-			Error: &RPCError{
-				Code:    -32602,
-				Message: "Invalid params",
-			},
-			ID: req.ID,
-		}
-	}
-
-	minerStr, ok := params["miner"].(string)
-	if !ok {
-		return &RPCResponse{
-			JSONRPC: "2.0",
-			Error: &RPCError{
-				Code:    -32602,
-				Message: "Invalid params",
-				Data:    "miner parameter required",
-			},
-			ID: req.ID,
-		}
-	}
-
-	// Parse miner address - handle kalon1 + hex format
-	var miner core.Address
-
-	if strings.HasPrefix(minerStr, "kalon1") {
-		// Remove "kalon1" prefix and decode hex
-		hexStr := strings.TrimPrefix(minerStr, "kalon1")
-		if len(hexStr) == 40 {
-			// Decode 40-char hex to 20 bytes
-			decodedBytes, err := hex.DecodeString(hexStr)
-			if err == nil && len(decodedBytes) == 20 {
-				copy(miner[:], decodedBytes)
-				log.Printf("✅ Parsed kalon1+hex address successfully")
-			} else {
-				log.Printf("❌ Failed to decode kalon1+hex: %v", err)
-				return &RPCResponse{
-					JSONRPC: "2.0",
-					Error:   &RPCError{Code: -32602, Message: "Invalid miner address"},
-					ID:      req.ID,
-				}
-			}
-		} else {
-			// Not a valid hex after kalon1
-			log.Printf("❌ Invalid: kalon1 address has wrong length: %d", len(hexStr))
-			return &RPCResponse{
-				JSONRPC: "2.0",
-				Error:   &RPCError{Code: -32602, Message: "Invalid miner address format"},
-				ID:      req.ID,
-			}
-		}
-	} else {
-		// Try to parse as plain 40-char hex
-		if len(minerStr) == 40 {
-			decodedBytes, err := hex.DecodeString(minerStr)
-			if err == nil && len(decodedBytes) == 20 {
-				copy(miner[:], decodedBytes)
-				log.Printf("✅ Parsed plain 40-char hex address")
-			} else {
-				log.Printf("❌ Invalid address format: %s", minerStr)
-				return &RPCResponse{
-					JSONRPC: "2.0",
-					Error:   &RPCError{Code: -32602, Message: "Invalid miner address format"},
-					ID:      req.ID,
-				}
-			}
-		} else {
-			log.Printf("❌ Invalid address format: %s (len=%d)", minerStr, len(minerStr))
-			return &RPCResponse{
-				JSONRPC: "2.0",
-				Error:   &RPCError{Code: -32602, Message: "Invalid miner address format"},
-				ID:      req.ID,
-			}
-		}
-	}
-	log.Printf("🔍 Miner address bytes: %x", miner)
-
-	// Get current blockchain state
-	bestBlock := s.blockchain.GetBestBlock()
-	if bestBlock == nil {
-		return &RPCResponse{
-			JSONRPC: "2.0",
-			Error: &RPCError{
-				Code:    -32603,
-				Message: "Internal error",
-				Data:    "No blocks found",
-			},
-			ID: req.ID,
-		}
-	}
-
-	// Create new block with rewards using CreateNewBlockV2
-	block := s.blockchain.CreateNewBlockV2(miner, []core.Transaction{})
-	log.Printf("🔍 Block created with %d transactions", len(block.Txs))
-	log.Printf("🔍 Miner address in block: %x", block.Header.Miner)
-	if len(block.Txs) > 0 && len(block.Txs[0].Outputs) > 0 {
-		log.Printf("🔍 Reward TX Output - Address: %x (40 chars: %t)", block.Txs[0].Outputs[0].Address, len(hex.EncodeToString(block.Txs[0].Outputs[0].Address[:])) == 40)
-		log.Printf("🔍 Reward TX Output - Amount: %d", block.Txs[0].Outputs[0].Amount)
-	}
-	if block == nil {
-		return &RPCResponse{
-			JSONRPC: "2.0",
-			Error: &RPCError{
-				Code:    -32603,
-				Message: "Internal error",
-				Data:    "Failed to create block template",
-			},
-			ID: req.ID,
-		}
-	}
-
-	log.Printf("🔧 Creating template for block #%d with parent hash: %x", block.Header.Number, block.Header.ParentHash)
-
-	// Serialize transactions properly for JSON response
-	txList := make([]interface{}, 0, len(block.Txs))
-	for _, tx := range block.Txs {
-		txMap := map[string]interface{}{
-			"hash":      hex.EncodeToString(tx.Hash[:]),
-			"from":      hex.EncodeToString(tx.From[:]), // Safe: hex encoding
-			"to":        hex.EncodeToString(tx.To[:]),   // Safe: hex encoding
-			"amount":    tx.Amount,
-			"fee":       tx.Fee,
-			"nonce":     tx.Nonce,
-			"timestamp": tx.Timestamp.Unix(),
-		}
-
-		// Serialize outputs
-		outputs := make([]interface{}, 0, len(tx.Outputs))
-		for _, output := range tx.Outputs {
-			outputs = append(outputs, map[string]interface{}{
-				"address": hex.EncodeToString(output.Address[:]),
-				"amount":  output.Amount,
-			})
-		}
-		txMap["outputs"] = outputs
-
-		// Serialize inputs to be complete
-		inputs := make([]interface{}, 0, len(tx.Inputs))
-		for _, input := range tx.Inputs {
-			inputs = append(inputs, map[string]interface{}{
-				"previousTxHash": hex.EncodeToString(input.PreviousTxHash[:]),
-				"index":          input.Index,
-			})
-		}
-		txMap["inputs"] = inputs
-
-		txList = append(txList, txMap)
-	}
-
-	return &RPCResponse{
-		JSONRPC: "2.0",
-		Result: map[string]interface{}{
-			"number":       block.Header.Number,
-			"difficulty":   block.Header.Difficulty,
-			"parentHash":   hex.EncodeToString(block.Header.ParentHash[:]),
-			"timestamp":    block.Header.Timestamp.Unix(),
-			"miner":        hex.EncodeToString(block.Header.Miner[:]),
-			"transactions": txList,
-		},
-		ID: req.ID,
-	}
-}
-
-// handleSubmitBlockV2 handles submitBlock requests professionally
-func (s *ServerV2) handleSubmitBlockV2(req *RPCRequest) (response *RPCResponse) {
-	defer func() {
-		if r := recover(); r != nil {
-			log.Printf("❌ PANIC in handleSubmitBlockV2: %v", r)
-			response = &RPCResponse{
-				JSONRPC: "2.0",
-				Error: &RPCError{
-					Code:    -32603,
-					Message: "Internal error",
-					Data:    fmt.Sprintf("Panic: %v", r),
-				},
-				ID: req.ID,
-			}
-		}
-	}()
-
-	params, ok := req.Params.(map[string]interface{})
-	if !ok {
-		return &RPCResponse{
-			JSONRPC: "2.0",
-			Error: &RPCError{
-				Code:    -32602,
-				Message: "Invalid params",
-			},
-			ID: req.ID,
-		}
-	}
-
-	blockData, ok := params["block"].(map[string]interface{})
-	if !ok {
-		return &RPCResponse{
-			JSONRPC: "2.0",
-			Error: &RPCError{
-				Code:    -32602,
-				Message: "Invalid params",
-				Data:    "block parameter required",
-			},
-			ID: req.ID,
-		}
-	}
-
-	// Parse block data
-	block, err := s.parseBlockData(blockData)
-	if err != nil {
-		log.Printf("❌ Failed to parse block data: %v", err)
-		return &RPCResponse{
-			JSONRPC: "2.0",
-			Error: &RPCError{
-				Code:    -32602,
-				Message: "Invalid block data",
-				Data:    err.Error(),
-			},
-			ID: req.ID,
-		}
-	}
-
-	// Submit block to blockchain using V2 function
-	if err := s.blockchain.AddBlockV2(block); err != nil {
-		log.Printf("❌ Failed to add block: %v", err)
-		return &RPCResponse{
-			JSONRPC: "2.0",
-			Error: &RPCError{
-				Code:    -32603,
-				Message: "Block submission failed",
-				Data:    err.Error(),
-			},
-			ID: req.ID,
-		}
-	}
-
-	log.Printf("✅ Block #%d submitted successfully: %x", block.Header.Number, block.Hash)
-
-	return &RPCResponse{
-		JSONRPC: "2.0",
-		Result: map[string]interface{}{
-			"success": true,
-			"hash":    hex.EncodeToString(block.Hash[:]),
-			"number":  block.Header.Number,
-		},
-		ID: req.ID,
-	}
-}
-
-// parseBlockData parses block data from RPC request
-func (s *ServerV2) parseBlockData(data map[string]interface{}) (*core.Block, error) {
-	// Parse number
-	number, ok := data["number"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("invalid number")
-	}
-
-	// Parse difficulty
-	difficulty, ok := data["difficulty"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("invalid difficulty")
-	}
-
-	// Parse nonce
-	nonce, ok := data["nonce"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("invalid nonce")
-	}
-
-	// Parse hash
-	hashStr, ok := data["hash"].(string)
-	if !ok {
-		return nil, fmt.Errorf("invalid hash")
-	}
-
-	hashBytes, err := hex.DecodeString(hashStr)
-	if err != nil || len(hashBytes) != 32 {
-		return nil, fmt.Errorf("invalid hash format")
-	}
-
-	// Parse parent hash
-	parentHashStr, ok := data["parentHash"].(string)
-	if !ok {
-		return nil, fmt.Errorf("invalid parentHash")
-	}
-
-	parentHashBytes, err := hex.DecodeString(parentHashStr)
-	if err != nil || len(parentHashBytes) != 32 {
-		return nil, fmt.Errorf("invalid parentHash format")
-	}
-
-	// Parse timestamp
-	timestamp, ok := data["timestamp"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("invalid timestamp")
-	}
-
-	// Parse transactions from block data
-	var transactions []core.Transaction
-	log.Printf("🔍 DEBUG: Parsing block data, checking for transactions...")
-	if txsData, ok := data["transactions"].([]interface{}); ok {
-		log.Printf("🔍 DEBUG: Found %d transactions in block data", len(txsData))
-		for _, txData := range txsData {
-			if txMap, ok := txData.(map[string]interface{}); ok {
-				// Parse transaction from map
-				tx := core.Transaction{}
-
-				// IMPORTANT: Parse transaction hash FIRST so it's available
-				if hashStr, ok := txMap["hash"].(string); ok {
-					if hashBytes, err := hex.DecodeString(hashStr); err == nil {
-						copy(tx.Hash[:], hashBytes)
-					}
-				}
-
-				// Parse From address
-				if fromStr, ok := txMap["from"].(string); ok {
-					tx.From = core.AddressFromString(fromStr)
-				}
-
-				// Parse To address
-				if toStr, ok := txMap["to"].(string); ok {
-					tx.To = core.AddressFromString(toStr)
-				}
-
-				// Parse Amount
-				if amount, ok := txMap["amount"].(float64); ok {
-					tx.Amount = uint64(amount)
-				}
-
-				// Parse other fields
-				if nonce, ok := txMap["nonce"].(float64); ok {
-					tx.Nonce = uint64(nonce)
-				}
-				if fee, ok := txMap["fee"].(float64); ok {
-					tx.Fee = uint64(fee)
-				}
-				if gasUsed, ok := txMap["gasUsed"].(float64); ok {
-					tx.GasUsed = uint64(gasUsed)
-				}
-				if gasPrice, ok := txMap["gasPrice"].(float64); ok {
-					tx.GasPrice = uint64(gasPrice)
-				}
-				if data, ok := txMap["data"].([]byte); ok {
-					tx.Data = data
-				}
-				if signature, ok := txMap["signature"].([]byte); ok {
-					tx.Signature = signature
-				}
-				// Parse UTXO fields
-				if inputs, ok := txMap["inputs"].([]interface{}); ok {
-					for _, inputData := range inputs {
-						if inputMap, ok := inputData.(map[string]interface{}); ok {
-							input := core.TxInput{}
-							if prevTxHashStr, ok := inputMap["previousTxHash"].(string); ok {
-								if prevTxHashBytes, err := hex.DecodeString(prevTxHashStr); err == nil {
-									copy(input.PreviousTxHash[:], prevTxHashBytes)
-								}
-							}
-							if index, ok := inputMap["index"].(float64); ok {
-								input.Index = uint32(index)
-							}
-							if signature, ok := inputMap["signature"].([]byte); ok {
-								input.Signature = signature
-							}
-							tx.Inputs = append(tx.Inputs, input)
-						}
-					}
-				}
-
-				if outputs, ok := txMap["outputs"].([]interface{}); ok {
-					for _, outputData := range outputs {
-						if outputMap, ok := outputData.(map[string]interface{}); ok {
-							output := core.TxOutput{}
-							if addressValue, ok := outputMap["address"]; ok {
-								// Parse address from various formats
-								var address core.Address
-								addressSet := false
-
-								// Try to parse as string (40-char hex or kalon1... format)
-								if addressStr, ok := addressValue.(string); ok {
-									// If it's a hex-encoded address (like from Miner)
-									if len(addressStr) == 40 && isHexString(addressStr) {
-										if decoded, err := hex.DecodeString(addressStr); err == nil && len(decoded) == 20 {
-											copy(address[:], decoded)
-											addressSet = true
-										}
-									} else {
-										// Use AddressFromString for other formats
-										address = core.AddressFromString(addressStr)
-										addressSet = true
-									}
-								}
-
-								// Try to parse as array of numbers
-								if !addressSet {
-									if addressBytes, ok := addressValue.([]interface{}); ok && len(addressBytes) == 20 {
-										var addrBytes []byte
-										for _, b := range addressBytes {
-											if byteVal, ok := b.(float64); ok {
-												addrBytes = append(addrBytes, byte(byteVal))
-											}
-										}
-										if len(addrBytes) == 20 {
-											copy(address[:], addrBytes)
-											addressSet = true
-										}
-									}
-								}
-
-								if addressSet {
-									output.Address = address
-								}
-							}
-							if amount, ok := outputMap["amount"].(float64); ok {
-								output.Amount = uint64(amount)
-							}
-							tx.Outputs = append(tx.Outputs, output)
-						}
-					}
-				}
-
-				// Parse timestamp
-				if timestamp, ok := txMap["timestamp"].(string); ok {
-					if t, err := time.Parse(time.RFC3339, timestamp); err == nil {
-						tx.Timestamp = t
-					}
-				}
-
-				// Calculate transaction hash if not set
-				if tx.Hash == (core.Hash{}) {
-					tx.Hash = core.CalculateTransactionHash(&tx)
-				}
-
-				transactions = append(transactions, tx)
-				log.Printf("💰 Parsed transaction with %d outputs, total amount: %d, hash: %x", len(tx.Outputs), tx.Amount, tx.Hash)
-			}
-		}
-	} else {
-		log.Printf("⚠️ DEBUG: No transactions found in block data!")
-	}
-
-	log.Printf("🔍 DEBUG: Total transactions parsed: %d", len(transactions))
-
-	// Create block with transactions
-	block := &core.Block{
-		Header: core.BlockHeader{
-			Number:     uint64(number),
-			Difficulty: uint64(difficulty),
-			Nonce:      uint64(nonce),
-			Timestamp:  time.Unix(int64(timestamp), 0),
-		},
-		Txs: transactions, // Use parsed transactions
-	}
-
-	// Copy hashes
-	copy(block.Hash[:], hashBytes)
-	copy(block.Header.ParentHash[:], parentHashBytes)
-
-	return block, nil
-}
-
-// handleGetMiningInfo handles getMiningInfo requests
-func (s *ServerV2) handleGetMiningInfo(req *RPCRequest) *RPCResponse {
-	bestBlock := s.blockchain.GetBestBlock()
-	if bestBlock == nil {
-		return &RPCResponse{
-			JSONRPC: "2.0",
-			Error: &RPCError{
-				Code:    -32603,
-				Message: "Internal error",
-				Data:    "No blocks found",
-			},
-			ID: req.ID,
-		}
-	}
-
-	// Use ConsensusManager to calculate difficulty (uses Genesis config)
-	consensusManager := core.NewConsensusManager(s.blockchain.GetGenesis())
-	difficulty := consensusManager.CalculateDifficulty(bestBlock.Header.Number+1, bestBlock)
-
-	return &RPCResponse{
-		JSONRPC: "2.0",
-		Result: map[string]interface{}{
-			"height":     s.blockchain.GetHeight(),
-			"difficulty": difficulty,
-			"bestBlock":  hex.EncodeToString(bestBlock.Hash[:]),
-		},
-		ID: req.ID,
-	}
-}
-
-// handleGetBalance handles getBalance requests
-func (s *ServerV2) handleGetBalance(req *RPCRequest) *RPCResponse {
-	// Parse parameters
-	params, ok := req.Params.(map[string]interface{})
-	if !ok {
-		return &RPCResponse{
-			JSONRPC: "2.0",
-			Error: &RPCError{
-				Code:    -32602,
-				Message: "Invalid params",
-				Data:    "Expected object with 'address' field",
-			},
-			ID: req.ID,
-		}
-	}
-
-	addressStr, ok := params["address"].(string)
-	if !ok {
-		return &RPCResponse{
-			JSONRPC: "2.0",
-			Error: &RPCError{
-				Code:    -32602,
-				Message: "Invalid params",
-				Data:    "Missing or invalid 'address' field",
-			},
-			ID: req.ID,
-		}
-	}
-
-	// Convert string address to Address type
-	address := core.AddressFromString(addressStr)
-
-	// Get balance from blockchain
-	balance := s.blockchain.GetBalance(address)
-
-	// Debug logging
-	log.Printf("🔍 Balance query - Address: %s, Parsed: %s, Balance: %d", addressStr, hex.EncodeToString(address[:]), balance)
-
-	return &RPCResponse{
-		JSONRPC: "2.0",
-		Result:  balance,
-		ID:      req.ID,
-	}
-}
-
-// handleSendTransaction handles sendTransaction requests
-func (s *ServerV2) handleSendTransaction(req *RPCRequest) *RPCResponse {
-	params, ok := req.Params.(map[string]interface{})
-	if !ok {
-		return &RPCResponse{
-			JSONRPC: "2.0",
-			Error: &RPCError{
-				Code:    -32602,
-				Message: "Invalid params",
-			},
-			ID: req.ID,
-		}
-	}
-
-	// Parse transaction fields
-	fromStr, _ := params["from"].(string)
-	toStr, _ := params["to"].(string)
-	amount, _ := params["amount"].(float64)
-	fee, _ := params["fee"].(float64)
-
-	if fromStr == "" || toStr == "" || amount == 0 {
-		return &RPCResponse{
-			JSONRPC: "2.0",
-			Error: &RPCError{
-				Code:    -32602,
-				Message: "Invalid params",
-				Data:    "from, to and amount are required",
-			},
-			ID: req.ID,
-		}
-	}
-
-	// Get addresses
-	fromAddr := core.AddressFromString(fromStr)
-	toAddr := core.AddressFromString(toStr)
-
-	// Create transaction from UTXOs
-	tx, err := s.blockchain.CreateTransaction(fromAddr, toAddr, uint64(amount), uint64(fee))
-	if err != nil {
-		return &RPCResponse{
-			JSONRPC: "2.0",
-			Error: &RPCError{
-				Code:    -32603,
-				Message: "Transaction creation failed",
-				Data:    err.Error(),
-			},
-			ID: req.ID,
-		}
-	}
-
-	log.Printf("📤 Transaction created - From: %s, To: %s, Amount: %d, Hash: %x", fromStr, toStr, tx.Amount, tx.Hash)
-
-	// Add to mempool
-	s.blockchain.GetMempool().AddTransaction(tx)
-
-	// Return transaction hash
-	return &RPCResponse{
-		JSONRPC: "2.0",
-		Result: map[string]interface{}{
-			"txHash": hex.EncodeToString(tx.Hash[:]),
-			"status": "pending",
-		},
-		ID: req.ID,
-	}
-}
-
-// handleHealth handles health check requests
-func (s *ServerV2) handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":    "ok",
-		"timestamp": time.Now(),
-		"version":   "2.0",
-		"height":    s.blockchain.GetHeight(),
-	})
-}
-
-// writeError writes an error response
-func (s *ServerV2) writeError(w http.ResponseWriter, id interface{}, code int, message, data string) {
-	response := RPCResponse{
-		JSONRPC: "2.0",
-		Error: &RPCError{
-			Code:    code,
-			Message: message,
-			Data:    data,
-		},
-		ID: id,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-// limitConnections limits concurrent connections professionally
-func (s *ServerV2) limitConnections(h http.Handler) http.Handler {
-	semaphore := make(chan struct{}, 50) // Max 50 concurrent connections
-
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		select {
-		case semaphore <- struct{}{}:
-			defer func() { <-semaphore }()
-			h.ServeHTTP(w, r)
-		default:
-			http.Error(w, "Too many connections", http.StatusServiceUnavailable)
-		}
-	})
-}
-
-// trackConnection tracks a client connection
-func (s *ServerV2) trackConnection(connID string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if conn, exists := s.connections[connID]; exists {
-		conn.LastSeen = time.Now()
-		conn.Requests++
-	} else {
-		s.connections[connID] = &Connection{
-			ID:        connID,
-			CreatedAt: time.Now(),
-			LastSeen:  time.Now(),
-			Requests:  1,
-		}
-	}
-}
-
-// cleanupConnections cleans up old connections
-func (s *ServerV2) cleanupConnections() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			s.mu.Lock()
-			now := time.Now()
-			for id, conn := range s.connections {
-				if now.Sub(conn.LastSeen) > 10*time.Minute {
-					delete(s.connections, id)
-				}
-			}
-			s.mu.Unlock()
-		case <-s.ctx.Done():
-			return
-		}
-	}
-}
-
-// Helper function to check if string is hex
-func isHexString(s string) bool {
-	for _, c := range s {
-		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')) {
-			return false
-		}
-	}
-	return true
-}
-
-// extractIP extracts the client IP from the request
-func (s *ServerV2) extractIP(r *http.Request) string {
-	ip := r.Header.Get("X-Forwarded-For")
-	if ip == "" {
-		ip = r.Header.Get("X-Real-Ip")
-	}
-	if ip == "" {
-		ip = strings.Split(r.RemoteAddr, ":")[0]
-	}
-	return ip
-}
-
-// checkRateLimit checks if the request rate is within limits
-func (s *ServerV2) checkRateLimit(ip string) bool {
-	// CRITICAL: Allow unlimited requests from localhost FIRST (before any locking)
-	// This prevents rate limiting during local testing
-	if ip == "127.0.0.1" || ip == "::1" {
-		return true
-	}
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	// Get or create rate limit for this IP
-	limit, exists := s.rateLimits[ip]
-	if !exists {
-		limit = &RateLimit{
-			Count:          0,
-			LastReset:      time.Now(),
-			RequestsPerMin: 600, // Increased to 600 requests per minute for testing
-		}
-		s.rateLimits[ip] = limit
-	}
-
-	// Reset counter if more than a minute has passed
-	if time.Since(limit.LastReset) > time.Minute {
-		limit.Count = 0
-		limit.LastReset = time.Now()
-	}
-
-	// Check if limit exceeded
-	if limit.Count >= limit.RequestsPerMin {
-		return false
-	}
-
-	// Increment counter
-	limit.Count++
-	return true
-}
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+	"github.com/kalon-network/kalon/network"
+)
+
+// RPCRequest represents a JSON-RPC request
+type RPCRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+	ID      interface{} `json:"id"`
+}
+
+// RPCResponse represents a JSON-RPC response
+type RPCResponse struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   *RPCError   `json:"error,omitempty"`
+	ID      interface{} `json:"id"`
+}
+
+// RPCError represents an RPC error
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+// ServerV2 represents a professional RPC server
+type ServerV2 struct {
+	addr        string
+	blockchain  *core.BlockchainV2
+	mu          sync.RWMutex
+	connections map[string]*Connection
+	eventBus    *core.EventBus
+	ctx         context.Context
+	cancel      context.CancelFunc
+	access      *accessControl        // Allowed clients, trusted proxies and limits
+	rateLimits  map[string]*RateLimit // Rate limiting per IP
+	inFlight    atomic.Int64          // Requests being served
+	auth        *authenticator        // Credentials and the methods each role may call
+	dataDir     string                // Where the auth cookie is written
+	cookie      bool                  // Whether to write an auth cookie on start
+	server      *http.Server          // HTTP server instance for shutdown
+	unixServer  *http.Server          // Server on the Unix socket, nil if there is none
+	listenAddr  net.Addr              // Address the server is bound to
+	useTLS      bool                  // Whether to serve HTTPS
+	tlsCert     string                // Certificate and key files, empty for the self-signed certificate
+	tlsKey      string                // Key file of tlsCert
+	socketPath  string                // Unix socket to serve on, empty for none
+	socketMode  os.FileMode           // Permissions of the Unix socket
+	minPeers    int                   // Peers required to be ready
+	maxBodySize int64                 // Maximum accepted request body size
+	p2p         *network.P2P          // Peer network for relaying accepted blocks and transactions
+	wsMu        sync.Mutex
+	wsClients   map[*wsClient]struct{} // Open WebSocket connections
+
+	templatesMu   sync.Mutex
+	templates     map[string]*blockTemplate // Issued block templates by id
+	templateOrder []string                  // Template ids, oldest first
+}
+
+// Connection represents a client connection
+type Connection struct {
+	ID        string
+	CreatedAt time.Time
+	LastSeen  time.Time
+	Requests  int64
+}
+
+// RateLimit tracks rate limiting for an IP
+type RateLimit struct {
+	mu             sync.RWMutex
+	Count          int
+	LastReset      time.Time
+	RequestsPerMin int
+}
+
+// shutdownTimeout bounds how long Stop waits for requests in progress; it matches
+// the write timeout, which no request outlives
+const shutdownTimeout = 30 * time.Second
+
+// ServerConfig configures an RPC server
+type ServerConfig struct {
+	Addr    string
+	DataDir string       // Where the auth cookie is written; empty disables the cookie
+	Cookie  bool         // Write an admin cookie to DataDir on start for local tools
+	Auth    AuthConfig   // Static credentials, roles and the role of anonymous requests
+	Access  AccessConfig // Allowed clients, trusted proxies, rate limits and concurrency
+
+	TLS     bool   // Serve HTTPS instead of HTTP
+	TLSCert string // Certificate file; without one a self-signed certificate is kept in DataDir
+	TLSKey  string // Key file of TLSCert
+
+	UnixSocket string      // Path of a Unix socket to serve on in addition to Addr, none if empty
+	SocketMode os.FileMode // Permissions of the Unix socket, DefaultSocketMode if zero
+
+	ReadyMinPeers int // Peers the node needs before /readyz reports ready
+}
+
+// NewServerV2 creates a new professional RPC server
+func NewServerV2(addr string, blockchain *core.BlockchainV2) *ServerV2 {
+	// The default configuration is always valid
+	server, _ := NewServerV2WithConfig(ServerConfig{Addr: addr}, blockchain)
+	return server
+}
+
+// NewServerV2WithConfig creates an RPC server from a configuration
+func NewServerV2WithConfig(config ServerConfig, blockchain *core.BlockchainV2) (*ServerV2, error) {
+	auth, err := newAuthenticator(config.Auth)
+	if err != nil {
+		return nil, err
+	}
+	access, err := newAccessControl(config.Access)
+	if err != nil {
+		return nil, err
+	}
+	if config.Cookie && config.DataDir == "" {
+		return nil, fmt.Errorf("cookie authentication requires a data directory")
+	}
+	if (config.TLSCert == "") != (config.TLSKey == "") {
+		return nil, fmt.Errorf("a TLS certificate and key must be given together")
+	}
+	if config.TLS && config.TLSCert == "" && config.DataDir == "" {
+		return nil, fmt.Errorf("a self-signed TLS certificate requires a data directory")
+	}
+	if config.SocketMode == 0 {
+		config.SocketMode = DefaultSocketMode
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	addr := config.Addr
+
+	server := &ServerV2{
+		addr:        addr,
+		blockchain:  blockchain,
+		connections: make(map[string]*Connection),
+		eventBus:    blockchain.GetEventBus(),
+		ctx:         ctx,
+		cancel:      cancel,
+		access:      access,
+		rateLimits:  make(map[string]*RateLimit),
+		auth:        auth,
+		dataDir:     config.DataDir,
+		cookie:      config.Cookie,
+		useTLS:      config.TLS,
+		tlsCert:     config.TLSCert,
+		tlsKey:      config.TLSKey,
+		socketPath:  config.UnixSocket,
+		socketMode:  config.SocketMode,
+		minPeers:    config.ReadyMinPeers,
+		wsClients:   make(map[*wsClient]struct{}),
+		templates:   make(map[string]*blockTemplate),
+		// A submitted block is re-encoded as JSON params, so allow twice the block limit
+		maxBodySize: int64(blockchain.GetGenesis().MaxScheduledBlockBytes())*2 + 64*1024,
+	}
+
+	// Start connection cleanup routine
+	go server.cleanupConnections()
+
+	return server, nil
+}
+
+// SetP2P sets the peer network used to relay accepted blocks and transactions
+func (s *ServerV2) SetP2P(p2p *network.P2P) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.p2p = p2p
+}
+
+// getP2P returns the peer network, or nil if the node runs without one
+func (s *ServerV2) getP2P() *network.P2P {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.p2p
+}
+
+// Start binds the RPC listeners and serves them in the background. It returns an
+// error if a listener cannot be bound, in which case nothing is left running.
+func (s *ServerV2) Start() error {
+	var tlsConfig *tls.Config
+	if s.useTLS {
+		var err error
+		if tlsConfig, err = newServerTLSConfig(s.tlsCert, s.tlsKey, s.dataDir, s.addr); err != nil {
+			return err
+		}
+		if s.tlsCert == "" {
+			log.Printf("🔒 RPC uses the self-signed certificate %s", filepath.Join(s.dataDir, TLSCertFile))
+		}
+	}
+
+	calls := http.NewServeMux()
+	calls.HandleFunc("/", s.handleRequest)
+	calls.HandleFunc("/rpc", s.handleRequest)
+	calls.HandleFunc("/ws", s.handleWebSocket)
+
+	// Health checks stay outside the concurrency limit: long polls can hold every
+	// slot, and a busy node must not look dead to its orchestrator
+	mux := http.NewServeMux()
+	mux.Handle("/", s.limitConnections(calls))
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/livez", s.handleLivez)
+	mux.HandleFunc("/readyz", s.handleReadyz)
+
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", s.addr, err)
+	}
+
+	// Local tools can use the Unix socket, whose file mode decides who may connect
+	var unixListener net.Listener
+	if s.socketPath != "" {
+		if unixListener, err = listenUnix(s.socketPath, s.socketMode); err != nil {
+			listener.Close()
+			return fmt.Errorf("failed to listen on RPC socket: %v", err)
+		}
+	}
+
+	// The cookie is only written once the server can be reached with it
+	if s.cookie {
+		if err := s.auth.writeCookie(s.dataDir); err != nil {
+			listener.Close()
+			if unixListener != nil {
+				unixListener.Close()
+			}
+			return err
+		}
+		log.Printf("🔑 RPC cookie written to %s", filepath.Join(s.dataDir, CookieFile))
+	}
+
+	s.mu.Lock()
+	s.listenAddr = listener.Addr()
+	s.server = newHTTPServer(mux, tlsConfig)
+	if unixListener != nil {
+		s.unixServer = newHTTPServer(unixSocketHandler(mux), nil)
+	}
+	s.mu.Unlock()
+
+	go serveHTTP(s.server, listener, "RPC server")
+	if unixListener != nil {
+		go serveHTTP(s.unixServer, unixListener, "RPC socket")
+		log.Printf("🔌 RPC listening on Unix socket %s (mode %04o)", s.socketPath, s.socketMode)
+	}
+
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+	log.Printf("✅ RPC Server listening on %s (%s)", listener.Addr(), scheme)
+	return nil
+}
+
+// newHTTPServer creates an HTTP server with professional settings
+func newHTTPServer(handler http.Handler, tlsConfig *tls.Config) *http.Server {
+	return &http.Server{
+		Handler:        handler,
+		TLSConfig:      tlsConfig,
+		ReadTimeout:    30 * time.Second,
+		WriteTimeout:   30 * time.Second,
+		IdleTimeout:    60 * time.Second,
+		MaxHeaderBytes: 1 << 20, // 1MB
+	}
+}
+
+// serveHTTP serves a bound listener until the server shuts down
+func serveHTTP(server *http.Server, listener net.Listener, name string) {
+	var err error
+	if server.TLSConfig != nil {
+		// The certificate is already in TLSConfig
+		err = server.ServeTLS(listener, "", "")
+	} else {
+		err = server.Serve(listener)
+	}
+	if err != nil && err != http.ErrServerClosed {
+		log.Printf("❌ %s error: %v", name, err)
+	}
+}
+
+// Addr returns the address the RPC server is bound to, or nil before Start
+func (s *ServerV2) Addr() net.Addr {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.listenAddr
+}
+
+// Stop stops accepting requests and waits for those in progress to finish. Long
+// polls return as soon as the server stops; WebSocket clients are disconnected.
+func (s *ServerV2) Stop() {
+	s.cancel()
+
+	// Hijacked WebSocket connections are not closed by Shutdown
+	s.wsMu.Lock()
+	clients := make([]*wsClient, 0, len(s.wsClients))
+	for client := range s.wsClients {
+		clients = append(clients, client)
+	}
+	s.wsMu.Unlock()
+	for _, client := range clients {
+		client.close(closeGoingAway, "server shutting down")
+	}
+
+	s.mu.RLock()
+	servers := []*http.Server{s.server, s.unixServer}
+	s.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		if server == nil {
+			continue
+		}
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("⚠️ Error shutting down RPC server: %v", err)
+		}
+	}
+
+	s.auth.removeCookie(s.dataDir)
+}
+
+// handleRequest handles RPC requests professionally
+func (s *ServerV2) handleRequest(w http.ResponseWriter, r *http.Request) {
+	// CRITICAL: Panic recovery to prevent server crashes
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("❌ PANIC in handleRequest: %v\nStack trace: %s", rec, debug.Stack())
+
+			// Try to write error response
+			w.Header().Set("Content-Type", "application/json")
+			errorResponse := map[string]interface{}{
+				"jsonrpc": "2.0",
+				"error": map[string]interface{}{
+					"code":    CodeInternalError,
+					"message": "Internal error",
+					"data":    fmt.Sprintf("Panic: %v", rec),
+				},
+				"id": nil,
+			}
+			if jsonBytes, err := json.Marshal(errorResponse); err == nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write(jsonBytes)
+			} else {
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+			}
+		}
+	}()
+
+	// Extract IP
+	ip := s.extractIP(r)
+
+	// Check the allowed networks
+	if !s.clientAllowed(ip) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Reject clients whose budget is used up; each call is charged by its method
+	if !s.checkRateLimit(ip, 0) {
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+		return
+	}
+
+	// Track connection
+	s.trackConnection(ip)
+
+	// Authenticate before reading the body
+	role, err := s.auth.authenticate(r)
+	if err != nil {
+		s.writeUnauthorized(w, err)
+		return
+	}
+
+	// Limit request body size
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxBodySize)
+
+	// Read the single or batch request
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			s.writeError(w, nil, CodeInvalidRequest, "Invalid Request", fmt.Sprintf("request body exceeds %d bytes", s.maxBodySize))
+			return
+		}
+		s.writeError(w, nil, CodeParseError, "Parse error", err.Error())
+		return
+	}
+
+	// Handle request; notifications are not answered
+	reply := s.handleBody(body, &caller{ip: ip, role: role})
+	if reply == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(reply); err != nil {
+		log.Printf("❌ Failed to write RPC response: %v", err)
+	}
+}
+
+// handleRPCMethod handles RPC methods professionally
+func (s *ServerV2) handleRPCMethod(req *RPCRequest) *RPCResponse {
+	switch req.Method {
+	case "getHeight":
+		return s.handleGetHeight(req)
+	case "getBestBlock":
+		return s.handleGetBestBlock(req)
+	case "getRecentBlocks":
+		return s.handleGetRecentBlocks(req)
+	case "getBlockByHash":
+		return s.handleGetBlockByHash(req)
+	case "getBlockByNumber":
+		return s.handleGetBlockByNumber(req)
+	case "getBlockHeader":
+		return s.handleGetBlockHeader(req)
+	case "getTransaction":
+		return s.handleGetTransaction(req)
+	case "getRawMempool":
+		return s.handleGetRawMempool(req)
+	case "getMempoolEntry":
+		return s.handleGetMempoolEntry(req)
+	case "createBlockTemplate":
+		return s.handleCreateBlockTemplateV2(req)
+	case "getBlockTemplate":
+		return s.handleGetBlockTemplate(req)
+	case "submitBlock":
+		return s.handleSubmitBlockV2(req)
+	case "getMiningInfo":
+		return s.handleGetMiningInfo(req)
+	case "getUpgrades":
+		return s.handleGetUpgrades(req)
+	case "getSyncStatus":
+		return s.handleGetSyncStatus(req)
+	case "listBanned":
+		return s.handleListBanned(req)
+	case "setBan":
+		return s.handleSetBan(req)
+	case "clearBanned":
+		return s.handleClearBanned(req)
+	case "getBalance":
+		return s.handleGetBalance(req)
+	case "sendTransaction":
+		return s.handleSendTransaction(req)
+	case "sendRawTransaction":
+		return s.handleSendRawTransaction(req)
+	case "createRawTransaction":
+		return s.handleCreateRawTransaction(req)
+	case "fundRawTransaction":
+		return s.handleFundRawTransaction(req)
+	case "getRPCInfo":
+		return s.handleGetRPCInfo(req)
+	case "subscribe", "unsubscribe":
+		return errorResponse(req.ID, CodeMethodNotFound, "Method not found", "subscriptions require a WebSocket connection to /ws")
+	default:
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeMethodNotFound,
+				Message: "Method not found",
+			},
+			ID: req.ID,
+		}
+	}
+}
+
+// handleGetHeight handles getHeight requests
+func (s *ServerV2) handleGetHeight(req *RPCRequest) *RPCResponse {
+	height := s.blockchain.GetHeight()
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result:  height,
+		ID:      req.ID,
+	}
+}
+
+// handleGetBestBlock handles getBestBlock requests
+func (s *ServerV2) handleGetBestBlock(req *RPCRequest) *RPCResponse {
+	block := s.blockchain.GetBestBlock()
+	if block == nil {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInternalError,
+				Message: "Internal error",
+				Data:    "No blocks found",
+			},
+			ID: req.ID,
+		}
+	}
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"hash":   hex.EncodeToString(block.Hash[:]),
+			"number": block.Header.Number,
+		},
+		ID: req.ID,
+	}
+}
+
+// handleGetRecentBlocks handles getRecentBlocks requests
+func (s *ServerV2) handleGetRecentBlocks(req *RPCRequest) *RPCResponse {
+	// Parse parameters
+	params, ok := req.Params.(map[string]interface{})
+	limit := 20 // Default limit
+
+	if ok {
+		if limitVal, ok := params["limit"].(float64); ok {
+			limit = int(limitVal)
+			if limit > 100 {
+				limit = 100
+			}
+			if limit < 1 {
+				limit = 1
+			}
+		}
+	}
+
+	// Get recent blocks
+	blocks := s.blockchain.GetRecentBlocks(limit)
+	if blocks == nil || len(blocks) == 0 {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Result:  []interface{}{},
+			ID:      req.ID,
+		}
+	}
+
+	// Convert blocks to JSON-compatible format
+	result := make([]map[string]interface{}, 0, len(blocks))
+	for _, block := range blocks {
+		txCount := uint32(len(block.Txs))
+		blockMap := map[string]interface{}{
+			"hash":        hex.EncodeToString(block.Hash[:]),
+			"number":      block.Header.Number,
+			"parentHash":  hex.EncodeToString(block.Header.ParentHash[:]),
+			"timestamp":   float64(block.Header.Timestamp.Unix()),
+			"difficulty":  block.Header.Difficulty,
+			"nonce":       block.Header.Nonce,
+			"merkleRoot":  hex.EncodeToString(block.Header.MerkleRoot[:]),
+			"txCount":     txCount,
+			"networkFee":  block.Header.NetworkFee,
+			"treasuryFee": block.Header.TreasuryFee,
+			"gasUsed":     uint64(0), // Not used in Kalon
+			"gasLimit":    uint64(0), // Not used in Kalon
+		}
+
+		// Add miner if available
+		if block.Header.Miner != (core.Address{}) {
+			blockMap["miner"] = block.Header.Miner.String()
+		}
+
+		result = append(result, blockMap)
+	}
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result:  result,
+		ID:      req.ID,
+	}
+}
+
+// handleCreateBlockTemplateV2 handles createBlockTemplate requests professionally
+func (s *ServerV2) handleCreateBlockTemplateV2(req *RPCRequest) *RPCResponse {
+	params, ok := req.Params.(map[string]interface{})
+	if !ok {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInvalidParams,
+				Message: "Invalid params",
+			},
+			ID: req.ID,
+		}
+	}
+
+	minerStr, ok := params["miner"].(string)
+	if !ok {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInvalidParams,
+				Message: "Invalid params",
+				Data:    "miner parameter required",
+			},
+			ID: req.ID,
+		}
+	}
+
+	// Parse miner address - handle kalon1 + hex format
+	var miner core.Address
+
+	if strings.HasPrefix(minerStr, "kalon1") {
+		// Remove "kalon1" prefix and decode hex
+		hexStr := strings.TrimPrefix(minerStr, "kalon1")
+		if len(hexStr) == 40 {
+			// Decode 40-char hex to 20 bytes
+			decodedBytes, err := hex.DecodeString(hexStr)
+			if err == nil && len(decodedBytes) == 20 {
+				copy(miner[:], decodedBytes)
+				log.Printf("✅ Parsed kalon1+hex address successfully")
+			} else {
+				log.Printf("❌ Failed to decode kalon1+hex: %v", err)
+				return &RPCResponse{
+					JSONRPC: "2.0",
+					Error:   &RPCError{Code: CodeInvalidParams, Message: "Invalid miner address"},
+					ID:      req.ID,
+				}
+			}
+		} else {
+			// Not a valid hex after kalon1
+			log.Printf("❌ Invalid: kalon1 address has wrong length: %d", len(hexStr))
+			return &RPCResponse{
+				JSONRPC: "2.0",
+				Error:   &RPCError{Code: CodeInvalidParams, Message: "Invalid miner address format"},
+				ID:      req.ID,
+			}
+		}
+	} else {
+		// Try to parse as plain 40-char hex
+		if len(minerStr) == 40 {
+			decodedBytes, err := hex.DecodeString(minerStr)
+			if err == nil && len(decodedBytes) == 20 {
+				copy(miner[:], decodedBytes)
+				log.Printf("✅ Parsed plain 40-char hex address")
+			} else {
+				log.Printf("❌ Invalid address format: %s", minerStr)
+				return &RPCResponse{
+					JSONRPC: "2.0",
+					Error:   &RPCError{Code: CodeInvalidParams, Message: "Invalid miner address format"},
+					ID:      req.ID,
+				}
+			}
+		} else {
+			log.Printf("❌ Invalid address format: %s (len=%d)", minerStr, len(minerStr))
+			return &RPCResponse{
+				JSONRPC: "2.0",
+				Error:   &RPCError{Code: CodeInvalidParams, Message: "Invalid miner address format"},
+				ID:      req.ID,
+			}
+		}
+	}
+	log.Printf("🔍 Miner address bytes: %x", miner)
+
+	// Get current blockchain state
+	bestBlock := s.blockchain.GetBestBlock()
+	if bestBlock == nil {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInternalError,
+				Message: "Internal error",
+				Data:    "No blocks found",
+			},
+			ID: req.ID,
+		}
+	}
+
+	// Create new block with rewards using CreateNewBlockV2
+	block := s.blockchain.CreateNewBlockV2(miner, []core.Transaction{})
+	log.Printf("🔍 Block created with %d transactions", len(block.Txs))
+	log.Printf("🔍 Miner address in block: %x", block.Header.Miner)
+	if len(block.Txs) > 0 && len(block.Txs[0].Outputs) > 0 {
+		log.Printf("🔍 Reward TX Output - Address: %x (40 chars: %t)", block.Txs[0].Outputs[0].Address, len(hex.EncodeToString(block.Txs[0].Outputs[0].Address[:])) == 40)
+		log.Printf("🔍 Reward TX Output - Amount: %d", block.Txs[0].Outputs[0].Amount)
+	}
+	if block == nil {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInternalError,
+				Message: "Internal error",
+				Data:    "Failed to create block template",
+			},
+			ID: req.ID,
+		}
+	}
+
+	log.Printf("🔧 Creating template for block #%d with parent hash: %x", block.Header.Number, block.Header.ParentHash)
+
+	// Serialize transactions properly for JSON response
+	txList := make([]interface{}, 0, len(block.Txs))
+	for _, tx := range block.Txs {
+		txMap := map[string]interface{}{
+			"hash":      hex.EncodeToString(tx.Hash[:]),
+			"from":      hex.EncodeToString(tx.From[:]), // Safe: hex encoding
+			"to":        hex.EncodeToString(tx.To[:]),   // Safe: hex encoding
+			"amount":    tx.Amount,
+			"fee":       tx.Fee,
+			"nonce":     tx.Nonce,
+			"timestamp": tx.Timestamp.Unix(),
+		}
+
+		// Serialize outputs
+		outputs := make([]interface{}, 0, len(tx.Outputs))
+		for _, output := range tx.Outputs {
+			outputs = append(outputs, map[string]interface{}{
+				"address": hex.EncodeToString(output.Address[:]),
+				"amount":  output.Amount,
+			})
+		}
+		txMap["outputs"] = outputs
+
+		// Serialize inputs to be complete
+		inputs := make([]interface{}, 0, len(tx.Inputs))
+		for _, input := range tx.Inputs {
+			inputs = append(inputs, map[string]interface{}{
+				"previousTxHash": hex.EncodeToString(input.PreviousTxHash[:]),
+				"index":          input.Index,
+			})
+		}
+		txMap["inputs"] = inputs
+
+		txList = append(txList, txMap)
+	}
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"number":       block.Header.Number,
+			"difficulty":   block.Header.Difficulty,
+			"parentHash":   hex.EncodeToString(block.Header.ParentHash[:]),
+			"timestamp":    block.Header.Timestamp.Unix(),
+			"miner":        hex.EncodeToString(block.Header.Miner[:]),
+			"transactions": txList,
+		},
+		ID: req.ID,
+	}
+}
+
+// handleSubmitBlockV2 handles submitBlock requests professionally
+func (s *ServerV2) handleSubmitBlockV2(req *RPCRequest) (response *RPCResponse) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ PANIC in handleSubmitBlockV2: %v", r)
+			response = &RPCResponse{
+				JSONRPC: "2.0",
+				Error: &RPCError{
+					Code:    CodeInternalError,
+					Message: "Internal error",
+					Data:    fmt.Sprintf("Panic: %v", r),
+				},
+				ID: req.ID,
+			}
+		}
+	}()
+
+	// Miners submit the nonce for an issued template; the node builds the block and computes its hash
+	block, resp := s.blockFromWork(req)
+	if resp != nil {
+		return resp
+	}
+
+	// Submit block to blockchain using V2 function
+	if err := s.blockchain.AddBlockV2(block); err != nil {
+		log.Printf("❌ Failed to add block: %v", err)
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInternalError,
+				Message: "Block submission failed",
+				Data:    err.Error(),
+			},
+			ID: req.ID,
+		}
+	}
+
+	log.Printf("✅ Block #%d submitted successfully: %x", block.Header.Number, block.Hash)
+
+	// Relay the accepted block to peers
+	if p2p := s.getP2P(); p2p != nil {
+		if err := p2p.BroadcastBlock(block); err != nil {
+			log.Printf("⚠️ Failed to relay block #%d: %v", block.Header.Number, err)
+		}
+	}
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"success": true,
+			"hash":    hex.EncodeToString(block.Hash[:]),
+			"number":  block.Header.Number,
+		},
+		ID: req.ID,
+	}
+}
+
+// handleGetMiningInfo handles getMiningInfo requests
+func (s *ServerV2) handleGetMiningInfo(req *RPCRequest) *RPCResponse {
+	bestBlock := s.blockchain.GetBestBlock()
+	if bestBlock == nil {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInternalError,
+				Message: "Internal error",
+				Data:    "No blocks found",
+			},
+			ID: req.ID,
+		}
+	}
+
+	// Difficulty required for the next block under the consensus rules
+	difficulty := s.blockchain.GetNextDifficulty()
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"height":     s.blockchain.GetHeight(),
+			"difficulty": difficulty,
+			"bestBlock":  hex.EncodeToString(bestBlock.Hash[:]),
+		},
+		ID: req.ID,
+	}
+}
+
+// handleGetUpgrades handles getUpgrades requests
+func (s *ServerV2) handleGetUpgrades(req *RPCRequest) *RPCResponse {
+	genesis := s.blockchain.GetGenesis()
+	height := s.blockchain.GetHeight()
+	// Upgrades apply to the next block, so report status for it
+	next := height + 1
+
+	upgrades := make([]map[string]interface{}, 0, len(core.KnownUpgrades))
+	for _, name := range core.KnownUpgrades {
+		entry := map[string]interface{}{
+			"name":   name,
+			"active": genesis.IsActive(name, next),
+		}
+
+		upgrade, ok := genesis.GetUpgrade(name)
+		switch {
+		case !ok:
+			entry["status"] = "unscheduled"
+		case next >= upgrade.Height:
+			entry["status"] = "active"
+		default:
+			entry["status"] = "pending"
+			entry["blocksRemaining"] = upgrade.Height - next
+		}
+		if ok {
+			entry["height"] = upgrade.Height
+			entry["description"] = upgrade.Description
+		}
+
+		upgrades = append(upgrades, entry)
+	}
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"height":   height,
+			"upgrades": upgrades,
+		},
+		ID: req.ID,
+	}
+}
+
+// handleGetSyncStatus handles getSyncStatus requests
+func (s *ServerV2) handleGetSyncStatus(req *RPCRequest) *RPCResponse {
+	p2p := s.getP2P()
+	if p2p == nil {
+		// Without a peer network the local chain is all there is
+		height := s.blockchain.GetHeight()
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Result: network.SyncStatus{
+				CurrentHeight: height,
+				TargetHeight:  height,
+				Progress:      1,
+			},
+			ID: req.ID,
+		}
+	}
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result:  p2p.GetSyncStatus(),
+		ID:      req.ID,
+	}
+}
+
+// p2pUnavailable is the response to peer management requests when P2P is not running
+func p2pUnavailable(req *RPCRequest) *RPCResponse {
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Error: &RPCError{
+			Code:    CodeInternalError,
+			Message: "Internal error",
+			Data:    "P2P network is not running",
+		},
+		ID: req.ID,
+	}
+}
+
+// handleListBanned handles listBanned requests
+func (s *ServerV2) handleListBanned(req *RPCRequest) *RPCResponse {
+	p2p := s.getP2P()
+	if p2p == nil {
+		return p2pUnavailable(req)
+	}
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result:  p2p.ListBanned(),
+		ID:      req.ID,
+	}
+}
+
+// handleSetBan handles setBan requests
+func (s *ServerV2) handleSetBan(req *RPCRequest) *RPCResponse {
+	params, ok := req.Params.(map[string]interface{})
+	if !ok {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInvalidParams,
+				Message: "Invalid params",
+				Data:    "Expected object with 'address' and 'command' fields",
+			},
+			ID: req.ID,
+		}
+	}
+
+	address, _ := params["address"].(string)
+	command, _ := params["command"].(string)
+	if address == "" || (command != "add" && command != "remove") {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInvalidParams,
+				Message: "Invalid params",
+				Data:    "'address' is required and 'command' must be 'add' or 'remove'",
+			},
+			ID: req.ID,
+		}
+	}
+
+	p2p := s.getP2P()
+	if p2p == nil {
+		return p2pUnavailable(req)
+	}
+
+	if command == "remove" {
+		removed, err := p2p.Unban(address)
+		if err == nil && !removed {
+			err = fmt.Errorf("address %s is not banned", address)
+		}
+		if err != nil {
+			return &RPCResponse{
+				JSONRPC: "2.0",
+				Error: &RPCError{
+					Code:    CodeInvalidParams,
+					Message: "Invalid params",
+					Data:    err.Error(),
+				},
+				ID: req.ID,
+			}
+		}
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Result:  true,
+			ID:      req.ID,
+		}
+	}
+
+	// Ban time is given in seconds
+	duration := network.DefaultBanDuration
+	if banTime, ok := params["banTime"].(float64); ok {
+		duration = time.Duration(banTime) * time.Second
+	}
+	reason, _ := params["reason"].(string)
+	if reason == "" {
+		reason = "manually banned"
+	}
+
+	entry, err := p2p.Ban(address, duration, reason)
+	if err != nil {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInvalidParams,
+				Message: "Invalid params",
+				Data:    err.Error(),
+			},
+			ID: req.ID,
+		}
+	}
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result:  entry,
+		ID:      req.ID,
+	}
+}
+
+// handleClearBanned handles clearBanned requests
+func (s *ServerV2) handleClearBanned(req *RPCRequest) *RPCResponse {
+	p2p := s.getP2P()
+	if p2p == nil {
+		return p2pUnavailable(req)
+	}
+
+	if err := p2p.ClearBanned(); err != nil {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInternalError,
+				Message: "Internal error",
+				Data:    err.Error(),
+			},
+			ID: req.ID,
+		}
+	}
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result:  true,
+		ID:      req.ID,
+	}
+}
+
+// handleGetBalance handles getBalance requests
+func (s *ServerV2) handleGetBalance(req *RPCRequest) *RPCResponse {
+	// Parse parameters
+	params, ok := req.Params.(map[string]interface{})
+	if !ok {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInvalidParams,
+				Message: "Invalid params",
+				Data:    "Expected object with 'address' field",
+			},
+			ID: req.ID,
+		}
+	}
+
+	addressStr, ok := params["address"].(string)
+	if !ok {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInvalidParams,
+				Message: "Invalid params",
+				Data:    "Missing or invalid 'address' field",
+			},
+			ID: req.ID,
+		}
+	}
+
+	// Convert string address to Address type
+	address := core.AddressFromString(addressStr)
+
+	// Get balance from blockchain
+	balance := s.blockchain.GetBalance(address)
+
+	// Debug logging
+	log.Printf("🔍 Balance query - Address: %s, Parsed: %s, Balance: %d", addressStr, hex.EncodeToString(address[:]), balance)
+
+	// Verbose mode reports immature block rewards separately
+	if verbose, _ := params["verbose"].(bool); verbose {
+		spendable, immature := s.blockchain.GetBalanceDetails(address)
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Result: map[string]interface{}{
+				"balance":          balance,
+				"spendable":        spendable,
+				"immature":         immature,
+				"coinbaseMaturity": s.blockchain.GetGenesis().CoinbaseMaturity,
+			},
+			ID: req.ID,
+		}
+	}
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result:  balance,
+		ID:      req.ID,
+	}
+}
+
+// handleSendTransaction handles sendTransaction requests.
+// Deprecated: the node builds the transaction unsigned; use sendRawTransaction.
+func (s *ServerV2) handleSendTransaction(req *RPCRequest) *RPCResponse {
+	if s.blockchain.GetGenesis().DisableLegacySendTransaction {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeMethodNotFound,
+				Message: "Method not found",
+				Data:    "sendTransaction is disabled on this network; sign transactions locally and use sendRawTransaction",
+			},
+			ID: req.ID,
+		}
+	}
+	log.Printf("⚠️ sendTransaction is deprecated and will be removed; use sendRawTransaction")
+
+	params, ok := req.Params.(map[string]interface{})
+	if !ok {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInvalidParams,
+				Message: "Invalid params",
+			},
+			ID: req.ID,
+		}
+	}
+
+	// Parse transaction fields
+	fromStr, _ := params["from"].(string)
+	toStr, _ := params["to"].(string)
+	amount, _ := params["amount"].(float64)
+	fee, _ := params["fee"].(float64)
+
+	if fromStr == "" || toStr == "" || amount == 0 {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInvalidParams,
+				Message: "Invalid params",
+				Data:    "from, to and amount are required",
+			},
+			ID: req.ID,
+		}
+	}
+
+	// Get addresses
+	fromAddr := core.AddressFromString(fromStr)
+	toAddr := core.AddressFromString(toStr)
+
+	// Create transaction from UTXOs
+	tx, err := s.blockchain.CreateTransaction(fromAddr, toAddr, uint64(amount), uint64(fee))
+	if err != nil {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInternalError,
+				Message: "Transaction creation failed",
+				Data:    err.Error(),
+			},
+			ID: req.ID,
+		}
+	}
+
+	log.Printf("📤 Transaction created - From: %s, To: %s, Amount: %d, Hash: %x", fromStr, toStr, tx.Amount, tx.Hash)
+
+	// Add to mempool (validated against the current chain state)
+	if err := s.blockchain.AddToMempool(tx); err != nil {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInternalError,
+				Message: "Transaction rejected",
+				Data:    err.Error(),
+			},
+			ID: req.ID,
+		}
+	}
+
+	// Relay the accepted transaction to peers
+	if p2p := s.getP2P(); p2p != nil {
+		if err := p2p.BroadcastTransaction(tx); err != nil {
+			log.Printf("⚠️ Failed to relay transaction %x: %v", tx.Hash, err)
+		}
+	}
+
+	// Return transaction hash
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"txHash":  hex.EncodeToString(tx.Hash[:]),
+			"status":  "pending",
+			"warning": "sendTransaction is deprecated; sign transactions locally and use sendRawTransaction",
+		},
+		ID: req.ID,
+	}
+}
+
+// handleSendRawTransaction validates a hex-encoded, client-signed transaction,
+// adds it to the mempool and relays it to peers
+func (s *ServerV2) handleSendRawTransaction(req *RPCRequest) *RPCResponse {
+	params, ok := req.Params.(map[string]interface{})
+	if !ok {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInvalidParams,
+				Message: "Invalid params",
+				Data:    "Expected object with 'hex' field",
+			},
+			ID: req.ID,
+		}
+	}
+
+	rawHex, _ := params["hex"].(string)
+	maxTxBytes := s.blockchain.GetGenesis().MaxScheduledTxBytes()
+	if rawHex == "" || uint64(len(rawHex)) > 2*maxTxBytes {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInvalidParams,
+				Message: "Invalid params",
+				Data:    fmt.Sprintf("'hex' must hold a serialized transaction of at most %d bytes", maxTxBytes),
+			},
+			ID: req.ID,
+		}
+	}
+
+	raw, err := hex.DecodeString(rawHex)
+	if err != nil {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInvalidParams,
+				Message: "Invalid params",
+				Data:    fmt.Sprintf("invalid hex: %v", err),
+			},
+			ID: req.ID,
+		}
+	}
+
+	var tx core.Transaction
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInvalidParams,
+				Message: "Invalid params",
+				Data:    fmt.Sprintf("invalid transaction: %v", err),
+			},
+			ID: req.ID,
+		}
+	}
+
+	// Signatures, ownership, amounts and double spends are checked before the mempool takes it
+	if err := s.blockchain.AcceptRawTransaction(&tx); err != nil {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInternalError,
+				Message: "Transaction rejected",
+				Data:    err.Error(),
+			},
+			ID: req.ID,
+		}
+	}
+
+	log.Printf("📤 Raw transaction accepted - Hash: %x, Inputs: %d, Outputs: %d", tx.Hash, len(tx.Inputs), len(tx.Outputs))
+
+	// Relay the accepted transaction to peers
+	if p2p := s.getP2P(); p2p != nil {
+		if err := p2p.BroadcastTransaction(&tx); err != nil {
+			log.Printf("⚠️ Failed to relay transaction %x: %v", tx.Hash, err)
+		}
+	}
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result: map[string]interface{}{
+			"txHash": hex.EncodeToString(tx.Hash[:]),
+			"status": "pending",
+		},
+		ID: req.ID,
+	}
+}
+
+// handleCreateRawTransaction builds an unsigned transaction from explicit inputs and outputs.
+// Whatever the inputs hold beyond the outputs is the fee.
+func (s *ServerV2) handleCreateRawTransaction(req *RPCRequest) *RPCResponse {
+	params, ok := req.Params.(map[string]interface{})
+	if !ok {
+		return invalidParams(req, "Expected object with 'inputs' and 'outputs' fields")
+	}
+
+	rawInputs, _ := params["inputs"].([]interface{})
+	if len(rawInputs) == 0 {
+		return invalidParams(req, "'inputs' must be a non-empty array of {txHash, index}")
+	}
+	inputs := make([]core.TxInput, 0, len(rawInputs))
+	for i, raw := range rawInputs {
+		input, _ := raw.(map[string]interface{})
+		txHash, err := parseHashParam(input["txHash"])
+		if err != nil {
+			return invalidParams(req, fmt.Sprintf("input %d: %v", i, err))
+		}
+		index, ok := input["index"].(float64)
+		if !ok || index < 0 || index != float64(uint32(index)) {
+			return invalidParams(req, fmt.Sprintf("input %d: missing or invalid 'index'", i))
+		}
+		inputs = append(inputs, core.TxInput{PreviousTxHash: txHash, Index: uint32(index)})
+	}
+
+	outputs, err := parseOutputsParam(params["outputs"])
+	if err != nil {
+		return invalidParams(req, err.Error())
+	}
+	data, err := parseDataParam(params["data"])
+	if err != nil {
+		return invalidParams(req, err.Error())
+	}
+
+	tx, err := s.blockchain.CreateRawTransaction(inputs, outputs, data)
+	if err != nil {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInternalError,
+				Message: "Transaction creation failed",
+				Data:    err.Error(),
+			},
+			ID: req.ID,
+		}
+	}
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result:  s.unsignedTransactionResult(tx, -1),
+		ID:      req.ID,
+	}
+}
+
+// handleFundRawTransaction builds an unsigned transaction paying 'to' (or 'outputs')
+// from the spendable outputs of 'from', selecting inputs, estimating the fee and
+// returning the change
+func (s *ServerV2) handleFundRawTransaction(req *RPCRequest) *RPCResponse {
+	params, ok := req.Params.(map[string]interface{})
+	if !ok {
+		return invalidParams(req, "Expected object with 'from' and 'to'/'amount' or 'outputs' fields")
+	}
+
+	fromStr, _ := params["from"].(string)
+	from, err := parseAddressParam(fromStr)
+	if err != nil {
+		return invalidParams(req, fmt.Sprintf("from: %v", err))
+	}
+
+	var outputs []core.TxOutput
+	if rawOutputs, ok := params["outputs"]; ok {
+		if outputs, err = parseOutputsParam(rawOutputs); err != nil {
+			return invalidParams(req, err.Error())
+		}
+	} else {
+		if outputs, err = parseOutputsParam([]interface{}{params}); err != nil {
+			return invalidParams(req, "either 'outputs' or 'to' and 'amount' are required")
+		}
+	}
+
+	opts := core.FundOptions{ChangeAddress: from}
+	if changeStr, ok := params["changeAddress"].(string); ok {
+		if opts.ChangeAddress, err = parseAddressParam(changeStr); err != nil {
+			return invalidParams(req, fmt.Sprintf("changeAddress: %v", err))
+		}
+	}
+	if opts.FeeRate, err = parseAmountParam(params, "feeRate"); err != nil {
+		return invalidParams(req, err.Error())
+	}
+	if opts.Fee, err = parseAmountParam(params, "fee"); err != nil {
+		return invalidParams(req, err.Error())
+	}
+	if opts.Data, err = parseDataParam(params["data"]); err != nil {
+		return invalidParams(req, err.Error())
+	}
+
+	tx, err := s.blockchain.FundTransaction(from, outputs, opts)
+	if err != nil {
+		return &RPCResponse{
+			JSONRPC: "2.0",
+			Error: &RPCError{
+				Code:    CodeInternalError,
+				Message: "Transaction funding failed",
+				Data:    err.Error(),
+			},
+			ID: req.ID,
+		}
+	}
+
+	changeIndex := -1
+	if len(tx.Outputs) > len(outputs) {
+		changeIndex = len(tx.Outputs) - 1
+	}
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result:  s.unsignedTransactionResult(tx, changeIndex),
+		ID:      req.ID,
+	}
+}
+
+// unsignedTransactionResult describes an unsigned transaction together with the
+// sighash the client must sign for every input
+func (s *ServerV2) unsignedTransactionResult(tx *core.Transaction, changeIndex int) map[string]interface{} {
+	raw, _ := tx.MarshalBinary()
+
+	inputs := make([]map[string]interface{}, len(tx.Inputs))
+	for i, input := range tx.Inputs {
+		sigHash := tx.SigHash(i)
+		inputs[i] = map[string]interface{}{
+			"txHash":  hex.EncodeToString(input.PreviousTxHash[:]),
+			"index":   input.Index,
+			"sigHash": hex.EncodeToString(sigHash[:]),
+		}
+		if utxo := s.blockchain.GetUTXO(input.PreviousTxHash, input.Index); utxo != nil {
+			inputs[i]["address"] = hex.EncodeToString(utxo.Address[:])
+			inputs[i]["amount"] = utxo.Amount
+		}
+	}
+
+	outputs := make([]map[string]interface{}, len(tx.Outputs))
+	for i, output := range tx.Outputs {
+		outputs[i] = map[string]interface{}{
+			"address": hex.EncodeToString(output.Address[:]),
+			"amount":  output.Amount,
+		}
+	}
+
+	return map[string]interface{}{
+		"hex":         hex.EncodeToString(raw),
+		"txHash":      hex.EncodeToString(tx.Hash[:]),
+		"fee":         tx.Fee,
+		"signedSize":  tx.SignedSize(),
+		"inputs":      inputs,
+		"outputs":     outputs,
+		"changeIndex": changeIndex,
+	}
+}
+
+// invalidParams returns an invalid params error for a request
+func invalidParams(req *RPCRequest, data string) *RPCResponse {
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Error: &RPCError{
+			Code:    CodeInvalidParams,
+			Message: "Invalid params",
+			Data:    data,
+		},
+		ID: req.ID,
+	}
+}
+
+// parseAddressParam parses an address parameter, rejecting malformed and zero addresses
+func parseAddressParam(s string) (core.Address, error) {
+	address := core.AddressFromString(s)
+	if address == (core.Address{}) {
+		return address, fmt.Errorf("missing or invalid address %q", s)
+	}
+	return address, nil
+}
+
+// parseHashParam parses a hex-encoded 32 byte hash parameter
+func parseHashParam(value interface{}) (core.Hash, error) {
+	var hash core.Hash
+	s, _ := value.(string)
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil || len(b) != len(hash) {
+		return hash, fmt.Errorf("missing or invalid 'txHash'")
+	}
+	copy(hash[:], b)
+	return hash, nil
+}
+
+// parseAmountParam parses an optional non-negative integer parameter, returning 0 when absent
+func parseAmountParam(params map[string]interface{}, name string) (uint64, error) {
+	value, ok := params[name]
+	if !ok {
+		return 0, nil
+	}
+	amount, ok := value.(float64)
+	if !ok || amount < 0 || amount != float64(uint64(amount)) {
+		return 0, fmt.Errorf("'%s' must be a non-negative integer", name)
+	}
+	return uint64(amount), nil
+}
+
+// parseOutputsParam parses a non-empty array of {address|to, amount} outputs
+func parseOutputsParam(value interface{}) ([]core.TxOutput, error) {
+	rawOutputs, _ := value.([]interface{})
+	if len(rawOutputs) == 0 {
+		return nil, fmt.Errorf("'outputs' must be a non-empty array of {address, amount}")
+	}
+	outputs := make([]core.TxOutput, 0, len(rawOutputs))
+	for i, raw := range rawOutputs {
+		output, _ := raw.(map[string]interface{})
+		addressStr, ok := output["address"].(string)
+		if !ok {
+			addressStr, _ = output["to"].(string)
+		}
+		address, err := parseAddressParam(addressStr)
+		if err != nil {
+			return nil, fmt.Errorf("output %d: %v", i, err)
+		}
+		amount, err := parseAmountParam(output, "amount")
+		if err != nil || amount == 0 {
+			return nil, fmt.Errorf("output %d: 'amount' must be a positive integer", i)
+		}
+		outputs = append(outputs, core.TxOutput{Address: address, Amount: amount})
+	}
+	return outputs, nil
+}
+
+// parseDataParam parses optional hex-encoded transaction data
+func parseDataParam(value interface{}) ([]byte, error) {
+	if value == nil {
+		return nil, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("'data' must be a hex string")
+	}
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'data' hex: %v", err)
+	}
+	return data, nil
+}
+
+// handleHealth handles health check requests
+func (s *ServerV2) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "ok",
+		"timestamp": time.Now(),
+		"version":   "2.0",
+		"height":    s.blockchain.GetHeight(),
+	})
+}
+
+// writeError writes an error response
+func (s *ServerV2) writeError(w http.ResponseWriter, id interface{}, code int, message, data string) {
+	response := RPCResponse{
+		JSONRPC: "2.0",
+		Error: &RPCError{
+			Code:    code,
+			Message: message,
+			Data:    data,
+		},
+		ID: id,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// writeUnauthorized rejects a request with missing or invalid credentials
+func (s *ServerV2) writeUnauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="kalon-rpc"`)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	s.writeError(w, nil, CodeUnauthorized, "Unauthorized", err.Error())
+}
+
+// limitConnections limits concurrent requests professionally
+func (s *ServerV2) limitConnections(h http.Handler) http.Handler {
+	semaphore := make(chan struct{}, s.access.maxConcurrent)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case semaphore <- struct{}{}:
+			defer func() { <-semaphore }()
+			s.inFlight.Add(1)
+			defer s.inFlight.Add(-1)
+			h.ServeHTTP(w, r)
+		default:
+			http.Error(w, "Too many connections", http.StatusServiceUnavailable)
+		}
+	})
+}
+
+// trackConnection tracks a client connection
+func (s *ServerV2) trackConnection(connID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if conn, exists := s.connections[connID]; exists {
+		conn.LastSeen = time.Now()
+		conn.Requests++
+	} else {
+		s.connections[connID] = &Connection{
+			ID:        connID,
+			CreatedAt: time.Now(),
+			LastSeen:  time.Now(),
+			Requests:  1,
+		}
+	}
+}
+
+// cleanupConnections cleans up old connections
+func (s *ServerV2) cleanupConnections() {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			now := time.Now()
+			for id, conn := range s.connections {
+				if now.Sub(conn.LastSeen) > 10*time.Minute {
+					delete(s.connections, id)
+				}
+			}
+			for ip, limit := range s.rateLimits {
+				if now.Sub(limit.LastReset) > time.Minute {
+					delete(s.rateLimits, ip)
+				}
+			}
+			s.mu.Unlock()
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
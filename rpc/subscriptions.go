@@ -0,0 +1,426 @@
+package rpc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+)
+
+// Subscription topics available over /ws
+const (
+	TopicNewHeads               = "newHeads"               // Every block added to the main chain
+	TopicNewPendingTransactions = "newPendingTransactions" // Every transaction accepted into the mempool
+	TopicAddressActivity        = "addressActivity"        // Pending and confirmed transactions touching an address
+)
+
+const (
+	// maxWebSocketClients is the most WebSocket connections served at once
+	maxWebSocketClients = 100
+
+	// maxSubscriptionsPerClient is the most subscriptions one connection may hold
+	maxSubscriptionsPerClient = 32
+
+	// wsSendQueueSize is how many messages a client may fall behind by before it is
+	// disconnected; events are never held back for a slow client
+	wsSendQueueSize = 256
+
+	// wsPingInterval is how often the server pings an idle client
+	wsPingInterval = 30 * time.Second
+
+	// wsReadTimeout closes a connection that sent nothing, not even a pong, for this long
+	wsReadTimeout = 2 * wsPingInterval
+)
+
+// SubscribeParams are the parameters of subscribe
+type SubscribeParams struct {
+	Topic   string `json:"topic"`
+	Address string `json:"address"` // Required for addressActivity only
+}
+
+// UnsubscribeParams are the parameters of unsubscribe
+type UnsubscribeParams struct {
+	Subscription string `json:"subscription"`
+}
+
+// subscription is one topic a client subscribed to
+type subscription struct {
+	id      string
+	topic   string
+	address core.Address // Filter of addressActivity
+}
+
+// wsClient serves subscriptions over one WebSocket connection
+type wsClient struct {
+	server *ServerV2
+	ws     *wsConn
+	ip     string
+	role   *role
+
+	send      chan []byte   // Outgoing messages, written in order by writeLoop
+	done      chan struct{} // Closed when the connection shuts down
+	closeOnce sync.Once
+
+	mu   sync.Mutex
+	subs map[string]*subscription
+}
+
+// handleWebSocket upgrades a request to a WebSocket connection serving subscriptions.
+// The handler returns once the connection is set up; the client is served in the background.
+func (s *ServerV2) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	ip := s.extractIP(r)
+	if !s.clientAllowed(ip) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if !s.checkRateLimit(ip, 0) {
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+		return
+	}
+
+	role, err := s.auth.authenticate(r)
+	if err != nil {
+		s.writeUnauthorized(w, err)
+		return
+	}
+
+	s.wsMu.Lock()
+	full := len(s.wsClients) >= maxWebSocketClients
+	s.wsMu.Unlock()
+	if full {
+		http.Error(w, "Too many WebSocket connections", http.StatusServiceUnavailable)
+		return
+	}
+
+	ws, err := upgradeWebSocket(w, r, s.originAllowed)
+	if err != nil {
+		log.Printf("⚠️ WebSocket upgrade from %s failed: %v", ip, err)
+		return
+	}
+	s.trackConnection(ip)
+
+	client := &wsClient{
+		server: s,
+		ws:     ws,
+		ip:     ip,
+		role:   role,
+		send:   make(chan []byte, wsSendQueueSize),
+		done:   make(chan struct{}),
+		subs:   make(map[string]*subscription),
+	}
+	s.wsMu.Lock()
+	s.wsClients[client] = struct{}{}
+	s.wsMu.Unlock()
+
+	go client.writeLoop()
+	go client.readLoop()
+	go client.eventLoop()
+}
+
+// close shuts the connection down once, telling the client why
+func (c *wsClient) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.server.wsMu.Lock()
+		delete(c.server.wsClients, c)
+		c.server.wsMu.Unlock()
+
+		// A slow client may hold the connection until its write times out, so the
+		// close frame is sent without blocking the caller
+		go func() {
+			c.ws.writeClose(code, reason)
+			c.ws.conn.Close()
+		}()
+	})
+}
+
+// enqueue queues a message without blocking. A client whose queue is full has fallen
+// too far behind and is disconnected, so it knows it missed events.
+func (c *wsClient) enqueue(message []byte) {
+	select {
+	case <-c.done:
+	case c.send <- message:
+	default:
+		log.Printf("⚠️ WebSocket client %s is too slow, disconnecting", c.ip)
+		c.close(closePolicyViolation, "client too slow")
+	}
+}
+
+// writeLoop writes queued messages and keeps the connection alive with pings
+func (c *wsClient) writeLoop() {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		var err error
+		select {
+		case <-c.done:
+			return
+		case message := <-c.send:
+			err = c.ws.writeFrame(opText, message)
+		case <-ticker.C:
+			err = c.ws.writeFrame(opPing, nil)
+		}
+		if err != nil {
+			c.close(closeGoingAway, "write failed")
+			return
+		}
+	}
+}
+
+// readLoop answers subscribe and unsubscribe requests until the connection closes
+func (c *wsClient) readLoop() {
+	for {
+		c.ws.conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+		message, err := c.ws.readMessage()
+		if err != nil {
+			switch {
+			case errors.Is(err, errWSClosed):
+				c.close(closeNormal, "")
+			case errors.Is(err, errWSTooBig):
+				c.close(closeMessageTooBig, fmt.Sprintf("messages are limited to %d bytes", wsMaxMessageSize))
+			case errors.Is(err, errWSBinaryData):
+				c.close(closeUnsupportedData, "only text messages are supported")
+			case errors.Is(err, errWSProtocol):
+				c.close(closeProtocolError, err.Error())
+			default:
+				c.close(closeGoingAway, "read failed")
+			}
+			return
+		}
+
+		if !c.server.checkRateLimit(c.ip, 0) {
+			c.enqueue(encodeResponse(errorResponse(nil, CodeLimitExceeded, "Limit exceeded", "rate limit exceeded")))
+			continue
+		}
+		if resp := c.handleMessage(message); resp != nil {
+			c.enqueue(encodeResponse(resp))
+		}
+	}
+}
+
+// handleMessage runs one request received over the connection
+func (c *wsClient) handleMessage(message []byte) *RPCResponse {
+	if !json.Valid(message) {
+		return errorResponse(nil, CodeParseError, "Parse error", "message is not valid JSON")
+	}
+	req, answer, resp := parseCall(message)
+	if resp == nil {
+		resp = forbidden(req, c.role)
+	}
+	if resp == nil {
+		resp = c.server.chargeCall(req, &caller{ip: c.ip, role: c.role})
+	}
+	if resp == nil {
+		switch req.Method {
+		case "subscribe":
+			resp = c.subscribe(req)
+		case "unsubscribe":
+			resp = c.unsubscribe(req)
+		default:
+			resp = errorResponse(req.ID, CodeMethodNotFound, "Method not found",
+				"only subscribe and unsubscribe are available over WebSocket; use HTTP for other methods")
+		}
+	}
+	return reply(resp, answer)
+}
+
+// subscribe adds a subscription and returns its id
+func (c *wsClient) subscribe(req *RPCRequest) *RPCResponse {
+	var params SubscribeParams
+	if resp := decodeParams(req, &params); resp != nil {
+		return resp
+	}
+
+	sub := &subscription{topic: params.Topic}
+	switch params.Topic {
+	case TopicAddressActivity:
+		address, err := parseAddressParam(params.Address)
+		if err != nil {
+			return invalidParams(req, err.Error())
+		}
+		sub.address = address
+	case TopicNewHeads, TopicNewPendingTransactions:
+		if params.Address != "" {
+			return invalidParams(req, fmt.Sprintf("topic %q takes no address", params.Topic))
+		}
+	default:
+		return invalidParams(req, fmt.Sprintf("unknown topic %q", params.Topic))
+	}
+
+	id := make([]byte, 8)
+	rand.Read(id)
+	sub.id = "0x" + hex.EncodeToString(id)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.subs) >= maxSubscriptionsPerClient {
+		return errorResponse(req.ID, CodeInvalidRequest, "Invalid Request",
+			fmt.Sprintf("a connection may hold at most %d subscriptions", maxSubscriptionsPerClient))
+	}
+	c.subs[sub.id] = sub
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result:  sub.id,
+		ID:      req.ID,
+	}
+}
+
+// unsubscribe removes a subscription and reports whether it existed
+func (c *wsClient) unsubscribe(req *RPCRequest) *RPCResponse {
+	var params UnsubscribeParams
+	if resp := decodeParams(req, &params); resp != nil {
+		return resp
+	}
+	if params.Subscription == "" {
+		return invalidParams(req, "missing 'subscription'")
+	}
+
+	c.mu.Lock()
+	_, found := c.subs[params.Subscription]
+	delete(c.subs, params.Subscription)
+	c.mu.Unlock()
+
+	return &RPCResponse{
+		JSONRPC: "2.0",
+		Result:  found,
+		ID:      req.ID,
+	}
+}
+
+// eventLoop turns chain events into notifications. It only ever queues messages, so
+// the event bus keeps delivering to other subscribers however slow this client is.
+func (c *wsClient) eventLoop() {
+	bus := c.server.eventBus
+	blocks := bus.Subscribe(core.EventBlockAdded)
+	txs := bus.Subscribe(core.EventTransactionAdded)
+	defer bus.Unsubscribe(core.EventBlockAdded, blocks)
+	defer bus.Unsubscribe(core.EventTransactionAdded, txs)
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.server.ctx.Done():
+			c.close(closeGoingAway, "server shutting down")
+			return
+		case data := <-blocks:
+			if event, ok := data.(map[string]interface{}); ok {
+				if block, ok := event["block"].(*core.Block); ok {
+					c.publishBlock(block)
+				}
+			}
+		case data := <-txs:
+			if tx, ok := data.(*core.Transaction); ok {
+				c.publishTransaction(tx)
+			}
+		}
+	}
+}
+
+// subscriptions returns the client's subscriptions to a topic
+func (c *wsClient) subscriptions(topic string) []*subscription {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var subs []*subscription
+	for _, sub := range c.subs {
+		if sub.topic == topic {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// notify queues a notification for a subscription
+func (c *wsClient) notify(sub *subscription, result interface{}) {
+	message, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "subscription",
+		"params": map[string]interface{}{
+			"subscription": sub.id,
+			"result":       result,
+		},
+	})
+	if err != nil {
+		log.Printf("❌ Failed to marshal %s notification: %v", sub.topic, err)
+		return
+	}
+	c.enqueue(message)
+}
+
+// publishBlock notifies newHeads and addressActivity subscribers of a new block
+func (c *wsClient) publishBlock(block *core.Block) {
+	if subs := c.subscriptions(TopicNewHeads); len(subs) > 0 {
+		header := c.server.blockHeaderResult(block)
+		for _, sub := range subs {
+			c.notify(sub, header)
+		}
+	}
+
+	for _, sub := range c.subscriptions(TopicAddressActivity) {
+		for i := range block.Txs {
+			if activity := addressActivity(&block.Txs[i], sub.address); activity != nil {
+				activity["status"] = "confirmed"
+				activity["blockHash"] = hex.EncodeToString(block.Hash[:])
+				activity["blockNumber"] = block.Header.Number
+				c.notify(sub, activity)
+			}
+		}
+	}
+}
+
+// publishTransaction notifies newPendingTransactions and addressActivity subscribers
+// of a transaction accepted into the mempool
+func (c *wsClient) publishTransaction(tx *core.Transaction) {
+	if subs := c.subscriptions(TopicNewPendingTransactions); len(subs) > 0 {
+		result := transactionResult(tx)
+		for _, sub := range subs {
+			c.notify(sub, result)
+		}
+	}
+
+	for _, sub := range c.subscriptions(TopicAddressActivity) {
+		if activity := addressActivity(tx, sub.address); activity != nil {
+			activity["status"] = "pending"
+			c.notify(sub, activity)
+		}
+	}
+}
+
+// addressActivity describes how a transaction involves an address, or returns nil
+// if it does not. An address is involved if it sends, signs an input or receives.
+func addressActivity(tx *core.Transaction, address core.Address) map[string]interface{} {
+	sent := !tx.IsCoinbase() && tx.From == address
+	for _, input := range tx.Inputs {
+		if len(input.PublicKey) > 0 && core.PubKeyAddress(input.PublicKey) == address {
+			sent = true
+		}
+	}
+	var received uint64
+	receives := false
+	for _, output := range tx.Outputs {
+		if output.Address == address {
+			received += output.Amount
+			receives = true
+		}
+	}
+	if !sent && !receives {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"address":  hex.EncodeToString(address[:]),
+		"txHash":   hex.EncodeToString(tx.Hash[:]),
+		"sent":     sent,
+		"received": received,
+	}
+}
@@ -0,0 +1,57 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	// DefaultSocketMode lets only the node's user connect to the Unix socket
+	DefaultSocketMode os.FileMode = 0600
+
+	// unixSocketClient is the client address of requests over the Unix socket. Its
+	// file mode decides who may connect, so allow-lists and rate limits treat it as
+	// a local client.
+	unixSocketClient = "unix"
+)
+
+// unixSocketKey marks the context of requests received over the Unix socket
+type unixSocketKey struct{}
+
+// listenUnix listens on a Unix socket with the given file mode. A socket left behind
+// by a node that did not shut down cleanly is replaced; one still in use is not.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("socket %s is in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %v", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set socket mode: %v", err)
+	}
+	return listener, nil
+}
+
+// unixSocketHandler marks requests as received over the Unix socket
+func unixSocketHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), unixSocketKey{}, true)))
+	})
+}
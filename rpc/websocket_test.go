@@ -0,0 +1,290 @@
+package rpc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kalon-network/kalon/core"
+	"github.com/kalon-network/kalon/crypto"
+)
+
+// testWSClient is a minimal WebSocket client
+type testWSClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// dialWebSocket opens a WebSocket connection to the server's /ws endpoint
+func dialWebSocket(t *testing.T, server *httptest.Server) *testWSClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: kalon\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: "+key+"\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Failed to read handshake: %v", err)
+	}
+	// The accept value for this key is given in RFC 6455
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Unexpected handshake response: %d %v", resp.StatusCode, resp.Header)
+	}
+	return &testWSClient{conn: conn, reader: reader}
+}
+
+// send writes a masked text frame
+func (c *testWSClient) send(t *testing.T, message string) {
+	t.Helper()
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opText}
+	if len(message) < 126 {
+		frame = append(frame, 0x80|byte(len(message)))
+	} else {
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(message)))
+	}
+	frame = append(frame, mask[:]...)
+	for i := 0; i < len(message); i++ {
+		frame = append(frame, message[i]^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+}
+
+// receive reads the next text message as JSON
+func (c *testWSClient) receive(t *testing.T) map[string]interface{} {
+	t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+			t.Fatalf("Failed to read frame: %v", err)
+		}
+		length := uint64(header[1] & 0x7F)
+		switch length {
+		case 126:
+			var ext [2]byte
+			io.ReadFull(c.reader, ext[:])
+			length = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			io.ReadFull(c.reader, ext[:])
+			length = binary.BigEndian.Uint64(ext[:])
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(c.reader, payload); err != nil {
+			t.Fatalf("Failed to read payload: %v", err)
+		}
+		if header[0]&0x0F != opText {
+			continue
+		}
+		var message map[string]interface{}
+		if err := json.Unmarshal(payload, &message); err != nil {
+			t.Fatalf("Message is not valid JSON: %s", payload)
+		}
+		return message
+	}
+}
+
+// subscribe subscribes to a topic and returns the subscription id
+func (c *testWSClient) subscribe(t *testing.T, params string) string {
+	t.Helper()
+	c.send(t, `{"jsonrpc":"2.0","id":1,"method":"subscribe","params":`+params+`}`)
+	reply := c.receive(t)
+	id, ok := reply["result"].(string)
+	if !ok {
+		t.Fatalf("Subscribe %s failed: %v", params, reply)
+	}
+	return id
+}
+
+// TestWebSocketSubscriptions tests subscribing to blocks, pending transactions and address activity
+func TestWebSocketSubscriptions(t *testing.T) {
+	s, _ := newTestServer(t)
+	server := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	defer server.Close()
+	bc := s.blockchain
+
+	keypair, _ := crypto.Generate()
+	owner := core.PubKeyAddress(keypair.Public)
+	recipient := core.Address{9}
+
+	client := dialWebSocket(t, server)
+	heads := client.subscribe(t, `["newHeads"]`)
+	pending := client.subscribe(t, `{"topic":"newPendingTransactions"}`)
+	activity := client.subscribe(t, `["addressActivity","`+hex.EncodeToString(recipient[:])+`"]`)
+
+	block := bc.CreateNewBlockV2(owner, nil)
+	if err := bc.AddBlockV2(block); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+	notification := client.receive(t)
+	params := notification["params"].(map[string]interface{})
+	if notification["method"] != "subscription" || params["subscription"] != heads {
+		t.Fatalf("Expected newHeads notification, got %v", notification)
+	}
+	if result := params["result"].(map[string]interface{}); result["hash"] != hex.EncodeToString(block.Hash[:]) {
+		t.Errorf("Expected header of the new block, got %v", result)
+	}
+
+	tx, err := bc.FundTransaction(owner, []core.TxOutput{{Address: recipient, Amount: 1000}}, core.FundOptions{})
+	if err != nil {
+		t.Fatalf("Failed to fund transaction: %v", err)
+	}
+	crypto.SignTransactionInputs(keypair, tx)
+	if err := bc.AcceptRawTransaction(tx); err != nil {
+		t.Fatalf("Failed to accept transaction: %v", err)
+	}
+	txHash := hex.EncodeToString(tx.Hash[:])
+
+	// Both notifications come from the same event, in subscription map order
+	seen := map[string]map[string]interface{}{}
+	for i := 0; i < 2; i++ {
+		params := client.receive(t)["params"].(map[string]interface{})
+		seen[params["subscription"].(string)] = params["result"].(map[string]interface{})
+	}
+	if seen[pending]["hash"] != txHash {
+		t.Errorf("Expected pending transaction notification, got %v", seen[pending])
+	}
+	if got := seen[activity]; got["txHash"] != txHash || got["status"] != "pending" || got["received"] != float64(1000) || got["sent"] != false {
+		t.Errorf("Expected pending activity for the recipient, got %v", got)
+	}
+
+	client.send(t, `{"jsonrpc":"2.0","id":2,"method":"unsubscribe","params":["`+pending+`"]}`)
+	if reply := client.receive(t); reply["result"] != true {
+		t.Errorf("Expected unsubscribe to succeed, got %v", reply)
+	}
+
+	for _, c := range []struct{ message, want string }{
+		{`{"jsonrpc":"2.0","id":3,"method":"subscribe","params":["logs"]}`, `"code":-32602`},
+		{`{"jsonrpc":"2.0","id":4,"method":"subscribe","params":["addressActivity"]}`, `"code":-32602`},
+		{`{"jsonrpc":"2.0","id":5,"method":"getHeight"}`, `"code":-32601`},
+		{`{"jsonrpc":"2.0","id":6,"method":"unsubscribe","params":["` + pending + `"]}`, `"result":false`},
+		{`not json`, `"code":-32700`},
+	} {
+		client.send(t, c.message)
+		reply, _ := json.Marshal(client.receive(t))
+		if !strings.Contains(string(reply), c.want) {
+			t.Errorf("%s: expected %s, got %s", c.message, c.want, reply)
+		}
+	}
+
+	// The block confirming the transaction is reported as confirmed activity
+	block = bc.CreateNewBlockV2(owner, nil)
+	if err := bc.AddBlockV2(block); err != nil {
+		t.Fatalf("Failed to add block: %v", err)
+	}
+	seen = map[string]map[string]interface{}{}
+	for i := 0; i < 2; i++ {
+		params := client.receive(t)["params"].(map[string]interface{})
+		seen[params["subscription"].(string)] = params["result"].(map[string]interface{})
+	}
+	if got := seen[activity]; got["status"] != "confirmed" || got["blockNumber"] != float64(block.Header.Number) {
+		t.Errorf("Expected confirmed activity, got %v", got)
+	}
+}
+
+// TestWebSocketSlowClient tests that a client that stops reading is disconnected
+// without holding up the event bus
+func TestWebSocketSlowClient(t *testing.T) {
+	s, _ := newTestServer(t)
+	server := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	defer server.Close()
+
+	client := dialWebSocket(t, server)
+	client.subscribe(t, `["newPendingTransactions"]`)
+	s.wsMu.Lock()
+	var slow *wsClient
+	for c := range s.wsClients {
+		slow = c
+	}
+	s.wsMu.Unlock()
+
+	// The client never reads again, so its socket and then its queue fill up
+	tx := &core.Transaction{Data: make([]byte, 4096)}
+	bus := s.blockchain.GetEventBus()
+	deadline := time.After(10 * time.Second)
+	for {
+		select {
+		case <-slow.done:
+			s.wsMu.Lock()
+			remaining := len(s.wsClients)
+			s.wsMu.Unlock()
+			if remaining != 0 {
+				t.Errorf("Expected slow client to be removed, %d connections left", remaining)
+			}
+			return
+		case <-deadline:
+			t.Fatal("Expected slow client to be disconnected")
+		default:
+			start := time.Now()
+			bus.Emit(core.EventTransactionAdded, tx)
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Fatalf("Emit blocked for %v", elapsed)
+			}
+		}
+	}
+}
+
+// TestWebSocketOrigin tests that browser pages from other sites may only connect if allowed
+func TestWebSocketOrigin(t *testing.T) {
+	s := newConfiguredTestServer(t, ServerConfig{
+		Access: AccessConfig{AllowedOrigins: []string{"https://explorer.example.com"}},
+	})
+	server := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	defer server.Close()
+
+	handshake := func(origin string) int {
+		conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+		if err != nil {
+			t.Fatalf("Failed to dial: %v", err)
+		}
+		defer conn.Close()
+		io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: kalon:16316\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+			"Origin: "+origin+"\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatalf("Failed to read handshake: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	cases := []struct {
+		origin string
+		want   int
+	}{
+		{"http://kalon:16316", http.StatusSwitchingProtocols},
+		{"https://explorer.example.com", http.StatusSwitchingProtocols},
+		{"https://EXPLORER.example.com", http.StatusSwitchingProtocols},
+		{"https://evil.example.com", http.StatusForbidden},
+		{"http://explorer.example.com", http.StatusForbidden},
+		{"null", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		if got := handshake(c.origin); got != c.want {
+			t.Errorf("Origin %s: expected %d, got %d", c.origin, c.want, got)
+		}
+	}
+
+	if _, err := NewServerV2WithConfig(ServerConfig{
+		Access: AccessConfig{AllowedOrigins: []string{"explorer.example.com"}},
+	}, s.blockchain); err == nil {
+		t.Error("Expected an origin without a scheme to be rejected")
+	}
+}